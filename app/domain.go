@@ -15,7 +15,18 @@ type MqttSubscriber interface {
 	UpdateSwingModeCommandTopic(ctx context.Context) mqtt.MessageHandler
 	UpdateModeCommandTopic(ctx context.Context) mqtt.MessageHandler
 	UpdateTemperatureCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateTemperatureLowCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateTemperatureHighCommandTopic(ctx context.Context) mqtt.MessageHandler
 	UpdateDisplaySwitchCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateHomiePropertyCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateScheduleSwitchCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateTimerCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateExternalTemperatureTopic(ctx context.Context, macs []string) mqtt.MessageHandler
+	UpdateContactTopic(ctx context.Context, macs []string) mqtt.MessageHandler
+	ApplySceneCommandTopic(ctx context.Context) mqtt.MessageHandler
+	ApplySceneToAllCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateGroupCommandTopic(ctx context.Context) mqtt.MessageHandler
+	UpdateSleepCommandTopic(ctx context.Context) mqtt.MessageHandler
 
 	GetStatesOnHomeAssistantRestart(ctx context.Context) mqtt.MessageHandler
 }
@@ -23,19 +34,40 @@ type MqttSubscriber interface {
 type MqttPublisher interface {
 	PublishClimateDiscoveryTopic(ctx context.Context, input modelsMqtt.PublishClimateDiscoveryTopicInput) error
 	PublishSwitchDiscoveryTopic(ctx context.Context, input modelsMqtt.PublishSwitchDiscoveryTopicInput) error
+	PublishSensorDiscoveryTopic(ctx context.Context, input modelsMqtt.PublishSensorDiscoveryTopicInput) error
+	PublishBinarySensorDiscoveryTopic(ctx context.Context, input modelsMqtt.PublishBinarySensorDiscoveryTopicInput) error
+	PublishDeviceTriggerDiscoveryTopic(ctx context.Context, input modelsMqtt.PublishDeviceTriggerDiscoveryTopicInput) error
+	PublishNumberDiscoveryTopic(ctx context.Context, input modelsMqtt.PublishNumberDiscoveryTopicInput) error
 	PublishAmbientTemp(ctx context.Context, input *modelsMqtt.PublishAmbientTempInput) error
 	PublishTemperature(ctx context.Context, input *modelsMqtt.PublishTemperatureInput) error
+	PublishTemperatureRange(ctx context.Context, input *modelsMqtt.PublishTemperatureRangeInput) error
 	PublishMode(ctx context.Context, input *modelsMqtt.PublishModeInput) error
 	PublishSwingMode(ctx context.Context, input *modelsMqtt.PublishSwingModeInput) error
 	PublishFanMode(ctx context.Context, input *modelsMqtt.PublishFanModeInput) error
 	PublishAvailability(ctx context.Context, input *modelsMqtt.PublishAvailabilityInput) error
 	PublishDisplaySwitch(ctx context.Context, input *modelsMqtt.PublishDisplaySwitchInput) error
+	PublishHvacAction(ctx context.Context, input *modelsMqtt.PublishHvacActionInput) error
+	PublishDiagnostic(ctx context.Context, input *modelsMqtt.PublishDiagnosticInput) error
+	PublishFaults(ctx context.Context, input *modelsMqtt.PublishFaultsInput) error
+	PublishFaultState(ctx context.Context, input *modelsMqtt.PublishFaultStateInput) error
+	PublishFaultEvent(ctx context.Context, input *modelsMqtt.PublishFaultEventInput) error
+	PublishBridgeDevices(ctx context.Context, input *modelsMqtt.PublishBridgeDevicesInput) error
+	PublishDeviceEvent(ctx context.Context, input *modelsMqtt.PublishDeviceEventInput) error
+	PublishWorkerStatus(ctx context.Context, input *modelsMqtt.PublishWorkerStatusInput) error
+	PublishScheduleSwitch(ctx context.Context, input *modelsMqtt.PublishScheduleSwitchInput) error
+	PublishTimer(ctx context.Context, input *modelsMqtt.PublishTimerInput) error
+	PublishInterlock(ctx context.Context, input *modelsMqtt.PublishInterlockInput) error
+	PublishSleep(ctx context.Context, input *modelsMqtt.PublishSleepInput) error
+	PublishGroupResult(ctx context.Context, input *modelsMqtt.PublishGroupResultInput) error
+	PublishGroupState(ctx context.Context, input *modelsMqtt.PublishGroupStateInput) error
+	PublishGroupAvailability(ctx context.Context, input *modelsMqtt.PublishGroupAvailabilityInput) error
 }
 
 type Service interface {
 	PublishDiscoveryTopic(ctx context.Context, input *modelsService.PublishDiscoveryTopicInput) error
 	CreateDevice(ctx context.Context, input *modelsService.CreateDeviceInput) error
 	AuthDevice(ctx context.Context, input *modelsService.AuthDeviceInput) error
+	GetDeviceFirmware(ctx context.Context, input *modelsService.GetDeviceFirmwareInput) error
 	GetDeviceAmbientTemperature(ctx context.Context, input *modelsService.GetDeviceAmbientTemperatureInput) error
 	GetDeviceStates(ctx context.Context, input *modelsService.GetDeviceStatesInput) error
 
@@ -43,11 +75,27 @@ type Service interface {
 	UpdateMode(ctx context.Context, input *modelsService.UpdateModeInput) error
 	UpdateSwingMode(ctx context.Context, input *modelsService.UpdateSwingModeInput) error
 	UpdateTemperature(ctx context.Context, input *modelsService.UpdateTemperatureInput) error
+	UpdateTemperatureRange(ctx context.Context, input *modelsService.UpdateTemperatureRangeInput) error
 	UpdateDisplaySwitch(ctx context.Context, input *modelsService.UpdateDisplaySwitchInput) error
+	UpdateScheduleSwitch(ctx context.Context, input *modelsService.UpdateScheduleSwitchInput) error
+	UpdateTimer(ctx context.Context, input *modelsService.UpdateTimerInput) error
+	UpdateExternalTemperature(ctx context.Context, input *modelsService.UpdateExternalTemperatureInput) error
+	UpdateContact(ctx context.Context, input *modelsService.UpdateContactInput) error
+	ApplyScene(ctx context.Context, input *modelsService.ApplySceneInput) error
+	ApplySceneToAll(ctx context.Context, input *modelsService.ApplySceneToAllInput) error
+	UpdateSleep(ctx context.Context, input *modelsService.UpdateSleepInput) error
+	CreateGroup(ctx context.Context, input *modelsService.CreateGroupInput) error
+	UpdateGroup(ctx context.Context, input *modelsService.UpdateGroupInput) error
+	PublishGroupDiscoveryTopic(ctx context.Context, input *modelsService.PublishGroupDiscoveryTopicInput) error
+	UpdateGroupAvailability(ctx context.Context, input *modelsService.UpdateGroupAvailabilityInput) error
 
 	UpdateDeviceAvailability(ctx context.Context, input *modelsService.UpdateDeviceAvailabilityInput) error
+	UpdateWorkerStatus(ctx context.Context, input *modelsService.UpdateWorkerStatusInput) error
 
 	StartDeviceMonitoring(ctx context.Context, input *modelsService.StartDeviceMonitoringInput) error
+	StartScheduler(ctx context.Context, input *modelsService.StartSchedulerInput) error
+	StartTimers(ctx context.Context, input *modelsService.StartTimersInput) error
+	StartSleepCurve(ctx context.Context, input *modelsService.StartSleepCurveInput) error
 
 	PublishStatesOnHomeAssistantRestart(ctx context.Context, input *modelsService.PublishStatesOnHomeAssistantRestartInput) error
 }
@@ -63,22 +111,59 @@ type Cache interface {
 	UpsertDeviceAuth(ctx context.Context, input *modelsCache.UpsertDeviceAuthInput) error
 	ReadDeviceAuth(ctx context.Context, input *modelsCache.ReadDeviceAuthInput) (*modelsCache.ReadDeviceAuthReturn, error)
 
+	UpsertDeviceFirmware(ctx context.Context, input *modelsCache.UpsertDeviceFirmwareInput) error
+	ReadDeviceFirmware(ctx context.Context, input *modelsCache.ReadDeviceFirmwareInput) (*modelsCache.ReadDeviceFirmwareReturn, error)
+
 	UpsertAmbientTemp(ctx context.Context, input *modelsCache.UpsertAmbientTempInput) error
 	ReadAmbientTemp(ctx context.Context, input *modelsCache.ReadAmbientTempInput) (*modelsCache.ReadAmbientTempReturn, error)
 
+	UpsertAmbientFilter(ctx context.Context, input *modelsCache.UpsertAmbientFilterInput) error
+	ReadAmbientFilter(ctx context.Context, input *modelsCache.ReadAmbientFilterInput) (*modelsCache.ReadAmbientFilterReturn, error)
+
 	UpsertDeviceStatusRaw(ctx context.Context, input *modelsCache.UpsertDeviceStatusRawInput) error
 	ReadDeviceStatusRaw(ctx context.Context, input *modelsCache.ReadDeviceStatusRawInput) (*modelsCache.ReadDeviceStatusRawReturn, error)
 
-	UpsertMqttModeMessage(ctx context.Context, input *modelsCache.UpsertMqttModeMessageInput) error
-	UpsertMqttSwingModeMessage(ctx context.Context, input *modelsCache.UpsertMqttSwingModeMessageInput) error
-	UpsertMqttFanModeMessage(ctx context.Context, input *modelsCache.UpsertMqttFanModeMessageInput) error
-	UpsertMqttTemperatureMessage(ctx context.Context, input *modelsCache.UpsertMqttTemperatureMessageInput) error
-	UpsertMqttDisplaySwitchMessage(ctx context.Context, input *modelsCache.UpsertMqttDisplaySwitchMessageInput) error
+	UpsertDeviceInfoRaw(ctx context.Context, input *modelsCache.UpsertDeviceInfoRawInput) error
+	ReadDeviceInfoRaw(ctx context.Context, input *modelsCache.ReadDeviceInfoRawInput) (*modelsCache.ReadDeviceInfoRawReturn, error)
+
+	UpsertDeviceFaults(ctx context.Context, input *modelsCache.UpsertDeviceFaultsInput) error
+	ReadDeviceFaults(ctx context.Context, input *modelsCache.ReadDeviceFaultsInput) (*modelsCache.ReadDeviceFaultsReturn, error)
+
+	UpsertDesiredState(ctx context.Context, input *modelsCache.UpsertDesiredStateInput) error
+	ReadDesiredState(ctx context.Context, input *modelsCache.ReadDesiredStateInput) (*modelsCache.ReadDesiredStateReturn, error)
 
-	ReadMqttMessage(ctx context.Context, input *modelsCache.ReadMqttMessageInput) (*modelsCache.ReadMqttMessageReturn, error)
+	UpsertExternalTemp(ctx context.Context, input *modelsCache.UpsertExternalTempInput) error
+	ReadExternalTemp(ctx context.Context, input *modelsCache.ReadExternalTempInput) (*modelsCache.ReadExternalTempReturn, error)
+
+	UpsertThermostat(ctx context.Context, input *modelsCache.UpsertThermostatInput) error
+	ReadThermostat(ctx context.Context, input *modelsCache.ReadThermostatInput) (*modelsCache.ReadThermostatReturn, error)
+	UpsertHeatCool(ctx context.Context, input *modelsCache.UpsertHeatCoolInput) error
+	ReadHeatCool(ctx context.Context, input *modelsCache.ReadHeatCoolInput) (*modelsCache.ReadHeatCoolReturn, error)
+	UpsertInterlock(ctx context.Context, input *modelsCache.UpsertInterlockInput) error
+	ReadInterlock(ctx context.Context, input *modelsCache.ReadInterlockInput) (*modelsCache.ReadInterlockReturn, error)
+	UpsertInterlockSwitch(ctx context.Context, input *modelsCache.UpsertInterlockSwitchInput) error
+	ReadInterlockSwitch(ctx context.Context, input *modelsCache.ReadInterlockSwitchInput) (*modelsCache.ReadInterlockSwitchReturn, error)
+	UpsertCycle(ctx context.Context, input *modelsCache.UpsertCycleInput) error
+	ReadCycle(ctx context.Context, input *modelsCache.ReadCycleInput) (*modelsCache.ReadCycleReturn, error)
+
+	UpsertHvacAction(ctx context.Context, input *modelsCache.UpsertHvacActionInput) error
+	ReadHvacAction(ctx context.Context, input *modelsCache.ReadHvacActionInput) (*modelsCache.ReadHvacActionReturn, error)
 
 	UpsertDeviceAvailability(ctx context.Context, input *modelsCache.UpsertDeviceAvailabilityInput) error
 	ReadDeviceAvailability(ctx context.Context, input *modelsCache.ReadDeviceAvailabilityInput) (*modelsCache.ReadDeviceAvailabilityReturn, error)
 
 	ReadAuthedDevices(ctx context.Context) (*modelsCache.ReadAuthedDevicesReturn, error)
 }
+
+// Storage keeps the state which must survive the restarts of the bridge
+type Storage interface {
+	UpsertScheduleState(ctx context.Context, input *modelsCache.UpsertScheduleStateInput) error
+	ReadScheduleState(ctx context.Context, input *modelsCache.ReadScheduleStateInput) (*modelsCache.ReadScheduleStateReturn, error)
+
+	UpsertTimerState(ctx context.Context, input *modelsCache.UpsertTimerStateInput) error
+	ReadTimerState(ctx context.Context, input *modelsCache.ReadTimerStateInput) (*modelsCache.ReadTimerStateReturn, error)
+	DeleteTimerState(ctx context.Context, input *modelsCache.DeleteTimerStateInput) error
+	UpsertSleepState(ctx context.Context, input *modelsCache.UpsertSleepStateInput) error
+	ReadSleepState(ctx context.Context, input *modelsCache.ReadSleepStateInput) (*modelsCache.ReadSleepStateReturn, error)
+	DeleteSleepState(ctx context.Context, input *modelsCache.DeleteSleepStateInput) error
+}
@@ -1,8 +1,20 @@
 package models
 
+import "time"
+
 const (
 	DeviceClassClimate string = "climate"
 	DeviceClassSwitch  string = "switch"
+	DeviceClassNumber  string = "number"
+
+	DeviceClassSensor       string = "sensor"
+	DeviceClassBinarySensor string = "binary_sensor"
+	DeviceClassTrigger      string = "device_automation"
+
+	EntityCategoryDiagnostic string = "diagnostic"
+
+	DiscoveryFormatHomeAssistant string = "homeassistant"
+	DiscoveryFormatHomie         string = "homie"
 )
 
 type ConfigMqtt struct {
@@ -13,31 +25,40 @@ type ConfigMqtt struct {
 	TopicPrefix              string
 	AutoDiscoveryTopic       *string
 	AutoDiscoveryTopicRetain bool
+	DiscoveryFormat          string
+	HomieTopic               string
 }
 
+// ClimateDiscoveryTopic describes the climate entity. The entity of a group has no state topics, so it is optimistic.
 type ClimateDiscoveryTopic struct {
-	FanModeCommandTopic     string                     `json:"fan_mode_command_topic" example:"aircon/34ea345b0fd4/fan_mode/set"`
-	SwingModeCommandTopic   string                     `json:"swing_mode_command_topic" example:"aircon/34ea345b0fd4/swing_mode/set"`
-	SwingModes              []string                   `json:"swing_modes"` // 'on' 'off'
-	TempStep                float32                    `json:"temp_step" example:"0.5"`
-	TemperatureStateTopic   string                     `json:"temperature_state_topic" example:"aircon/34ea345b0fd4/temp/value"`
-	TemperatureCommandTopic string                     `json:"temperature_command_topic" example:"aircon/34ea345b0fd4/temp/set"`
-	Precision               float32                    `json:"precision" example:"0.5"`
-	CurrentTemperatureTopic string                     `json:"current_temperature_topic" example:"aircon/34ea345b0fd4/current_temp/value"` // Temperature in the room
-	Device                  DiscoveryTopicDevice       `json:"device"`
-	ModeCommandTopic        string                     `json:"mode_command_topic" example:"aircon/34ea345b0fd4/mode/set"`
-	ModeStateTopic          string                     `json:"mode_state_topic" example:"aircon/34ea345b0fd4/mode/value"`
-	Modes                   []string                   `json:"modes"` // [“auto”, “off”, “cool”, “heat”, “dry”, “fan_only”]
-	Name                    *string                    `json:"name"`
-	FanModes                []string                   `json:"fan_modes"` // : [“auto”, “low”, “medium”, “high”]
-	SwingModeStateTopic     string                     `json:"swing_mode_state_topic" example:"aircon/34ea345b0fd4/swing_mode/value"`
-	FanModeStateTopic       string                     `json:"fan_mode_state_topic" example:"aircon/34ea345b0fd4/fan_mode/value"`
-	UniqueId                string                     `json:"unique_id" example:"34ea345b0fd4"`
-	MaxTemp                 float32                    `json:"max_temp" example:"32.0"`
-	MinTemp                 float32                    `json:"min_temp" example:"16.0"`
-	Availability            DiscoveryTopicAvailability `json:"availability"`
-	Icon                    string                     `json:"icon"`
-	TemperatureUnit         string                     `json:"temperature_unit"` // C or F
+	FanModeCommandTopic     string   `json:"fan_mode_command_topic" example:"aircon/34ea345b0fd4/fan_mode/set"`
+	SwingModeCommandTopic   string   `json:"swing_mode_command_topic" example:"aircon/34ea345b0fd4/swing_mode/set"`
+	SwingModes              []string `json:"swing_modes"` // 'on' 'off'
+	TempStep                float32  `json:"temp_step" example:"0.5"`
+	TemperatureStateTopic   string   `json:"temperature_state_topic,omitempty" example:"aircon/34ea345b0fd4/temp/value"`
+	TemperatureCommandTopic string   `json:"temperature_command_topic" example:"aircon/34ea345b0fd4/temp/set"`
+	// The low and high targets are set only when the heat_cool mode is enabled
+	TemperatureLowStateTopic    string                     `json:"temperature_low_state_topic,omitempty" example:"aircon/34ea345b0fd4/temp_low/value"`
+	TemperatureLowCommandTopic  string                     `json:"temperature_low_command_topic,omitempty" example:"aircon/34ea345b0fd4/temp_low/set"`
+	TemperatureHighStateTopic   string                     `json:"temperature_high_state_topic,omitempty" example:"aircon/34ea345b0fd4/temp_high/value"`
+	TemperatureHighCommandTopic string                     `json:"temperature_high_command_topic,omitempty" example:"aircon/34ea345b0fd4/temp_high/set"`
+	Precision                   float32                    `json:"precision" example:"0.5"`
+	CurrentTemperatureTopic     string                     `json:"current_temperature_topic,omitempty" example:"aircon/34ea345b0fd4/current_temp/value"` // Temperature in the room
+	Device                      DiscoveryTopicDevice       `json:"device"`
+	ModeCommandTopic            string                     `json:"mode_command_topic" example:"aircon/34ea345b0fd4/mode/set"`
+	ModeStateTopic              string                     `json:"mode_state_topic,omitempty" example:"aircon/34ea345b0fd4/mode/value"`
+	ActionTopic                 string                     `json:"action_topic,omitempty" example:"aircon/34ea345b0fd4/action/value"`
+	Modes                       []string                   `json:"modes"` // [“auto”, “off”, “cool”, “heat”, “dry”, “fan_only”]
+	Name                        *string                    `json:"name"`
+	FanModes                    []string                   `json:"fan_modes"` // : [“auto”, “low”, “medium”, “high”]
+	SwingModeStateTopic         string                     `json:"swing_mode_state_topic,omitempty" example:"aircon/34ea345b0fd4/swing_mode/value"`
+	FanModeStateTopic           string                     `json:"fan_mode_state_topic,omitempty" example:"aircon/34ea345b0fd4/fan_mode/value"`
+	UniqueId                    string                     `json:"unique_id" example:"34ea345b0fd4"`
+	MaxTemp                     float32                    `json:"max_temp" example:"32.0"`
+	MinTemp                     float32                    `json:"min_temp" example:"16.0"`
+	Availability                DiscoveryTopicAvailability `json:"availability"`
+	Icon                        string                     `json:"icon"`
+	TemperatureUnit             string                     `json:"temperature_unit"` // C or F
 }
 
 type SwitchDiscoveryTopic struct {
@@ -50,10 +71,61 @@ type SwitchDiscoveryTopic struct {
 	Icon         string                     `json:"icon"`
 }
 
+type NumberDiscoveryTopic struct {
+	Device            DiscoveryTopicDevice       `json:"device"`
+	Name              string                     `json:"name" example:"Off timer"`
+	UniqueId          string                     `json:"unique_id" example:"34ea345b0fd4_timer_off"`
+	StateTopic        string                     `json:"state_topic" example:"aircon/34ea345b0fd4/timer/off/value"`
+	CommandTopic      string                     `json:"command_topic" example:"aircon/34ea345b0fd4/timer/off/set"`
+	Availability      DiscoveryTopicAvailability `json:"availability"`
+	Icon              string                     `json:"icon,omitempty"`
+	Min               float32                    `json:"min"`
+	Max               float32                    `json:"max"`
+	Step              float32                    `json:"step"`
+	Mode              string                     `json:"mode,omitempty" example:"box"`
+	UnitOfMeasurement string                     `json:"unit_of_measurement,omitempty" example:"min"`
+}
+
+type SensorDiscoveryTopic struct {
+	Device            DiscoveryTopicDevice       `json:"device"`
+	Name              string                     `json:"name" example:"Outdoor temperature"`
+	UniqueId          string                     `json:"unique_id" example:"34ea345b0fd4_outdoor_temp"`
+	StateTopic        string                     `json:"state_topic" example:"aircon/34ea345b0fd4/outdoor_temp/value"`
+	Availability      DiscoveryTopicAvailability `json:"availability"`
+	Icon              string                     `json:"icon,omitempty"`
+	DeviceClass       string                     `json:"device_class,omitempty" example:"temperature"`
+	UnitOfMeasurement string                     `json:"unit_of_measurement,omitempty" example:"°C"`
+	StateClass        string                     `json:"state_class,omitempty" example:"measurement"`
+	EntityCategory    string                     `json:"entity_category,omitempty" example:"diagnostic"`
+}
+
+type BinarySensorDiscoveryTopic struct {
+	Device         DiscoveryTopicDevice       `json:"device"`
+	Name           string                     `json:"name" example:"Compressor"`
+	UniqueId       string                     `json:"unique_id" example:"34ea345b0fd4_compressor"`
+	StateTopic     string                     `json:"state_topic" example:"aircon/34ea345b0fd4/compressor/value"`
+	Availability   DiscoveryTopicAvailability `json:"availability"`
+	Icon           string                     `json:"icon,omitempty"`
+	DeviceClass    string                     `json:"device_class,omitempty" example:"running"`
+	PayloadOn      string                     `json:"payload_on" example:"ON"`
+	PayloadOff     string                     `json:"payload_off" example:"OFF"`
+	EntityCategory string                     `json:"entity_category,omitempty" example:"diagnostic"`
+}
+
+type DeviceTriggerDiscoveryTopic struct {
+	Device         DiscoveryTopicDevice `json:"device"`
+	AutomationType string               `json:"automation_type" example:"trigger"`
+	Topic          string               `json:"topic" example:"aircon/34ea345b0fd4/fault/outdoor_error/event"`
+	Payload        string               `json:"payload" example:"raised"`
+	Type           string               `json:"type" example:"raised"`
+	Subtype        string               `json:"subtype" example:"outdoor_error"`
+	UniqueId       string               `json:"-" example:"34ea345b0fd4_outdoor_error_raised"`
+}
+
 type DiscoveryTopicDevice struct {
 	Model string `json:"model" example:"Aircon"`
 	Mf    string `json:"mf" example:"Broadlink"`
-	Sw    string `json:"sw" example:"1.1.3"`
+	Sw    string `json:"sw,omitempty" example:"55"`
 	Ids   string `json:"ids" example:"34ea345b0fd4"`
 	Name  string `json:"name" example:"childroom"`
 }
@@ -68,10 +140,26 @@ type PublishClimateDiscoveryTopicInput struct {
 	Topic ClimateDiscoveryTopic
 }
 
+type PublishNumberDiscoveryTopicInput struct {
+	Topic NumberDiscoveryTopic
+}
+
 type PublishSwitchDiscoveryTopicInput struct {
 	Topic SwitchDiscoveryTopic
 }
 
+type PublishSensorDiscoveryTopicInput struct {
+	Topic SensorDiscoveryTopic
+}
+
+type PublishBinarySensorDiscoveryTopicInput struct {
+	Topic BinarySensorDiscoveryTopic
+}
+
+type PublishDeviceTriggerDiscoveryTopicInput struct {
+	Topic DeviceTriggerDiscoveryTopic
+}
+
 type PublishAmbientTempInput struct {
 	Mac         string
 	Temperature float32
@@ -82,6 +170,13 @@ type PublishTemperatureInput struct {
 	Temperature float32
 }
 
+// PublishTemperatureRangeInput contains the low and high targets of the heat_cool mode
+type PublishTemperatureRangeInput struct {
+	Mac  string
+	Low  float32
+	High float32
+}
+
 type PublishModeInput struct {
 	Mac  string
 	Mode string
@@ -105,3 +200,129 @@ type PublishDisplaySwitchInput struct {
 	Mac    string
 	Status string
 }
+
+type PublishHvacActionInput struct {
+	Mac        string
+	HvacAction string
+}
+
+type PublishDiagnosticInput struct {
+	Mac   string
+	Name  string
+	Value string
+}
+
+type Fault struct {
+	Kind      string     `json:"kind" example:"outdoor_error"`
+	Code      int        `json:"code" example:"5"`
+	Message   string     `json:"message" example:"outdoor unit reports error code 5"`
+	FirstSeen time.Time  `json:"first_seen"`
+	ClearedAt *time.Time `json:"cleared_at,omitempty"`
+}
+
+type PublishFaultsInput struct {
+	Mac    string
+	Faults []Fault
+}
+
+type PublishFaultStateInput struct {
+	Mac    string
+	Kind   string
+	Status string
+}
+
+type PublishFaultEventInput struct {
+	Mac   string
+	Kind  string
+	Event string
+}
+
+type BridgeDevice struct {
+	Mac      string `json:"mac" example:"34ea345b0fd4"`
+	Name     string `json:"name" example:"childroom"`
+	Ip       string `json:"ip" example:"192.168.1.12"`
+	Firmware string `json:"firmware,omitempty" example:"55"`
+}
+
+type PublishBridgeDevicesInput struct {
+	Devices []BridgeDevice
+}
+
+// DeviceEvent describes a change of the device state
+type DeviceEvent struct {
+	Type    string            `json:"type" example:"overridden"`
+	Source  string            `json:"source" example:"remote"`
+	Message string            `json:"message,omitempty"`
+	Changes map[string]string `json:"changes,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+type PublishDeviceEventInput struct {
+	Mac   string
+	Event DeviceEvent
+}
+
+type WorkerStatus struct {
+	State     string    `json:"state" example:"running"`
+	Restarts  int       `json:"restarts" example:"0"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PublishWorkerStatusInput struct {
+	Mac    string
+	Status WorkerStatus
+}
+
+// PublishInterlockInput contains ON while the unit is switched by the interlock and OFF otherwise
+type PublishInterlockInput struct {
+	Mac    string
+	Status string
+}
+
+type PublishSleepInput struct {
+	Mac string
+	// Profile is the running sleep profile or OFF
+	Profile string
+	// Progress is the percentage of the applied steps
+	Progress int
+	// Next is the time of the next step or zero
+	Next time.Time
+}
+
+// GroupResult reports the results of a group command by the MAC addresses of the members
+type GroupResult struct {
+	Property string            `json:"property" example:"mode"`
+	Value    string            `json:"value" example:"cool"`
+	Results  map[string]string `json:"results" example:"34ea345b0fd4:ok"`
+	Time     time.Time         `json:"time"`
+}
+
+type PublishGroupResultInput struct {
+	Name   string
+	Result GroupResult
+}
+
+type PublishGroupStateInput struct {
+	Name  string
+	State string
+}
+
+type PublishGroupAvailabilityInput struct {
+	Name         string
+	Availability string
+}
+
+type PublishScheduleSwitchInput struct {
+	Mac    string
+	Name   string
+	Status string
+}
+
+// PublishTimerInput describes the timer. A zero At means that the timer is not set.
+type PublishTimerInput struct {
+	Mac       string
+	Kind      string
+	Remaining int
+	At        time.Time
+}
@@ -0,0 +1,254 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	homieVersion    = "4.0"
+	homieNodeId     = "climate"
+	homieStateInit  = "init"
+	homieStateLost  = "lost"
+	homieStateReady = "ready"
+)
+
+// homiePublisher describes every air conditioner as a Homie 4 device instead of
+// Home Assistant discovery entities. The states are still published to the bridge
+// topics, and are mirrored to the Homie property topics.
+type homiePublisher struct {
+	*mqttPublisher
+}
+
+func NewHomieSender(logger *slog.Logger, mqttConfig models.ConfigMqtt, client paho.Client) app.MqttPublisher {
+	return &homiePublisher{
+		mqttPublisher: &mqttPublisher{
+			logger:     logger,
+			mqttConfig: mqttConfig,
+			client:     client,
+		},
+	}
+}
+
+// PublishClimateDiscoveryTopic publishes the device, the climate node and all its properties.
+// The display is a property of the climate node, so it is described here as well.
+func (m *homiePublisher) PublishClimateDiscoveryTopic(ctx context.Context, input models.PublishClimateDiscoveryTopicInput) error {
+	deviceTopic := m.mqttConfig.HomieTopic + "/" + input.Topic.Device.Ids
+	nodeTopic := deviceTopic + "/" + homieNodeId
+
+	unit := "°" + input.Topic.TemperatureUnit
+
+	// The heat_cool mode has the low and high targets, which are kept by the bridge
+	isHeatCool := len(input.Topic.TemperatureLowCommandTopic) != 0
+	properties := "mode,action,target-temperature,current-temperature,fan,swing,display"
+	if isHeatCool {
+		properties += ",target-temperature-low,target-temperature-high"
+	}
+	temperatureFormat := fmt.Sprintf("%g:%g", input.Topic.MinTemp, input.Topic.MaxTemp)
+
+	type attribute struct {
+		topic   string
+		payload string
+	}
+	attributes := []attribute{
+		{deviceTopic + "/$state", homieStateInit},
+		{deviceTopic + "/$homie", homieVersion},
+		{deviceTopic + "/$name", input.Topic.Device.Name},
+		{deviceTopic + "/$nodes", homieNodeId},
+
+		{nodeTopic + "/$name", "Air conditioner"},
+		{nodeTopic + "/$type", models.DeviceClassClimate},
+		{nodeTopic + "/$properties", properties},
+
+		{nodeTopic + "/mode/$name", "Mode"},
+		{nodeTopic + "/mode/$datatype", "enum"},
+		{nodeTopic + "/mode/$format", strings.Join(input.Topic.Modes, ",")},
+		{nodeTopic + "/mode/$settable", "true"},
+
+		{nodeTopic + "/action/$name", "Action"},
+		{nodeTopic + "/action/$datatype", "enum"},
+		{nodeTopic + "/action/$format", "off,idle,cooling,heating,drying,fan,defrosting"},
+
+		{nodeTopic + "/target-temperature/$name", "Target temperature"},
+		{nodeTopic + "/target-temperature/$datatype", "float"},
+		{nodeTopic + "/target-temperature/$format", temperatureFormat},
+		{nodeTopic + "/target-temperature/$unit", unit},
+		{nodeTopic + "/target-temperature/$settable", "true"},
+
+		{nodeTopic + "/current-temperature/$name", "Current temperature"},
+		{nodeTopic + "/current-temperature/$datatype", "float"},
+		{nodeTopic + "/current-temperature/$unit", unit},
+
+		{nodeTopic + "/fan/$name", "Fan mode"},
+		{nodeTopic + "/fan/$datatype", "enum"},
+		{nodeTopic + "/fan/$format", strings.Join(input.Topic.FanModes, ",")},
+		{nodeTopic + "/fan/$settable", "true"},
+
+		{nodeTopic + "/swing/$name", "Swing mode"},
+		{nodeTopic + "/swing/$datatype", "enum"},
+		{nodeTopic + "/swing/$format", strings.Join(input.Topic.SwingModes, ",")},
+		{nodeTopic + "/swing/$settable", "true"},
+
+		{nodeTopic + "/display/$name", "Screen"},
+		{nodeTopic + "/display/$datatype", "boolean"},
+		{nodeTopic + "/display/$settable", "true"},
+	}
+
+	if isHeatCool {
+		for _, property := range []struct{ id, name string }{
+			{"target-temperature-low", "Low target temperature"},
+			{"target-temperature-high", "High target temperature"},
+		} {
+			attributes = append(attributes,
+				attribute{nodeTopic + "/" + property.id + "/$name", property.name},
+				attribute{nodeTopic + "/" + property.id + "/$datatype", "float"},
+				attribute{nodeTopic + "/" + property.id + "/$format", temperatureFormat},
+				attribute{nodeTopic + "/" + property.id + "/$unit", unit},
+				attribute{nodeTopic + "/" + property.id + "/$settable", "true"},
+			)
+		}
+	}
+
+	attributes = append(attributes, attribute{deviceTopic + "/$state", homieStateReady})
+
+	for _, attribute := range attributes {
+		err := m.publish(ctx, attribute.topic, true, attribute.payload)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "Failed to publish homie attribute",
+				slog.String("topic", attribute.topic),
+				slog.Any("err", err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishSwitchDiscoveryTopic does nothing as the display is already a property of the climate node
+// and the schedule switches are not a part of the Homie device
+func (m *homiePublisher) PublishSwitchDiscoveryTopic(ctx context.Context, input models.PublishSwitchDiscoveryTopicInput) error {
+	return nil
+}
+
+// PublishSensorDiscoveryTopic does nothing as the diagnostic sensors are not a part of the Homie device
+func (m *homiePublisher) PublishSensorDiscoveryTopic(ctx context.Context, input models.PublishSensorDiscoveryTopicInput) error {
+	return nil
+}
+
+// PublishBinarySensorDiscoveryTopic does nothing as the diagnostic sensors are not a part of the Homie device
+func (m *homiePublisher) PublishBinarySensorDiscoveryTopic(ctx context.Context, input models.PublishBinarySensorDiscoveryTopicInput) error {
+	return nil
+}
+
+// PublishDeviceTriggerDiscoveryTopic does nothing as Homie has no device triggers
+func (m *homiePublisher) PublishDeviceTriggerDiscoveryTopic(ctx context.Context, input models.PublishDeviceTriggerDiscoveryTopicInput) error {
+	return nil
+}
+
+// PublishNumberDiscoveryTopic does nothing as the timers are not a part of the Homie device
+func (m *homiePublisher) PublishNumberDiscoveryTopic(ctx context.Context, input models.PublishNumberDiscoveryTopicInput) error {
+	return nil
+}
+
+func (m *homiePublisher) PublishAmbientTemp(ctx context.Context, input *models.PublishAmbientTempInput) error {
+	err := m.mqttPublisher.PublishAmbientTemp(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "current-temperature", fmt.Sprintf("%.1f", input.Temperature))
+}
+
+func (m *homiePublisher) PublishTemperature(ctx context.Context, input *models.PublishTemperatureInput) error {
+	err := m.mqttPublisher.PublishTemperature(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "target-temperature", fmt.Sprintf("%.1f", input.Temperature))
+}
+
+func (m *homiePublisher) PublishTemperatureRange(ctx context.Context, input *models.PublishTemperatureRangeInput) error {
+	err := m.mqttPublisher.PublishTemperatureRange(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	err = m.publishProperty(ctx, input.Mac, "target-temperature-low", fmt.Sprintf("%.1f", input.Low))
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "target-temperature-high", fmt.Sprintf("%.1f", input.High))
+}
+
+func (m *homiePublisher) PublishMode(ctx context.Context, input *models.PublishModeInput) error {
+	err := m.mqttPublisher.PublishMode(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "mode", input.Mode)
+}
+
+func (m *homiePublisher) PublishSwingMode(ctx context.Context, input *models.PublishSwingModeInput) error {
+	err := m.mqttPublisher.PublishSwingMode(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "swing", input.SwingMode)
+}
+
+func (m *homiePublisher) PublishFanMode(ctx context.Context, input *models.PublishFanModeInput) error {
+	err := m.mqttPublisher.PublishFanMode(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "fan", input.FanMode)
+}
+
+func (m *homiePublisher) PublishDisplaySwitch(ctx context.Context, input *models.PublishDisplaySwitchInput) error {
+	err := m.mqttPublisher.PublishDisplaySwitch(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "display", fmt.Sprint(input.Status == "ON"))
+}
+
+func (m *homiePublisher) PublishHvacAction(ctx context.Context, input *models.PublishHvacActionInput) error {
+	err := m.mqttPublisher.PublishHvacAction(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return m.publishProperty(ctx, input.Mac, "action", input.HvacAction)
+}
+
+func (m *homiePublisher) PublishAvailability(ctx context.Context, input *models.PublishAvailabilityInput) error {
+	err := m.mqttPublisher.PublishAvailability(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	state := homieStateLost
+	if input.Availability == "online" {
+		state = homieStateReady
+	}
+
+	return m.publish(ctx, m.mqttConfig.HomieTopic+"/"+input.Mac+"/$state", true, state)
+}
+
+func (m *homiePublisher) publishProperty(ctx context.Context, mac, property, value string) error {
+	topic := m.mqttConfig.HomieTopic + "/" + mac + "/" + homieNodeId + "/" + property
+
+	return m.publish(ctx, topic, true, value)
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"time"
 
 	"github.com/ArtemVladimirov/broadlinkac2mqtt/app"
 
@@ -70,6 +72,88 @@ func (m *mqttPublisher) PublishSwitchDiscoveryTopic(ctx context.Context, input m
 	}
 }
 
+func (m *mqttPublisher) PublishNumberDiscoveryTopic(ctx context.Context, input models.PublishNumberDiscoveryTopicInput) error {
+	if m.mqttConfig.AutoDiscoveryTopic == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(input.Topic)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal discovery topic", slog.Any("input", input.Topic), slog.Any("err", err))
+		return err
+	}
+
+	topic := *m.mqttConfig.AutoDiscoveryTopic + "/" + models.DeviceClassNumber + "/" + input.Topic.UniqueId + "/config"
+
+	return m.publish(ctx, topic, m.mqttConfig.AutoDiscoveryTopicRetain, string(payload))
+}
+
+func (m *mqttPublisher) PublishSensorDiscoveryTopic(ctx context.Context, input models.PublishSensorDiscoveryTopicInput) error {
+	if m.mqttConfig.AutoDiscoveryTopic == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(input.Topic)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal discovery topic", slog.Any("input", input.Topic), slog.Any("err", err))
+		return err
+	}
+
+	topic := *m.mqttConfig.AutoDiscoveryTopic + "/" + models.DeviceClassSensor + "/" + input.Topic.UniqueId + "/config"
+
+	token := m.client.Publish(topic, 0, m.mqttConfig.AutoDiscoveryTopicRetain, string(payload))
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+func (m *mqttPublisher) PublishBinarySensorDiscoveryTopic(ctx context.Context, input models.PublishBinarySensorDiscoveryTopicInput) error {
+	if m.mqttConfig.AutoDiscoveryTopic == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(input.Topic)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal discovery topic", slog.Any("input", input.Topic), slog.Any("err", err))
+		return err
+	}
+
+	topic := *m.mqttConfig.AutoDiscoveryTopic + "/" + models.DeviceClassBinarySensor + "/" + input.Topic.UniqueId + "/config"
+
+	token := m.client.Publish(topic, 0, m.mqttConfig.AutoDiscoveryTopicRetain, string(payload))
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+func (m *mqttPublisher) PublishDeviceTriggerDiscoveryTopic(ctx context.Context, input models.PublishDeviceTriggerDiscoveryTopicInput) error {
+	if m.mqttConfig.AutoDiscoveryTopic == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(input.Topic)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal discovery topic", slog.Any("input", input.Topic), slog.Any("err", err))
+		return err
+	}
+
+	topic := *m.mqttConfig.AutoDiscoveryTopic + "/" + models.DeviceClassTrigger + "/" + input.Topic.UniqueId + "/config"
+
+	token := m.client.Publish(topic, 0, m.mqttConfig.AutoDiscoveryTopicRetain, string(payload))
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
 func (m *mqttPublisher) PublishAmbientTemp(ctx context.Context, input *models.PublishAmbientTempInput) error {
 	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/current_temp/value"
 
@@ -94,6 +178,18 @@ func (m *mqttPublisher) PublishTemperature(ctx context.Context, input *models.Pu
 	}
 }
 
+// PublishTemperatureRange publishes the low and high targets of the heat_cool mode. They are kept by the bridge, so they are retained.
+func (m *mqttPublisher) PublishTemperatureRange(ctx context.Context, input *models.PublishTemperatureRangeInput) error {
+	prefix := m.mqttConfig.TopicPrefix + "/" + input.Mac
+
+	err := m.publish(ctx, prefix+"/temp_low/value", true, fmt.Sprintf("%.1f", input.Low))
+	if err != nil {
+		return err
+	}
+
+	return m.publish(ctx, prefix+"/temp_high/value", true, fmt.Sprintf("%.1f", input.High))
+}
+
 func (m *mqttPublisher) PublishMode(ctx context.Context, input *models.PublishModeInput) error {
 	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/mode/value"
 
@@ -153,3 +249,199 @@ func (m *mqttPublisher) PublishDisplaySwitch(ctx context.Context, input *models.
 		return token.Error()
 	}
 }
+
+func (m *mqttPublisher) PublishHvacAction(ctx context.Context, input *models.PublishHvacActionInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/action/value"
+
+	token := m.client.Publish(topic, 0, false, input.HvacAction)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+func (m *mqttPublisher) PublishDiagnostic(ctx context.Context, input *models.PublishDiagnosticInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/" + input.Name + "/value"
+
+	token := m.client.Publish(topic, 0, false, input.Value)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+// PublishFaults publishes the retained list of the device faults
+func (m *mqttPublisher) PublishFaults(ctx context.Context, input *models.PublishFaultsInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/faults/value"
+
+	payload, err := json.Marshal(input.Faults)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal faults", slog.Any("input", input), slog.Any("err", err))
+		return err
+	}
+
+	token := m.client.Publish(topic, 0, true, string(payload))
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+func (m *mqttPublisher) PublishFaultState(ctx context.Context, input *models.PublishFaultStateInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/fault/" + input.Kind + "/value"
+
+	token := m.client.Publish(topic, 0, true, input.Status)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+func (m *mqttPublisher) PublishFaultEvent(ctx context.Context, input *models.PublishFaultEventInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/fault/" + input.Kind + "/event"
+
+	token := m.client.Publish(topic, 0, false, input.Event)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+// PublishBridgeDevices publishes the retained inventory of the bridge devices
+func (m *mqttPublisher) PublishBridgeDevices(ctx context.Context, input *models.PublishBridgeDevicesInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/bridge/devices"
+
+	payload, err := json.Marshal(input.Devices)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal bridge devices", slog.Any("input", input), slog.Any("err", err))
+		return err
+	}
+
+	token := m.client.Publish(topic, 0, true, string(payload))
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
+
+func (m *mqttPublisher) PublishDeviceEvent(ctx context.Context, input *models.PublishDeviceEventInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/events"
+
+	payload, err := json.Marshal(input.Event)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal device event", slog.Any("input", input), slog.Any("err", err))
+		return err
+	}
+
+	return m.publish(ctx, topic, false, string(payload))
+}
+
+func (m *mqttPublisher) PublishWorkerStatus(ctx context.Context, input *models.PublishWorkerStatusInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/worker/value"
+
+	payload, err := json.Marshal(input.Status)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal worker status", slog.Any("input", input), slog.Any("err", err))
+		return err
+	}
+
+	return m.publish(ctx, topic, true, string(payload))
+}
+
+func (m *mqttPublisher) PublishScheduleSwitch(ctx context.Context, input *models.PublishScheduleSwitchInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/schedule/" + input.Name + "/value"
+
+	return m.publish(ctx, topic, true, input.Status)
+}
+
+func (m *mqttPublisher) PublishInterlock(ctx context.Context, input *models.PublishInterlockInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/interlock/value"
+
+	return m.publish(ctx, topic, true, input.Status)
+}
+
+// PublishSleep publishes the running sleep profile, its progress in percent and the time of the next step.
+// The time is None when there is no next step.
+func (m *mqttPublisher) PublishSleep(ctx context.Context, input *models.PublishSleepInput) error {
+	prefix := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/sleep"
+
+	err := m.publish(ctx, prefix+"/value", true, input.Profile)
+	if err != nil {
+		return err
+	}
+
+	err = m.publish(ctx, prefix+"/progress/value", true, strconv.Itoa(input.Progress))
+	if err != nil {
+		return err
+	}
+
+	next := "None"
+	if !input.Next.IsZero() {
+		next = input.Next.Format(time.RFC3339)
+	}
+
+	return m.publish(ctx, prefix+"/next/value", true, next)
+}
+
+func (m *mqttPublisher) PublishGroupResult(ctx context.Context, input *models.PublishGroupResultInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/group/" + input.Name + "/result"
+
+	payload, err := json.Marshal(input.Result)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "Failed to marshal group result", slog.Any("input", input), slog.Any("err", err))
+		return err
+	}
+
+	return m.publish(ctx, topic, false, string(payload))
+}
+
+func (m *mqttPublisher) PublishGroupState(ctx context.Context, input *models.PublishGroupStateInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/group/" + input.Name + "/state/value"
+
+	return m.publish(ctx, topic, true, input.State)
+}
+
+func (m *mqttPublisher) PublishGroupAvailability(ctx context.Context, input *models.PublishGroupAvailabilityInput) error {
+	topic := m.mqttConfig.TopicPrefix + "/group/" + input.Name + "/availability/value"
+
+	return m.publish(ctx, topic, true, input.Availability)
+}
+
+// PublishTimer publishes the remaining minutes and the end time of the timer. The end time is None when the timer is not set.
+func (m *mqttPublisher) PublishTimer(ctx context.Context, input *models.PublishTimerInput) error {
+	prefix := m.mqttConfig.TopicPrefix + "/" + input.Mac + "/timer/" + input.Kind
+
+	err := m.publish(ctx, prefix+"/value", true, strconv.Itoa(input.Remaining))
+	if err != nil {
+		return err
+	}
+
+	end := "None"
+	if !input.At.IsZero() {
+		end = input.At.Format(time.RFC3339)
+	}
+
+	return m.publish(ctx, prefix+"/end/value", true, end)
+}
+
+func (m *mqttPublisher) publish(ctx context.Context, topic string, retained bool, payload string) error {
+	token := m.client.Publish(topic, 0, retained, payload)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-token.Done():
+		return token.Error()
+	}
+}
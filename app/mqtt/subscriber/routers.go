@@ -23,7 +23,71 @@ func Routers(ctx context.Context, logger *slog.Logger, mac string, topicPrefix s
 	if token := client.Subscribe(prefix+"/temp/set", 0, handler.UpdateTemperatureCommandTopic(ctx)); token.Wait() && token.Error() != nil {
 		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
 	}
+	if token := client.Subscribe(prefix+"/temp_low/set", 0, handler.UpdateTemperatureLowCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+	if token := client.Subscribe(prefix+"/temp_high/set", 0, handler.UpdateTemperatureHighCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
 	if token := client.Subscribe(prefix+"/display/switch/set", 0, handler.UpdateDisplaySwitchCommandTopic(ctx)); token.Wait() && token.Error() != nil {
 		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
 	}
+	if token := client.Subscribe(prefix+"/schedule/+/set", 0, handler.UpdateScheduleSwitchCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+	if token := client.Subscribe(prefix+"/timer/+/set", 0, handler.UpdateTimerCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+	if token := client.Subscribe(prefix+"/scene/set", 0, handler.ApplySceneCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+	if token := client.Subscribe(prefix+"/sleep/set", 0, handler.UpdateSleepCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+}
+
+// SceneRouters subscribes on the topic which applies a scene to all the devices
+func SceneRouters(ctx context.Context, logger *slog.Logger, topicPrefix string, client mqtt.Client, handler app.MqttSubscriber) {
+	if token := client.Subscribe(topicPrefix+"/scene/set", 0, handler.ApplySceneToAllCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+}
+
+// HomieRouters subscribes on the set topics of the Homie climate node properties
+func HomieRouters(ctx context.Context, logger *slog.Logger, mac string, homieTopic string, client mqtt.Client, handler app.MqttSubscriber) {
+	if token := client.Subscribe(homieTopic+"/"+mac+"/climate/+/set", 0, handler.UpdateHomiePropertyCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+}
+
+// ExternalSensorRouters subscribes on the topics of the external temperature sensors.
+// A topic is subscribed once as a new subscription replaces the handler, so the readings are passed to every device of the topic.
+func ExternalSensorRouters(ctx context.Context, logger *slog.Logger, topics map[string][]string, client mqtt.Client, handler app.MqttSubscriber) {
+	for topic, macs := range topics {
+		if token := client.Subscribe(topic, 0, handler.UpdateExternalTemperatureTopic(ctx, macs)); token.Wait() && token.Error() != nil {
+			logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+		}
+	}
+}
+
+// InterlockRouters subscribes on the topics of the interlock contacts.
+// A topic is subscribed once as a new subscription replaces the handler, so the states are passed to every device of the topic.
+func InterlockRouters(ctx context.Context, logger *slog.Logger, topics map[string][]string, client mqtt.Client, handler app.MqttSubscriber) {
+	for topic, macs := range topics {
+		if token := client.Subscribe(topic, 0, handler.UpdateContactTopic(ctx, macs)); token.Wait() && token.Error() != nil {
+			logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+		}
+	}
+}
+
+// GroupRouters subscribes on the command topics of the device groups
+func GroupRouters(ctx context.Context, logger *slog.Logger, topicPrefix string, client mqtt.Client, handler app.MqttSubscriber) {
+	prefix := topicPrefix + "/group/+"
+
+	if token := client.Subscribe(prefix+"/+/set", 0, handler.UpdateGroupCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
+	if token := client.Subscribe(prefix+"/display/switch/set", 0, handler.UpdateGroupCommandTopic(ctx)); token.Wait() && token.Error() != nil {
+		logger.ErrorContext(ctx, "failed to subscribe on topic", slog.Any("err", token.Error()))
+	}
 }
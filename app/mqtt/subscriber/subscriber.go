@@ -120,6 +120,64 @@ func (m *mqttSubscriber) UpdateTemperatureCommandTopic(ctx context.Context) mqtt
 	}
 }
 
+func (m *mqttSubscriber) UpdateTemperatureLowCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		mac := strings.TrimPrefix(strings.TrimSuffix(msg.Topic(), "/temp_low/set"), m.mqttConfig.TopicPrefix+"/")
+
+		m.logger.DebugContext(ctx, "new update low temperature message",
+			slog.String("device", mac),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		temperature, err := strconv.ParseFloat(string(msg.Payload()), 32)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to parse temperature", slog.Any("err", err), slog.String("input", string(msg.Payload())))
+			return
+		}
+
+		low := float32(temperature)
+		updateTemperatureRangeInput := &modelsservice.UpdateTemperatureRangeInput{
+			Mac: mac,
+			Low: &low,
+		}
+
+		err = m.service.UpdateTemperatureRange(ctx, updateTemperatureRangeInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to update low temperature", slog.Any("err", err), slog.Any("input", updateTemperatureRangeInput))
+			return
+		}
+	}
+}
+
+func (m *mqttSubscriber) UpdateTemperatureHighCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		mac := strings.TrimPrefix(strings.TrimSuffix(msg.Topic(), "/temp_high/set"), m.mqttConfig.TopicPrefix+"/")
+
+		m.logger.DebugContext(ctx, "new update high temperature message",
+			slog.String("device", mac),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		temperature, err := strconv.ParseFloat(string(msg.Payload()), 32)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to parse temperature", slog.Any("err", err), slog.String("input", string(msg.Payload())))
+			return
+		}
+
+		high := float32(temperature)
+		updateTemperatureRangeInput := &modelsservice.UpdateTemperatureRangeInput{
+			Mac:  mac,
+			High: &high,
+		}
+
+		err = m.service.UpdateTemperatureRange(ctx, updateTemperatureRangeInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to update high temperature", slog.Any("err", err), slog.Any("input", updateTemperatureRangeInput))
+			return
+		}
+	}
+}
+
 func (m *mqttSubscriber) GetStatesOnHomeAssistantRestart(ctx context.Context) mqtt.MessageHandler {
 	return func(c mqtt.Client, msg mqtt.Message) {
 		m.logger.DebugContext(ctx, "new home assistant LWT message",
@@ -159,3 +217,271 @@ func (m *mqttSubscriber) UpdateDisplaySwitchCommandTopic(ctx context.Context) mq
 		}
 	}
 }
+
+func (m *mqttSubscriber) UpdateScheduleSwitchCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		// <mac>/schedule/<name>/set
+		levels := strings.Split(strings.TrimPrefix(msg.Topic(), m.mqttConfig.TopicPrefix+"/"), "/")
+		if len(levels) != 4 {
+			m.logger.ErrorContext(ctx, "unknown schedule topic", slog.String("topic", msg.Topic()))
+			return
+		}
+
+		m.logger.DebugContext(ctx, "new update schedule switch message",
+			slog.String("device", levels[0]),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		updateScheduleSwitchInput := &modelsservice.UpdateScheduleSwitchInput{
+			Mac:    levels[0],
+			Name:   levels[2],
+			Status: string(msg.Payload()),
+		}
+
+		err := m.service.UpdateScheduleSwitch(ctx, updateScheduleSwitchInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to update schedule switch", slog.Any("input", updateScheduleSwitchInput))
+			return
+		}
+	}
+}
+
+func (m *mqttSubscriber) UpdateTimerCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		// <mac>/timer/<kind>/set
+		levels := strings.Split(strings.TrimPrefix(msg.Topic(), m.mqttConfig.TopicPrefix+"/"), "/")
+		if len(levels) != 4 {
+			m.logger.ErrorContext(ctx, "unknown timer topic", slog.String("topic", msg.Topic()))
+			return
+		}
+
+		m.logger.DebugContext(ctx, "new update timer message",
+			slog.String("device", levels[0]),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		updateTimerInput := &modelsservice.UpdateTimerInput{
+			Mac:   levels[0],
+			Kind:  levels[2],
+			Value: string(msg.Payload()),
+		}
+
+		err := m.service.UpdateTimer(ctx, updateTimerInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to update timer", slog.Any("input", updateTimerInput))
+			return
+		}
+	}
+}
+
+func (m *mqttSubscriber) ApplySceneCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		mac := strings.TrimPrefix(strings.TrimSuffix(msg.Topic(), "/scene/set"), m.mqttConfig.TopicPrefix+"/")
+
+		m.logger.DebugContext(ctx, "new apply scene message",
+			slog.String("device", mac),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		applySceneInput := &modelsservice.ApplySceneInput{
+			Mac:  mac,
+			Name: string(msg.Payload()),
+		}
+
+		err := m.service.ApplyScene(ctx, applySceneInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to apply scene", slog.Any("input", applySceneInput))
+			return
+		}
+	}
+}
+
+func (m *mqttSubscriber) UpdateSleepCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		mac := strings.TrimPrefix(strings.TrimSuffix(msg.Topic(), "/sleep/set"), m.mqttConfig.TopicPrefix+"/")
+
+		m.logger.DebugContext(ctx, "new update sleep message",
+			slog.String("device", mac),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		updateSleepInput := &modelsservice.UpdateSleepInput{
+			Mac:     mac,
+			Profile: string(msg.Payload()),
+		}
+
+		err := m.service.UpdateSleep(ctx, updateSleepInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to update sleep", slog.Any("input", updateSleepInput))
+			return
+		}
+	}
+}
+
+// ApplySceneToAllCommandTopic applies the scene to all the devices
+func (m *mqttSubscriber) ApplySceneToAllCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		m.logger.DebugContext(ctx, "new apply scene to all message",
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		applySceneToAllInput := &modelsservice.ApplySceneToAllInput{
+			Name: string(msg.Payload()),
+		}
+
+		err := m.service.ApplySceneToAll(ctx, applySceneToAllInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to apply scene to all devices",
+				slog.Any("err", err),
+				slog.Any("input", applySceneToAllInput))
+			return
+		}
+	}
+}
+
+// UpdateGroupCommandTopic maps the group/<name>/<property>/set topics on the fan-out to the members of the group
+func (m *mqttSubscriber) UpdateGroupCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		// group/<name>/<property>/set, the property may have several levels like display/switch
+		levels := strings.Split(strings.TrimPrefix(msg.Topic(), m.mqttConfig.TopicPrefix+"/"), "/")
+		if len(levels) < 4 {
+			m.logger.ErrorContext(ctx, "unknown group topic", slog.String("topic", msg.Topic()))
+			return
+		}
+
+		m.logger.DebugContext(ctx, "new update group message",
+			slog.String("group", levels[1]),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		updateGroupInput := &modelsservice.UpdateGroupInput{
+			Name:     levels[1],
+			Property: strings.Join(levels[2:len(levels)-1], "/"),
+			Value:    string(msg.Payload()),
+		}
+
+		err := m.service.UpdateGroup(ctx, updateGroupInput)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to update group",
+				slog.Any("err", err),
+				slog.Any("input", updateGroupInput))
+			return
+		}
+	}
+}
+
+// UpdateExternalTemperatureTopic receives the readings of the external sensor shared by the devices
+func (m *mqttSubscriber) UpdateExternalTemperatureTopic(ctx context.Context, macs []string) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		m.logger.DebugContext(ctx, "new external temperature message",
+			slog.Any("devices", macs),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		for _, mac := range macs {
+			updateExternalTemperatureInput := &modelsservice.UpdateExternalTemperatureInput{
+				Mac:     mac,
+				Payload: msg.Payload(),
+			}
+
+			err := m.service.UpdateExternalTemperature(ctx, updateExternalTemperatureInput)
+			if err != nil {
+				m.logger.ErrorContext(ctx, "failed to update external temperature",
+					slog.Any("err", err),
+					slog.String("device", mac),
+					slog.String("payload", string(msg.Payload())))
+			}
+		}
+	}
+}
+
+// UpdateContactTopic receives the states of the interlock contact shared by the devices. The topic identifies the contact.
+func (m *mqttSubscriber) UpdateContactTopic(ctx context.Context, macs []string) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		m.logger.DebugContext(ctx, "new contact message",
+			slog.Any("devices", macs),
+			slog.String("payload", string(msg.Payload())),
+			slog.String("topic", msg.Topic()))
+
+		for _, mac := range macs {
+			updateContactInput := &modelsservice.UpdateContactInput{
+				Mac:     mac,
+				Topic:   msg.Topic(),
+				Payload: msg.Payload(),
+			}
+
+			err := m.service.UpdateContact(ctx, updateContactInput)
+			if err != nil {
+				m.logger.ErrorContext(ctx, "failed to update contact",
+					slog.Any("err", err),
+					slog.String("device", mac),
+					slog.String("topic", msg.Topic()),
+					slog.String("payload", string(msg.Payload())))
+			}
+		}
+	}
+}
+
+// UpdateHomiePropertyCommandTopic maps the Homie <homie>/<mac>/climate/<property>/set topics on the service calls
+func (m *mqttSubscriber) UpdateHomiePropertyCommandTopic(ctx context.Context) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		// <mac>/climate/<property>/set
+		levels := strings.Split(strings.TrimPrefix(msg.Topic(), m.mqttConfig.HomieTopic+"/"), "/")
+		if len(levels) != 4 {
+			m.logger.ErrorContext(ctx, "unknown homie topic", slog.String("topic", msg.Topic()))
+			return
+		}
+		mac, property, payload := levels[0], levels[2], string(msg.Payload())
+
+		m.logger.DebugContext(ctx, "new homie property message",
+			slog.String("device", mac),
+			slog.String("property", property),
+			slog.String("payload", payload),
+			slog.String("topic", msg.Topic()))
+
+		var err error
+		switch property {
+		case "mode":
+			err = m.service.UpdateMode(ctx, &modelsservice.UpdateModeInput{Mac: mac, Mode: payload})
+		case "fan":
+			err = m.service.UpdateFanMode(ctx, &modelsservice.UpdateFanModeInput{Mac: mac, FanMode: payload})
+		case "swing":
+			err = m.service.UpdateSwingMode(ctx, &modelsservice.UpdateSwingModeInput{Mac: mac, SwingMode: payload})
+		case "target-temperature":
+			var temperature float64
+			temperature, err = strconv.ParseFloat(payload, 32)
+			if err != nil {
+				break
+			}
+			err = m.service.UpdateTemperature(ctx, &modelsservice.UpdateTemperatureInput{Mac: mac, Temperature: float32(temperature)})
+		case "target-temperature-low", "target-temperature-high":
+			var temperature float64
+			temperature, err = strconv.ParseFloat(payload, 32)
+			if err != nil {
+				break
+			}
+			value := float32(temperature)
+			updateTemperatureRangeInput := &modelsservice.UpdateTemperatureRangeInput{Mac: mac, High: &value}
+			if property == "target-temperature-low" {
+				updateTemperatureRangeInput = &modelsservice.UpdateTemperatureRangeInput{Mac: mac, Low: &value}
+			}
+			err = m.service.UpdateTemperatureRange(ctx, updateTemperatureRangeInput)
+		case "display":
+			status := "OFF"
+			if payload == "true" {
+				status = "ON"
+			}
+			err = m.service.UpdateDisplaySwitch(ctx, &modelsservice.UpdateDisplaySwitchInput{Mac: mac, Status: status})
+		default:
+			m.logger.ErrorContext(ctx, "unknown homie property", slog.String("topic", msg.Topic()))
+			return
+		}
+		if err != nil {
+			m.logger.ErrorContext(ctx, "failed to update homie property",
+				slog.Any("err", err),
+				slog.String("property", property),
+				slog.String("payload", payload))
+			return
+		}
+	}
+}
@@ -77,6 +77,38 @@ func (c *cache) ReadDeviceAuth(ctx context.Context, input *models.ReadDeviceAuth
 	return &models.ReadDeviceAuthReturn{Auth: *device.Auth}, nil
 }
 
+func (c *cache) UpsertDeviceFirmware(ctx context.Context, input *models.UpsertDeviceFirmwareInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.Firmware = &input.Firmware
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadDeviceFirmware(ctx context.Context, input *models.ReadDeviceFirmwareInput) (*models.ReadDeviceFirmwareReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.Firmware == nil {
+		return nil, models.ErrorDeviceFirmwareNotFound
+	}
+
+	return &models.ReadDeviceFirmwareReturn{Firmware: *device.Firmware}, nil
+}
+
 func (c *cache) UpsertAmbientTemp(ctx context.Context, input *models.UpsertAmbientTempInput) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -110,6 +142,40 @@ func (c *cache) ReadAmbientTemp(ctx context.Context, input *models.ReadAmbientTe
 	return &models.ReadAmbientTempReturn{Temperature: *device.DeviceStatus.AmbientTemp}, nil
 }
 
+func (c *cache) UpsertAmbientFilter(ctx context.Context, input *models.UpsertAmbientFilterInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.DeviceStatus.AmbientFilter = models.AmbientFilter{
+		Samples:  append([]float32(nil), input.Filter.Samples...),
+		Outliers: input.Filter.Outliers,
+	}
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadAmbientFilter(ctx context.Context, input *models.ReadAmbientFilterInput) (*models.ReadAmbientFilterReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	return &models.ReadAmbientFilterReturn{Filter: models.AmbientFilter{
+		Samples:  append([]float32(nil), device.DeviceStatus.AmbientFilter.Samples...),
+		Outliers: device.DeviceStatus.AmbientFilter.Outliers,
+	}}, nil
+}
+
 func (c *cache) UpsertDeviceStatusRaw(ctx context.Context, input *models.UpsertDeviceStatusRawInput) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -143,7 +209,39 @@ func (c *cache) ReadDeviceStatusRaw(ctx context.Context, input *models.ReadDevic
 	return &models.ReadDeviceStatusRawReturn{Status: *device.DeviceStatusRaw}, nil
 }
 
-func (c *cache) UpsertMqttModeMessage(ctx context.Context, input *models.UpsertMqttModeMessageInput) error {
+func (c *cache) UpsertDeviceInfoRaw(ctx context.Context, input *models.UpsertDeviceInfoRawInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.DeviceInfoRaw = &input.Info
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadDeviceInfoRaw(ctx context.Context, input *models.ReadDeviceInfoRawInput) (*models.ReadDeviceInfoRawReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.DeviceInfoRaw == nil {
+		return nil, models.ErrorDeviceInfoRawNotFound
+	}
+
+	return &models.ReadDeviceInfoRawReturn{Info: *device.DeviceInfoRaw}, nil
+}
+
+func (c *cache) UpsertDeviceFaults(ctx context.Context, input *models.UpsertDeviceFaultsInput) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -153,12 +251,25 @@ func (c *cache) UpsertMqttModeMessage(ctx context.Context, input *models.UpsertM
 		return models.ErrorDeviceNotFound
 	}
 
-	device.MqttLastMessage.Mode = &input.Mode
+	device.Faults = append([]models.Fault(nil), input.Faults...)
 	c.devices[input.Mac] = device
 	return nil
 }
 
-func (c *cache) UpsertMqttSwingModeMessage(ctx context.Context, input *models.UpsertMqttSwingModeMessageInput) error {
+func (c *cache) ReadDeviceFaults(ctx context.Context, input *models.ReadDeviceFaultsInput) (*models.ReadDeviceFaultsReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	return &models.ReadDeviceFaultsReturn{Faults: append([]models.Fault(nil), device.Faults...)}, nil
+}
+
+func (c *cache) UpsertDesiredState(ctx context.Context, input *models.UpsertDesiredStateInput) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -168,12 +279,29 @@ func (c *cache) UpsertMqttSwingModeMessage(ctx context.Context, input *models.Up
 		return models.ErrorDeviceNotFound
 	}
 
-	device.MqttLastMessage.SwingMode = &input.SwingMode
+	device.DesiredState = &input.State
 	c.devices[input.Mac] = device
 	return nil
 }
 
-func (c *cache) UpsertMqttFanModeMessage(ctx context.Context, input *models.UpsertMqttFanModeMessageInput) error {
+func (c *cache) ReadDesiredState(ctx context.Context, input *models.ReadDesiredStateInput) (*models.ReadDesiredStateReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.DesiredState == nil {
+		return nil, models.ErrorDeviceDesiredStateNotFound
+	}
+
+	return &models.ReadDesiredStateReturn{State: *device.DesiredState}, nil
+}
+
+func (c *cache) UpsertExternalTemp(ctx context.Context, input *models.UpsertExternalTempInput) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -183,12 +311,29 @@ func (c *cache) UpsertMqttFanModeMessage(ctx context.Context, input *models.Upse
 		return models.ErrorDeviceNotFound
 	}
 
-	device.MqttLastMessage.FanMode = &input.FanMode
+	device.ExternalTemp = &input.Temp
 	c.devices[input.Mac] = device
 	return nil
 }
 
-func (c *cache) UpsertMqttTemperatureMessage(ctx context.Context, input *models.UpsertMqttTemperatureMessageInput) error {
+func (c *cache) ReadExternalTemp(ctx context.Context, input *models.ReadExternalTempInput) (*models.ReadExternalTempReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.ExternalTemp == nil {
+		return nil, models.ErrorDeviceExternalTempNotFound
+	}
+
+	return &models.ReadExternalTempReturn{Temp: *device.ExternalTemp}, nil
+}
+
+func (c *cache) UpsertThermostat(ctx context.Context, input *models.UpsertThermostatInput) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -198,12 +343,12 @@ func (c *cache) UpsertMqttTemperatureMessage(ctx context.Context, input *models.
 		return models.ErrorDeviceNotFound
 	}
 
-	device.MqttLastMessage.Temperature = &input.Temperature
+	device.Thermostat = &input.Thermostat
 	c.devices[input.Mac] = device
 	return nil
 }
 
-func (c *cache) ReadMqttMessage(ctx context.Context, input *models.ReadMqttMessageInput) (*models.ReadMqttMessageReturn, error) {
+func (c *cache) ReadThermostat(ctx context.Context, input *models.ReadThermostatInput) (*models.ReadThermostatReturn, error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
@@ -213,13 +358,171 @@ func (c *cache) ReadMqttMessage(ctx context.Context, input *models.ReadMqttMessa
 		return nil, models.ErrorDeviceNotFound
 	}
 
-	return &models.ReadMqttMessageReturn{
-		Temperature: device.MqttLastMessage.Temperature,
-		SwingMode:   device.MqttLastMessage.SwingMode,
-		FanMode:     device.MqttLastMessage.FanMode,
-		Mode:        device.MqttLastMessage.Mode,
-		IsDisplayOn: device.MqttLastMessage.DisplaySwitch,
-	}, nil
+	if device.Thermostat == nil {
+		return nil, models.ErrorDeviceThermostatNotFound
+	}
+
+	return &models.ReadThermostatReturn{Thermostat: *device.Thermostat}, nil
+}
+
+func (c *cache) UpsertHeatCool(ctx context.Context, input *models.UpsertHeatCoolInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.HeatCool = &input.HeatCool
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadHeatCool(ctx context.Context, input *models.ReadHeatCoolInput) (*models.ReadHeatCoolReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.HeatCool == nil {
+		return nil, models.ErrorDeviceHeatCoolNotFound
+	}
+
+	return &models.ReadHeatCoolReturn{HeatCool: *device.HeatCool}, nil
+}
+
+func (c *cache) UpsertInterlock(ctx context.Context, input *models.UpsertInterlockInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.Interlock = &input.Interlock
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadInterlock(ctx context.Context, input *models.ReadInterlockInput) (*models.ReadInterlockReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.Interlock == nil {
+		return nil, models.ErrorDeviceInterlockNotFound
+	}
+
+	return &models.ReadInterlockReturn{Interlock: *device.Interlock}, nil
+}
+
+func (c *cache) UpsertInterlockSwitch(ctx context.Context, input *models.UpsertInterlockSwitchInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.InterlockSwitch = &input.InterlockSwitch
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadInterlockSwitch(ctx context.Context, input *models.ReadInterlockSwitchInput) (*models.ReadInterlockSwitchReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.InterlockSwitch == nil {
+		return nil, models.ErrorDeviceInterlockSwitchNotFound
+	}
+
+	return &models.ReadInterlockSwitchReturn{InterlockSwitch: *device.InterlockSwitch}, nil
+}
+
+func (c *cache) UpsertCycle(ctx context.Context, input *models.UpsertCycleInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.Cycle = &input.Cycle
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadCycle(ctx context.Context, input *models.ReadCycleInput) (*models.ReadCycleReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.Cycle == nil {
+		return nil, models.ErrorDeviceCycleNotFound
+	}
+
+	return &models.ReadCycleReturn{Cycle: *device.Cycle}, nil
+}
+
+func (c *cache) UpsertHvacAction(ctx context.Context, input *models.UpsertHvacActionInput) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return models.ErrorDeviceNotFound
+	}
+
+	device.DeviceStatus.HvacAction = &input.HvacAction
+	c.devices[input.Mac] = device
+	return nil
+}
+
+func (c *cache) ReadHvacAction(ctx context.Context, input *models.ReadHvacActionInput) (*models.ReadHvacActionReturn, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	device, ok := c.devices[input.Mac]
+	if !ok {
+		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	if device.DeviceStatus.HvacAction == nil {
+		return nil, models.ErrorDeviceStatusHvacActionNotFound
+	}
+
+	return &models.ReadHvacActionReturn{HvacAction: *device.DeviceStatus.HvacAction}, nil
 }
 
 func (c *cache) UpsertDeviceAvailability(ctx context.Context, input *models.UpsertDeviceAvailabilityInput) error {
@@ -268,18 +571,3 @@ func (c *cache) ReadAuthedDevices(ctx context.Context) (*models.ReadAuthedDevice
 
 	return &models.ReadAuthedDevicesReturn{Macs: macs}, nil
 }
-
-func (c *cache) UpsertMqttDisplaySwitchMessage(ctx context.Context, input *models.UpsertMqttDisplaySwitchMessageInput) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	device, ok := c.devices[input.Mac]
-	if !ok {
-		c.logger.ErrorContext(ctx, "device is not found in cache", slog.Any("input", input))
-		return models.ErrorDeviceNotFound
-	}
-
-	device.MqttLastMessage.DisplaySwitch = &input.DisplaySwitch
-	c.devices[input.Mac] = device
-	return nil
-}
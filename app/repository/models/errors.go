@@ -5,8 +5,22 @@ import "errors"
 var (
 	ErrorDeviceNotFound                   = errors.New("ErrorDeviceNotFound")
 	ErrorDeviceAuthNotFound               = errors.New("ErrorDeviceAuthNotFound")
+	ErrorDeviceFirmwareNotFound           = errors.New("ErrorDeviceFirmwareNotFound")
 	ErrorDeviceStatusRawNotFound          = errors.New("ErrorDeviceStatusRawNotFound")
+	ErrorDeviceInfoRawNotFound            = errors.New("ErrorDeviceInfoRawNotFound")
 	ErrorDeviceStatusAvailabilityNotFound = errors.New("ErrorDeviceStatusAvailabilityNotFound")
 
 	ErrorDeviceStatusAmbientTempNotFound = errors.New("ErrorDeviceStatusAmbientTempNotFound")
+	ErrorDeviceStatusHvacActionNotFound  = errors.New("ErrorDeviceStatusHvacActionNotFound")
+	ErrorDeviceDesiredStateNotFound      = errors.New("ErrorDeviceDesiredStateNotFound")
+	ErrorDeviceExternalTempNotFound      = errors.New("ErrorDeviceExternalTempNotFound")
+	ErrorDeviceThermostatNotFound        = errors.New("ErrorDeviceThermostatNotFound")
+	ErrorDeviceHeatCoolNotFound          = errors.New("ErrorDeviceHeatCoolNotFound")
+	ErrorDeviceInterlockNotFound         = errors.New("ErrorDeviceInterlockNotFound")
+	ErrorDeviceInterlockSwitchNotFound   = errors.New("ErrorDeviceInterlockSwitchNotFound")
+	ErrorDeviceCycleNotFound             = errors.New("ErrorDeviceCycleNotFound")
+
+	ErrorScheduleStateNotFound = errors.New("ErrorScheduleStateNotFound")
+	ErrorTimerStateNotFound    = errors.New("ErrorTimerStateNotFound")
+	ErrorSleepStateNotFound    = errors.New("ErrorSleepStateNotFound")
 )
@@ -7,9 +7,18 @@ import (
 type Device struct {
 	Config          DeviceConfig
 	Auth            *DeviceAuth
+	Firmware        *DeviceFirmware
 	DeviceStatus    DeviceStatus
 	DeviceStatusRaw *DeviceStatusRaw
-	MqttLastMessage MqttStatus
+	DeviceInfoRaw   *DeviceInfoRaw
+	Faults          []Fault
+	DesiredState    *DesiredState
+	ExternalTemp    *ExternalTemp
+	Thermostat      *Thermostat
+	HeatCool        *HeatCool
+	Interlock       *Interlock
+	InterlockSwitch *InterlockSwitch
+	Cycle           *Cycle
 }
 
 type DeviceConfig struct {
@@ -18,6 +27,47 @@ type DeviceConfig struct {
 	Name            string
 	Port            uint16
 	TemperatureUnit string
+	MinTemp         float32
+	MaxTemp         float32
+
+	AmbientOffset           float32
+	AmbientOutlierThreshold float32
+	AmbientSmoothing        string
+	AmbientSamples          int
+	RestoreState            bool
+	EnforceMode             string
+	EnforceQuietStart       time.Duration
+	EnforceQuietEnd         time.Duration
+	EnforceMinTemp          *float32
+	EnforceMaxTemp          *float32
+	ExternalTempTopic       string
+	ExternalTempKey         string
+	ExternalTempMaxAge      time.Duration
+	ThermostatControl       string
+	ThermostatHysteresis    float32
+	ThermostatMaxOffset     float32
+	ThermostatInterval      time.Duration
+	HeatCool                bool
+	HeatCoolLow             float32
+	HeatCoolHigh            float32
+	HeatCoolDeadband        float32
+	HeatCoolMinDwell        time.Duration
+	ShortCycleMinOff        time.Duration
+	ShortCycleMinRun        time.Duration
+	ShortCycleAction        string
+	InterlockAction         string
+	InterlockGrace          time.Duration
+	NativeTimer             bool
+
+	PollInterval        time.Duration
+	AmbientPollInterval time.Duration
+	OfflineIntervals    int
+	CommandDelay        time.Duration
+	CommandDebounce     time.Duration
+	AdaptivePolling     bool
+	FastPollInterval    time.Duration
+	FastPollWindow      time.Duration
+	IdlePollInterval    time.Duration
 }
 
 type DeviceAuth struct {
@@ -28,6 +78,10 @@ type DeviceAuth struct {
 	Iv            []byte
 }
 
+type DeviceFirmware struct {
+	Version int
+}
+
 type DeviceStatusRaw struct {
 	UpdatedAt          time.Time
 	Temperature        float32
@@ -46,17 +100,181 @@ type DeviceStatusRaw struct {
 	Clean              byte
 }
 
+type DeviceInfoRaw struct {
+	UpdatedAt           time.Time
+	IndoorFanRunning    byte
+	CompressorRunning   byte
+	Defrost             byte
+	AmbientTemp         float32
+	IndoorCoilTemp      *float32
+	OutdoorCoilTemp     *float32
+	OutdoorTemp         *float32
+	CompressorFrequency byte
+	ErrorCode           byte
+	OutdoorErrorCode    byte
+}
+
+type Fault struct {
+	Kind      string
+	Code      int
+	Message   string
+	FirstSeen time.Time
+	ClearedAt *time.Time
+}
+
 type DeviceStatus struct {
-	Availability *string
-	AmbientTemp  *float32
+	Availability  *string
+	AmbientTemp   *float32
+	AmbientFilter AmbientFilter
+	HvacAction    *string
+}
+
+// DesiredState is the last state commanded via MQTT
+type DesiredState struct {
+	UpdatedAt   time.Time
+	FanMode     *string
+	SwingMode   *string
+	Mode        *string
+	Temperature *float32
+	IsDisplayOn *bool
+}
+
+type UpsertDesiredStateInput struct {
+	Mac   string
+	State DesiredState
+}
+
+type ReadDesiredStateInput struct {
+	Mac string
+}
+
+type ReadDesiredStateReturn struct {
+	State DesiredState
+}
+
+// ExternalTemp is the last reading of the external sensor in Celsius
+type ExternalTemp struct {
+	Temperature float32
+	UpdatedAt   time.Time
+}
+
+type UpsertExternalTempInput struct {
+	Mac  string
+	Temp ExternalTemp
+}
+
+type ReadExternalTempInput struct {
+	Mac string
+}
+
+type ReadExternalTempReturn struct {
+	Temp ExternalTemp
+}
+
+// Thermostat is the state of the virtual thermostat
+type Thermostat struct {
+	// IsIdle is set when the unit is switched off by the thermostat
+	IsIdle bool
+}
+
+type UpsertThermostatInput struct {
+	Mac        string
+	Thermostat Thermostat
 }
 
-type MqttStatus struct {
-	FanMode       *MqttFanModeMessage
-	SwingMode     *MqttSwingModeMessage
-	Mode          *MqttModeMessage
-	Temperature   *MqttTemperatureMessage
-	DisplaySwitch *MqttDisplaySwitchMessage
+type ReadThermostatInput struct {
+	Mac string
+}
+
+type ReadThermostatReturn struct {
+	Thermostat Thermostat
+}
+
+// HeatCool is the state of the heat_cool mode emulated by the bridge
+type HeatCool struct {
+	IsActive   bool
+	Low        float32
+	High       float32
+	SwitchedAt time.Time
+}
+
+type UpsertHeatCoolInput struct {
+	Mac      string
+	HeatCool HeatCool
+}
+
+type ReadHeatCoolInput struct {
+	Mac string
+}
+
+type ReadHeatCoolReturn struct {
+	HeatCool HeatCool
+}
+
+// Interlock keeps the states of the interlock contacts
+type Interlock struct {
+	// Contacts are the open states by the topics of the contacts
+	Contacts map[string]bool
+	// OpenedAt is the time when the first contact has been opened
+	OpenedAt time.Time
+}
+
+type UpsertInterlockInput struct {
+	Mac       string
+	Interlock Interlock
+}
+
+type ReadInterlockInput struct {
+	Mac string
+}
+
+type ReadInterlockReturn struct {
+	Interlock Interlock
+}
+
+// InterlockSwitch keeps the state of the unit switched by the interlock
+type InterlockSwitch struct {
+	IsActive bool
+	// Mode is the mode of the unit saved when it has been switched
+	Mode *string
+}
+
+type UpsertInterlockSwitchInput struct {
+	Mac             string
+	InterlockSwitch InterlockSwitch
+}
+
+type ReadInterlockSwitchInput struct {
+	Mac string
+}
+
+type ReadInterlockSwitchReturn struct {
+	InterlockSwitch InterlockSwitch
+}
+
+// Cycle keeps the times of the last start and stop of the unit
+type Cycle struct {
+	StartedAt time.Time
+	StoppedAt time.Time
+}
+
+type UpsertCycleInput struct {
+	Mac   string
+	Cycle Cycle
+}
+
+type ReadCycleInput struct {
+	Mac string
+}
+
+type ReadCycleReturn struct {
+	Cycle Cycle
+}
+
+// AmbientFilter keeps the last accepted ambient samples and the number of outliers in a row
+type AmbientFilter struct {
+	Samples  []float32
+	Outliers int
 }
 
 type ReadDeviceConfigInput struct {
@@ -84,6 +302,19 @@ type UpsertDeviceAuthInput struct {
 	Auth DeviceAuth
 }
 
+type UpsertDeviceFirmwareInput struct {
+	Mac      string
+	Firmware DeviceFirmware
+}
+
+type ReadDeviceFirmwareInput struct {
+	Mac string
+}
+
+type ReadDeviceFirmwareReturn struct {
+	Firmware DeviceFirmware
+}
+
 type UpsertAmbientTempInput struct {
 	Mac         string
 	Temperature float32
@@ -97,6 +328,19 @@ type ReadAmbientTempReturn struct {
 	Temperature float32
 }
 
+type UpsertAmbientFilterInput struct {
+	Mac    string
+	Filter AmbientFilter
+}
+
+type ReadAmbientFilterInput struct {
+	Mac string
+}
+
+type ReadAmbientFilterReturn struct {
+	Filter AmbientFilter
+}
+
 type ReadDeviceStatusRawInput struct {
 	Mac string
 }
@@ -110,66 +354,43 @@ type UpsertDeviceStatusRawInput struct {
 	Status DeviceStatusRaw
 }
 
-type MqttModeMessage struct {
-	UpdatedAt time.Time
-	Mode      string
-}
-
-type UpsertMqttModeMessageInput struct {
-	Mac  string
-	Mode MqttModeMessage
-}
-
-type MqttFanModeMessage struct {
-	UpdatedAt time.Time
-	FanMode   string
+type ReadDeviceInfoRawInput struct {
+	Mac string
 }
 
-type UpsertMqttFanModeMessageInput struct {
-	Mac     string
-	FanMode MqttFanModeMessage
+type ReadDeviceInfoRawReturn struct {
+	Info DeviceInfoRaw
 }
 
-type MqttDisplaySwitchMessage struct {
-	UpdatedAt   time.Time
-	IsDisplayOn bool
-}
-
-type UpsertMqttDisplaySwitchMessageInput struct {
-	Mac           string
-	DisplaySwitch MqttDisplaySwitchMessage
+type UpsertDeviceInfoRawInput struct {
+	Mac  string
+	Info DeviceInfoRaw
 }
 
-type MqttSwingModeMessage struct {
-	UpdatedAt time.Time
-	SwingMode string
+type UpsertDeviceFaultsInput struct {
+	Mac    string
+	Faults []Fault
 }
 
-type UpsertMqttSwingModeMessageInput struct {
-	Mac       string
-	SwingMode MqttSwingModeMessage
+type ReadDeviceFaultsInput struct {
+	Mac string
 }
 
-type MqttTemperatureMessage struct {
-	UpdatedAt   time.Time
-	Temperature float32
+type ReadDeviceFaultsReturn struct {
+	Faults []Fault
 }
 
-type UpsertMqttTemperatureMessageInput struct {
-	Mac         string
-	Temperature MqttTemperatureMessage
+type UpsertHvacActionInput struct {
+	Mac        string
+	HvacAction string
 }
 
-type ReadMqttMessageInput struct {
+type ReadHvacActionInput struct {
 	Mac string
 }
 
-type ReadMqttMessageReturn struct {
-	Temperature *MqttTemperatureMessage
-	SwingMode   *MqttSwingModeMessage
-	FanMode     *MqttFanModeMessage
-	Mode        *MqttModeMessage
-	IsDisplayOn *MqttDisplaySwitchMessage
+type ReadHvacActionReturn struct {
+	HvacAction string
 }
 
 type UpsertDeviceAvailabilityInput struct {
@@ -185,6 +406,85 @@ type ReadDeviceAvailabilityReturn struct {
 	Availability string
 }
 
+// State is the part of the bridge state which is kept in the state file between the restarts
+type State struct {
+	// Schedules are indexed by <mac>/<schedule name>
+	Schedules map[string]ScheduleState `json:"schedules"`
+	// Timers are indexed by <mac>/<timer kind>
+	Timers map[string]TimerState `json:"timers"`
+	// Sleep curves are indexed by <mac>
+	Sleep map[string]SleepState `json:"sleep"`
+}
+
+type ScheduleState struct {
+	Enabled bool       `json:"enabled"`
+	NextRun time.Time  `json:"next_run"`
+	LastRun *time.Time `json:"last_run,omitempty"`
+}
+
+type UpsertScheduleStateInput struct {
+	Mac   string
+	Name  string
+	State ScheduleState
+}
+
+type ReadScheduleStateInput struct {
+	Mac  string
+	Name string
+}
+
+type ReadScheduleStateReturn struct {
+	State ScheduleState
+}
+
+type TimerState struct {
+	At time.Time `json:"at"`
+}
+
+type UpsertTimerStateInput struct {
+	Mac   string
+	Kind  string
+	State TimerState
+}
+
+type ReadTimerStateInput struct {
+	Mac  string
+	Kind string
+}
+
+type ReadTimerStateReturn struct {
+	State TimerState
+}
+
+type DeleteTimerStateInput struct {
+	Mac  string
+	Kind string
+}
+
+type SleepState struct {
+	Profile   string    `json:"profile"`
+	StartedAt time.Time `json:"started_at"`
+	Base      float32   `json:"base"`
+	Step      int       `json:"step"`
+}
+
+type UpsertSleepStateInput struct {
+	Mac   string
+	State SleepState
+}
+
+type ReadSleepStateInput struct {
+	Mac string
+}
+
+type ReadSleepStateReturn struct {
+	State SleepState
+}
+
+type DeleteSleepStateInput struct {
+	Mac string
+}
+
 type ReadAuthedDevicesReturn struct {
 	Macs []string
 }
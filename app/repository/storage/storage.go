@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+)
+
+// storage keeps the state in memory and writes it to the JSON file on every change
+type storage struct {
+	path   string
+	state  models.State
+	mutex  *sync.RWMutex
+	logger *slog.Logger
+}
+
+// NewStorage loads the state file. A missing file is created on the first change.
+func NewStorage(logger *slog.Logger, path string) (app.Storage, error) {
+	s := &storage{
+		path:   path,
+		mutex:  new(sync.RWMutex),
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		logger.Error("failed to read the state file", slog.String("path", path), slog.Any("err", err))
+		return nil, err
+	default:
+		err = json.Unmarshal(data, &s.state)
+		if err != nil {
+			logger.Error("failed to parse the state file", slog.String("path", path), slog.Any("err", err))
+			return nil, err
+		}
+	}
+
+	if s.state.Schedules == nil {
+		s.state.Schedules = make(map[string]models.ScheduleState)
+	}
+	if s.state.Timers == nil {
+		s.state.Timers = make(map[string]models.TimerState)
+	}
+	if s.state.Sleep == nil {
+		s.state.Sleep = make(map[string]models.SleepState)
+	}
+
+	return s, nil
+}
+
+func (s *storage) UpsertScheduleState(ctx context.Context, input *models.UpsertScheduleStateInput) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state.Schedules[input.Mac+"/"+input.Name] = input.State
+	return s.save(ctx)
+}
+
+func (s *storage) ReadScheduleState(ctx context.Context, input *models.ReadScheduleStateInput) (*models.ReadScheduleStateReturn, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	state, ok := s.state.Schedules[input.Mac+"/"+input.Name]
+	if !ok {
+		return nil, models.ErrorScheduleStateNotFound
+	}
+
+	return &models.ReadScheduleStateReturn{State: state}, nil
+}
+
+func (s *storage) UpsertTimerState(ctx context.Context, input *models.UpsertTimerStateInput) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state.Timers[input.Mac+"/"+input.Kind] = input.State
+	return s.save(ctx)
+}
+
+func (s *storage) ReadTimerState(ctx context.Context, input *models.ReadTimerStateInput) (*models.ReadTimerStateReturn, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	state, ok := s.state.Timers[input.Mac+"/"+input.Kind]
+	if !ok {
+		return nil, models.ErrorTimerStateNotFound
+	}
+
+	return &models.ReadTimerStateReturn{State: state}, nil
+}
+
+func (s *storage) DeleteTimerState(ctx context.Context, input *models.DeleteTimerStateInput) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.state.Timers[input.Mac+"/"+input.Kind]; !ok {
+		return nil
+	}
+
+	delete(s.state.Timers, input.Mac+"/"+input.Kind)
+	return s.save(ctx)
+}
+
+func (s *storage) UpsertSleepState(ctx context.Context, input *models.UpsertSleepStateInput) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state.Sleep[input.Mac] = input.State
+	return s.save(ctx)
+}
+
+func (s *storage) ReadSleepState(ctx context.Context, input *models.ReadSleepStateInput) (*models.ReadSleepStateReturn, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	state, ok := s.state.Sleep[input.Mac]
+	if !ok {
+		return nil, models.ErrorSleepStateNotFound
+	}
+
+	return &models.ReadSleepStateReturn{State: state}, nil
+}
+
+func (s *storage) DeleteSleepState(ctx context.Context, input *models.DeleteSleepStateInput) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.state.Sleep[input.Mac]; !ok {
+		return nil
+	}
+
+	delete(s.state.Sleep, input.Mac)
+	return s.save(ctx)
+}
+
+// save writes the state to a temporary file and renames it, so the state file is never left half-written
+func (s *storage) save(ctx context.Context) error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to marshal the state", slog.Any("err", err))
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to create the state file", slog.String("path", s.path), slog.Any("err", err))
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to write the state file", slog.String("path", s.path), slog.Any("err", err))
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to replace the state file", slog.String("path", s.path), slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
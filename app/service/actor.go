@@ -0,0 +1,557 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+const (
+	// commandsBuffer is the number of the commands which can wait for the device actor
+	commandsBuffer = 16
+	// commandCorrelationWindow is the time after a set frame in which the changes matching it are attributed to the bridge
+	commandCorrelationWindow = time.Second * 10
+)
+
+// deviceActor owns the communication with one device. The MQTT handlers post the commands to it
+// and StartDeviceMonitoring is the only goroutine which sends the frames to the device.
+type deviceActor struct {
+	commands chan models.UpdateDeviceStatesInput
+	// ready is set while the device is authorized and monitored, the commands are rejected otherwise,
+	// so they are neither queued for an offline device nor replayed stale when it is back
+	ready     atomic.Bool
+	schedules []models.Schedule
+	// timersChanged wakes up the timers of the device when a timer is set or canceled
+	timersChanged chan struct{}
+	contacts      []models.Contact
+	// interlockChanged wakes up the device actor when an interlock contact is opened or closed
+	interlockChanged chan struct{}
+	scenes           []models.Scene
+	sleepProfiles    []models.SleepProfile
+	// sleepChanged wakes up the sleep curve of the device when it is started or canceled
+	sleepChanged chan struct{}
+}
+
+func newDeviceActor(schedules []models.Schedule, contacts []models.Contact, scenes []models.Scene, sleepProfiles []models.SleepProfile) *deviceActor {
+	return &deviceActor{
+		commands:         make(chan models.UpdateDeviceStatesInput, commandsBuffer),
+		schedules:        schedules,
+		timersChanged:    make(chan struct{}, 1),
+		contacts:         contacts,
+		interlockChanged: make(chan struct{}, 1),
+		scenes:           scenes,
+		sleepProfiles:    sleepProfiles,
+		sleepChanged:     make(chan struct{}, 1),
+	}
+}
+
+// deviceMonitor is the state of the device actor
+type deviceMonitor struct {
+	mac    string
+	config models.DeviceConfig
+
+	lastGetDeviceState, lastGetAmbientTemp time.Time
+	// lastActivity is the time of the last command or change by the IR remote for the adaptive polling
+	lastActivity time.Time
+	// lastCommand is the last command sent to the device, the changes which match it are attributed to the bridge
+	lastCommand   models.UpdateDeviceStatesInput
+	lastCommandAt time.Time
+	lastStatus    *models.DeviceStatusRaw
+
+	isDeviceAvailable bool
+	// wentOffline is set when the device has been marked offline, the desired state is checked when it is back
+	wentOffline bool
+	// correction is the command which restores the desired state or reverts the change by the IR remote
+	correction     *models.UpdateDeviceStatesInput
+	invalidPackets int
+	// thermostatAdjustedAt is the time of the last setpoint or power change by the virtual thermostat
+	thermostatAdjustedAt time.Time
+	// cycle is the times of the last start and stop of the unit, a copy of the one in the cache
+	cycle models.Cycle
+	// interlock is the state of the unit switched by the interlock, a copy of the one in the cache.
+	// interlockWaitUntil delays the switch which is not allowed by the compressor protection yet.
+	interlock          models.InterlockSwitch
+	interlockWaitUntil time.Time
+}
+
+func (s *service) readActor(mac string) (*deviceActor, error) {
+	s.actorsMutex.RLock()
+	defer s.actorsMutex.RUnlock()
+
+	actor, ok := s.actors[mac]
+	if !ok {
+		return nil, models.ErrorDeviceNotFound
+	}
+
+	return actor, nil
+}
+
+// postCommand sends the command to the device actor
+func (s *service) postCommand(ctx context.Context, command models.UpdateDeviceStatesInput) error {
+	actor, err := s.readActor(command.Mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find the device actor",
+			slog.Any("err", err),
+			slog.String("device", command.Mac))
+		return err
+	}
+
+	if !actor.ready.Load() {
+		s.logger.ErrorContext(ctx, "the device is not ready for commands",
+			slog.String("device", command.Mac),
+			slog.Any("command", command))
+		return models.ErrorDeviceNotReady
+	}
+
+	// The MQTT handlers run one after another, so a full queue of one device must not stall the others
+	select {
+	case actor.commands <- command:
+		return nil
+	default:
+		s.logger.ErrorContext(ctx, "the command queue of the device is full",
+			slog.String("device", command.Mac),
+			slog.Any("command", command))
+		return models.ErrorDeviceBusy
+	}
+}
+
+// setActorReady allows the commands to the device or rejects them and drops the queued ones
+func (s *service) setActorReady(mac string, isReady bool) {
+	actor, err := s.readActor(mac)
+	if err != nil {
+		return
+	}
+
+	actor.ready.Store(isReady)
+	if isReady {
+		return
+	}
+
+	for {
+		select {
+		case <-actor.commands:
+		default:
+			return
+		}
+	}
+}
+
+// StartDeviceMonitoring runs the device actor. It polls the device states and the ambient temperature,
+// and coalesces the commands received within the debounce window into one set frame.
+func (s *service) StartDeviceMonitoring(ctx context.Context, input *models.StartDeviceMonitoringInput) error {
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find the device actor",
+			slog.Any("err", err),
+			slog.String("device", input.Mac))
+		return err
+	}
+	// The device is authorized again before the actor is restarted
+	defer s.setActorReady(input.Mac, false)
+
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{Mac: input.Mac}
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceConfigInput))
+		return err
+	}
+
+	err = s.republishFaults(ctx, input.Mac)
+	if err != nil {
+		return err
+	}
+
+	config := models.DeviceConfig(readDeviceConfigReturn.Config)
+
+	// The interlock applied before a restart of the worker is kept
+	interlock, err := s.readInterlockSwitch(ctx, input.Mac)
+	if err != nil {
+		return err
+	}
+	if len(actor.contacts) != 0 {
+		err = s.publishInterlock(ctx, input.Mac, interlock.IsActive)
+		if err != nil {
+			return err
+		}
+	}
+	if config.HeatCool {
+		heatCool, err := s.readHeatCool(ctx, config)
+		if err != nil {
+			return err
+		}
+		err = s.publishTemperatureRange(ctx, config, heatCool)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The compressor protection goes on after a restart of the worker
+	cycle, err := s.readCycle(ctx, input.Mac)
+	if err != nil {
+		return err
+	}
+
+	m := &deviceMonitor{
+		mac:       input.Mac,
+		config:    config,
+		interlock: interlock,
+		cycle:     cycle,
+	}
+
+	var (
+		pending          *models.UpdateDeviceStatesInput
+		debounceDeadline time.Time
+		// held is the mode change delayed by the compressor protection until heldUntil, it does not delay the other commands
+		held      *models.UpdateDeviceStatesInput
+		heldUntil time.Time
+		// retryAt delays the next request after a failed one
+		retryAt time.Time
+	)
+
+	for {
+		var power byte
+		if m.lastStatus != nil {
+			power = m.lastStatus.Power
+		}
+
+		next := m.lastGetDeviceState.Add(m.config.StatePollInterval(power, m.lastActivity))
+		if ambientNext := m.lastGetAmbientTemp.Add(m.config.AmbientPollInterval); ambientNext.Before(next) {
+			next = ambientNext
+		}
+		if pending != nil && debounceDeadline.Before(next) {
+			next = debounceDeadline
+		}
+		if held != nil && heldUntil.Before(next) {
+			next = heldUntil
+		}
+		interlockAt, err := s.interlockDue(ctx, m)
+		if err != nil {
+			return err
+		}
+		if !interlockAt.IsZero() {
+			interlockAt = latest(interlockAt, m.interlockWaitUntil)
+			if interlockAt.Before(next) {
+				next = interlockAt
+			}
+		}
+		if next.Before(retryAt) {
+			next = retryAt
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case command := <-actor.commands:
+			timer.Stop()
+			if pending == nil {
+				pending = &models.UpdateDeviceStatesInput{Mac: input.Mac}
+				debounceDeadline = time.Now().Add(m.config.CommandDebounce)
+			}
+			pending.Merge(command)
+			continue
+		case <-actor.interlockChanged:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		switch {
+		case !interlockAt.IsZero() && !now.Before(interlockAt):
+			wait, err := s.applyInterlock(ctx, m)
+			if err != nil {
+				retryAt = time.Now().Add(m.config.CommandDelay)
+				continue
+			}
+			m.interlockWaitUntil = now.Add(wait)
+		case held != nil && !now.Before(heldUntil):
+			// The delayed mode is sent with the pending commands, a newer mode of them wins
+			command := *held
+			if pending == nil {
+				pending = &models.UpdateDeviceStatesInput{Mac: input.Mac}
+				debounceDeadline = now
+			}
+			command.Merge(*pending)
+			pending = &command
+			held = nil
+			continue
+		case pending != nil && !now.Before(debounceDeadline):
+			// The mode change which would short cycle the compressor is queued or rejected, the other states are applied.
+			// While the unit is switched by the interlock the mode is kept until it is restored.
+			command := *pending
+			var nextHeld *models.UpdateDeviceStatesInput
+			err = s.deferToInterlock(ctx, m, &command)
+			wait, reason := m.shortCycleWait(now, command)
+			if err == nil && wait > 0 {
+				nextHeld, err = s.holdCommand(ctx, m, &command, wait, reason)
+			}
+			if err == nil && command != (models.UpdateDeviceStatesInput{Mac: input.Mac}) {
+				err = s.applyCommand(ctx, m, command, true)
+			}
+			if err != nil {
+				// The command is kept and sent again with the next commands
+				retryAt = time.Now().Add(m.config.CommandDelay)
+				continue
+			}
+			// A newer mode replaces the delayed one
+			if pending.Mode != nil {
+				held = nextHeld
+				heldUntil = now.Add(wait)
+			}
+			pending = nil
+		case now.Sub(m.lastGetAmbientTemp) >= m.config.AmbientPollInterval:
+			err = s.GetDeviceAmbientTemperature(ctx, &models.GetDeviceAmbientTemperatureInput{Mac: m.mac})
+			s.updateProtocolFaultLogged(ctx, m, err)
+			// An outlier is counted by the filter, so the next reading is taken after the interval and not at once
+			if errors.Is(err, models.ErrorInvalidParameterTemperature) {
+				m.lastGetAmbientTemp = time.Now()
+				break
+			}
+			if err != nil {
+				s.logger.ErrorContext(ctx, "failed to get ambient temperature",
+					slog.Any("err", err),
+					slog.String("device", m.mac))
+				retryAt = time.Now().Add(m.config.CommandDelay)
+				continue
+			}
+			m.lastGetAmbientTemp = time.Now()
+		default:
+			err = s.pollDeviceStates(ctx, m, false)
+			if err != nil {
+				retryAt = time.Now().Add(m.config.CommandDelay)
+				continue
+			}
+
+			// The heat_cool mode and the virtual thermostat wait for the commands, the queued mode and the corrections to be applied first,
+			// and do nothing while the unit is switched by the interlock.
+			// The switches of the heat_cool mode are stored as the desired state, the thermostat adjustments are not.
+			if pending == nil && held == nil && m.correction == nil && !m.interlock.IsActive {
+				command, err := s.heatCoolCommand(ctx, m)
+				isDesired := command != nil
+				if err == nil && command == nil {
+					command, err = s.thermostatCommand(ctx, m)
+				}
+				if err == nil && command != nil {
+					err = s.applyCommand(ctx, m, *command, isDesired)
+				}
+				if err == nil && isDesired {
+					err = s.switchHeatCool(ctx, m, *command)
+				}
+				if err != nil {
+					retryAt = time.Now().Add(m.config.CommandDelay)
+					continue
+				}
+			}
+		}
+
+		// The correction is sent at once, the newer commands are applied over it
+		if m.correction != nil {
+			if pending == nil {
+				pending = &models.UpdateDeviceStatesInput{Mac: input.Mac}
+				debounceDeadline = time.Now()
+			}
+			correction := *m.correction
+			correction.Merge(*pending)
+			pending = &correction
+			m.correction = nil
+		}
+	}
+}
+
+// applyCommand sends the set frame and confirms it by reading back the states.
+// The commands of the virtual thermostat are not stored as the desired state.
+func (s *service) applyCommand(ctx context.Context, m *deviceMonitor, command models.UpdateDeviceStatesInput, isDesired bool) error {
+	// The set frame contains all the states, so they must be fresh
+	err := s.pollDeviceStates(ctx, m, true)
+	if err != nil {
+		return err
+	}
+
+	// A short pause before sending a new message to the air conditioner so that it does not hang
+	time.Sleep(m.config.CommandDelay)
+
+	err = s.UpdateDeviceStates(ctx, &command)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to update device states",
+			slog.Any("err", err),
+			slog.String("device", m.mac),
+			slog.Any("input", command))
+		return err
+	}
+	m.lastActivity = time.Now()
+	m.lastCommand = command
+	m.lastCommandAt = m.lastActivity
+
+	if isDesired {
+		err = s.updateDesiredState(ctx, command)
+		if err != nil {
+			return err
+		}
+	}
+
+	time.Sleep(m.config.CommandDelay)
+
+	// The command is already sent, so a failed confirmation is repeated by the regular polling
+	err = s.pollDeviceStates(ctx, m, true)
+	if err != nil {
+		m.lastGetDeviceState = time.Time{}
+	}
+
+	return nil
+}
+
+// pollDeviceStates requests the device states and updates the availability of the device.
+// The changes which match the last command are attributed to the bridge shortly after it, the other ones to the IR remote.
+func (s *service) pollDeviceStates(ctx context.Context, m *deviceMonitor, isCommand bool) error {
+	err := s.GetDeviceStates(ctx, &models.GetDeviceStatesInput{Mac: m.mac})
+	s.updateProtocolFaultLogged(ctx, m, err)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get AC States",
+			slog.Any("err", err),
+			slog.String("device", m.mac))
+
+		// If we cannot receive data from the air conditioner within several intervals,
+		// then we send the status that the air conditioner is unavailable
+		if time.Since(m.lastGetDeviceState) > m.config.PollInterval*time.Duration(m.config.OfflineIntervals) && m.isDeviceAvailable {
+			s.updateDeviceAvailabilityLogged(ctx, m.mac, models.StatusOffline)
+			m.isDeviceAvailable = false
+			m.wentOffline = true
+		}
+		return err
+	}
+
+	m.lastGetDeviceState = time.Now()
+
+	status, err := s.readDeviceStatusRaw(ctx, m.mac)
+	if err != nil {
+		return err
+	}
+	if status != nil && (m.lastStatus == nil || !status.Equal(*m.lastStatus)) {
+		sources := m.changeSources(*status)
+
+		if m.lastStatus != nil {
+			err = s.updateCycle(ctx, m, *m.lastStatus, *status, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.publishChangeEvents(ctx, m, sources)
+		if err != nil {
+			return err
+		}
+
+		err = s.updateGroupStates(ctx, m.mac)
+		if err != nil {
+			return err
+		}
+
+		if len(sources[models.EventSourceRemote]) != 0 {
+			m.lastActivity = time.Now()
+
+			// The changes made while the unit is switched by the interlock are manual overrides, so they are kept
+			if !isCommand && !m.interlock.IsActive {
+				m.correction, err = s.enforceCommand(ctx, m, status)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	m.lastStatus = status
+
+	if !m.isDeviceAvailable {
+		s.updateDeviceAvailabilityLogged(ctx, m.mac, models.StatusOnline)
+		m.isDeviceAvailable = true
+
+		if m.wentOffline && m.config.RestoreState && !isCommand && !m.interlock.IsActive {
+			m.correction, err = s.restoreCommand(ctx, m.mac, status)
+			if err != nil {
+				return err
+			}
+		}
+		m.wentOffline = false
+	}
+
+	return nil
+}
+
+func (s *service) updateDeviceAvailabilityLogged(ctx context.Context, mac string, availability string) {
+	updateDeviceAvailabilityInput := &models.UpdateDeviceAvailabilityInput{
+		Mac:          mac,
+		Availability: availability,
+	}
+	err := s.UpdateDeviceAvailability(ctx, updateDeviceAvailabilityInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to update device availability",
+			slog.Any("err", err),
+			slog.String("device", mac),
+			slog.Any("input", updateDeviceAvailabilityInput))
+	}
+}
+
+func (s *service) updateProtocolFaultLogged(ctx context.Context, m *deviceMonitor, err error) {
+	faultErr := s.updateProtocolFault(ctx, m.mac, err, &m.invalidPackets)
+	if faultErr != nil {
+		s.logger.ErrorContext(ctx, "failed to update the communication fault",
+			slog.Any("err", faultErr),
+			slog.String("device", m.mac))
+	}
+}
+
+func (s *service) readDeviceConfig(ctx context.Context, mac string) (models.DeviceConfig, error) {
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{Mac: mac}
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.String("device", mac),
+			slog.Any("input", readDeviceConfigInput))
+		return models.DeviceConfig{}, err
+	}
+
+	return models.DeviceConfig(readDeviceConfigReturn.Config), nil
+}
+
+// readDeviceStatusRaw returns the cached raw status or nil when the device has not answered yet
+func (s *service) readDeviceStatusRaw(ctx context.Context, mac string) (*models.DeviceStatusRaw, error) {
+	readDeviceStatusRawInput := &modelsRepo.ReadDeviceStatusRawInput{Mac: mac}
+	readDeviceStatusRawReturn, err := s.cache.ReadDeviceStatusRaw(ctx, readDeviceStatusRawInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceStatusRawNotFound) {
+			return nil, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the device status",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceStatusRawInput))
+		return nil, err
+	}
+
+	status := models.DeviceStatusRaw(readDeviceStatusRawReturn.Status)
+	return &status, nil
+}
+
+// latest returns the later of the times
+func latest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return b
+	}
+	return a
+}
+
+// earliest returns the earlier of the times
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// changeSources returns the changed states by their source. Shortly after a set frame only the changes
+// which match the states of the last command are attributed to the bridge, the other ones to the IR remote.
+func (m *deviceMonitor) changeSources(status models.DeviceStatusRaw) map[string]map[string]string {
+	statusHass := status.ConvertToDeviceStatusHass()
+	if m.lastStatus == nil {
+		return map[string]map[string]string{
+			models.EventSourceStartup: statusHass.Changes(nil, m.config.TemperatureUnit),
+		}
+	}
+
+	previous := m.lastStatus.ConvertToDeviceStatusHass()
+	isCorrelated := time.Since(m.lastCommandAt) < commandCorrelationWindow
+
+	sources := make(map[string]map[string]string)
+	for name, value := range statusHass.Changes(&previous, m.config.TemperatureUnit) {
+		source := models.EventSourceRemote
+		if isCorrelated && m.lastCommand.Sets(name, statusHass) {
+			source = models.EventSourceBridge
+		}
+		if sources[source] == nil {
+			sources[source] = make(map[string]string)
+		}
+		sources[source][name] = value
+	}
+
+	return sources
+}
+
+// publishChangeEvents publishes the changed states with their sources
+func (s *service) publishChangeEvents(ctx context.Context, m *deviceMonitor, sources map[string]map[string]string) error {
+	for _, source := range []string{models.EventSourceStartup, models.EventSourceBridge, models.EventSourceRemote} {
+		changes := sources[source]
+		if len(changes) == 0 {
+			continue
+		}
+
+		s.logger.InfoContext(ctx, "Device state is changed",
+			slog.String("device", m.mac),
+			slog.String("source", source),
+			slog.Any("changes", changes))
+
+		err := s.publishDeviceEvent(ctx, m.mac, models.EventTypeChanged, source, "", changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *service) publishDeviceEvent(ctx context.Context, mac, eventType, source, message string, changes map[string]string) error {
+	publishDeviceEventInput := &modelsMqtt.PublishDeviceEventInput{
+		Mac: mac,
+		Event: modelsMqtt.DeviceEvent{
+			Type:    eventType,
+			Source:  source,
+			Message: message,
+			Changes: changes,
+			Time:    time.Now(),
+		},
+	}
+	err := s.mqtt.PublishDeviceEvent(ctx, publishDeviceEventInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the device event",
+			slog.Any("err", err),
+			slog.Any("input", publishDeviceEventInput))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// updateFault raises the fault of the kind when isActive is true and clears it otherwise.
+// Only the changes of the fault list are published.
+func (s *service) updateFault(ctx context.Context, mac string, kind string, isActive bool, code int, message string) error {
+	readDeviceFaultsInput := &modelsRepo.ReadDeviceFaultsInput{Mac: mac}
+	readDeviceFaultsReturn, err := s.cache.ReadDeviceFaults(ctx, readDeviceFaultsInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read the device faults",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceFaultsInput))
+		return err
+	}
+
+	faults := make([]models.Fault, 0, len(readDeviceFaultsReturn.Faults)+1)
+	index := -1
+	for i, fault := range readDeviceFaultsReturn.Faults {
+		faults = append(faults, models.Fault(fault))
+		if fault.Kind == kind {
+			index = i
+		}
+	}
+
+	var event string
+	switch {
+	case isActive && (index == -1 || faults[index].ClearedAt != nil):
+		fault := models.Fault{
+			Kind:      kind,
+			Code:      code,
+			Message:   message,
+			FirstSeen: time.Now(),
+		}
+		if index == -1 {
+			faults = append(faults, fault)
+		} else {
+			faults[index] = fault
+		}
+		event = models.FaultEventRaised
+
+		s.logger.WarnContext(ctx, "device fault is raised",
+			slog.String("device", mac),
+			slog.Any("fault", fault))
+	case isActive && faults[index].Code != code:
+		faults[index].Code = code
+		faults[index].Message = message
+	case !isActive && index != -1 && faults[index].ClearedAt == nil:
+		clearedAt := time.Now()
+		faults[index].ClearedAt = &clearedAt
+		event = models.FaultEventCleared
+
+		s.logger.InfoContext(ctx, "device fault is cleared",
+			slog.String("device", mac),
+			slog.Any("fault", faults[index]))
+	default:
+		return nil
+	}
+
+	repoFaults := make([]modelsRepo.Fault, 0, len(faults))
+	for _, fault := range faults {
+		repoFaults = append(repoFaults, modelsRepo.Fault(fault))
+	}
+	upsertDeviceFaultsInput := &modelsRepo.UpsertDeviceFaultsInput{
+		Mac:    mac,
+		Faults: repoFaults,
+	}
+	err = s.cache.UpsertDeviceFaults(ctx, upsertDeviceFaultsInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the device faults",
+			slog.Any("err", err),
+			slog.Any("input", upsertDeviceFaultsInput))
+		return err
+	}
+
+	err = s.publishFaults(ctx, mac, faults)
+	if err != nil {
+		return err
+	}
+
+	if event == "" {
+		return nil
+	}
+
+	publishFaultEventInput := &modelsMqtt.PublishFaultEventInput{
+		Mac:   mac,
+		Kind:  kind,
+		Event: event,
+	}
+	err = s.mqtt.PublishFaultEvent(ctx, publishFaultEventInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the fault event",
+			slog.Any("err", err),
+			slog.Any("input", publishFaultEventInput))
+		return err
+	}
+
+	return nil
+}
+
+// publishFaults publishes the fault list and the problem state of every fault kind
+func (s *service) publishFaults(ctx context.Context, mac string, faults []models.Fault) error {
+	mqttFaults := make([]modelsMqtt.Fault, 0, len(faults))
+	activeFaults := make(map[string]bool, len(faults))
+	for _, fault := range faults {
+		mqttFaults = append(mqttFaults, modelsMqtt.Fault(fault))
+		if fault.ClearedAt == nil {
+			activeFaults[fault.Kind] = true
+		}
+	}
+
+	publishFaultsInput := &modelsMqtt.PublishFaultsInput{
+		Mac:    mac,
+		Faults: mqttFaults,
+	}
+	err := s.mqtt.PublishFaults(ctx, publishFaultsInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the device faults",
+			slog.Any("err", err),
+			slog.Any("input", publishFaultsInput))
+		return err
+	}
+
+	for _, kind := range models.FaultKinds {
+		status := "OFF"
+		if activeFaults[kind.Name] {
+			status = "ON"
+		}
+
+		publishFaultStateInput := &modelsMqtt.PublishFaultStateInput{
+			Mac:    mac,
+			Kind:   kind.Name,
+			Status: status,
+		}
+		err = s.mqtt.PublishFaultState(ctx, publishFaultStateInput)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to publish the fault state",
+				slog.Any("err", err),
+				slog.Any("input", publishFaultStateInput))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// republishFaults publishes the cached fault list again, e.g. on start or when Home Assistant is restarted
+func (s *service) republishFaults(ctx context.Context, mac string) error {
+	readDeviceFaultsInput := &modelsRepo.ReadDeviceFaultsInput{Mac: mac}
+	readDeviceFaultsReturn, err := s.cache.ReadDeviceFaults(ctx, readDeviceFaultsInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read the device faults",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceFaultsInput))
+		return err
+	}
+
+	faults := make([]models.Fault, 0, len(readDeviceFaultsReturn.Faults))
+	for _, fault := range readDeviceFaultsReturn.Faults {
+		faults = append(faults, models.Fault(fault))
+	}
+
+	return s.publishFaults(ctx, mac, faults)
+}
+
+// updateInfoFaults checks the error codes and the temperatures of the info frame
+func (s *service) updateInfoFaults(ctx context.Context, mac string, info models.DeviceInfoRaw) error {
+	err := s.updateFault(ctx, mac, models.FaultIndoorError, info.ErrorCode != 0, int(info.ErrorCode),
+		fmt.Sprintf("indoor unit reports error code %d", info.ErrorCode))
+	if err != nil {
+		return err
+	}
+
+	err = s.updateFault(ctx, mac, models.FaultOutdoorError, info.OutdoorErrorCode != 0, int(info.OutdoorErrorCode),
+		fmt.Sprintf("outdoor unit reports error code %d", info.OutdoorErrorCode))
+	if err != nil {
+		return err
+	}
+
+	var message string
+	switch {
+	case !isPlausibleTemperature(&info.AmbientTemp, models.PlausibleAmbientTempMin, models.PlausibleAmbientTempMax):
+		message = fmt.Sprintf("ambient temperature %.1f is out of range", info.AmbientTemp)
+	case !isPlausibleTemperature(info.OutdoorTemp, models.PlausibleOutdoorTempMin, models.PlausibleOutdoorTempMax):
+		message = fmt.Sprintf("outdoor temperature %.0f is out of range", *info.OutdoorTemp)
+	case !isPlausibleTemperature(info.IndoorCoilTemp, models.PlausibleCoilTempMin, models.PlausibleCoilTempMax):
+		message = fmt.Sprintf("indoor coil temperature %.0f is out of range", *info.IndoorCoilTemp)
+	case !isPlausibleTemperature(info.OutdoorCoilTemp, models.PlausibleCoilTempMin, models.PlausibleCoilTempMax):
+		message = fmt.Sprintf("outdoor coil temperature %.0f is out of range", *info.OutdoorCoilTemp)
+	}
+
+	return s.updateFault(ctx, mac, models.FaultSensor, message != "", 0, message)
+}
+
+func isPlausibleTemperature(temperature *float32, min, max float32) bool {
+	return temperature == nil || (*temperature >= min && *temperature <= max)
+}
+
+// updateProtocolFault counts the invalid packets in a row and raises the communication fault
+// when there are FaultInvalidPacketsLimit of them. A successful request clears the fault.
+func (s *service) updateProtocolFault(ctx context.Context, mac string, err error, invalidPackets *int) error {
+	if err == nil {
+		if *invalidPackets == 0 {
+			return nil
+		}
+		*invalidPackets = 0
+		return s.updateFault(ctx, mac, models.FaultCommunication, false, 0, "")
+	}
+
+	if !errors.Is(err, models.ErrorInvalidResultPacket) && !errors.Is(err, models.ErrorInvalidResultPacketLength) {
+		return nil
+	}
+
+	*invalidPackets++
+	if *invalidPackets < models.FaultInvalidPacketsLimit {
+		return nil
+	}
+
+	return s.updateFault(ctx, mac, models.FaultCommunication, true, 0,
+		fmt.Sprintf("%d invalid packets in a row: %s", *invalidPackets, err))
+}
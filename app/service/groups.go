@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/converter"
+)
+
+func (s *service) CreateGroup(ctx context.Context, input *models.CreateGroupInput) error {
+	s.groupsMutex.Lock()
+	s.groups[input.Group.Name] = input.Group
+	s.groupsMutex.Unlock()
+
+	return nil
+}
+
+func (s *service) readGroup(name string) (models.Group, error) {
+	s.groupsMutex.RLock()
+	defer s.groupsMutex.RUnlock()
+
+	group, ok := s.groups[name]
+	if !ok {
+		return models.Group{}, models.ErrorGroupNotFound
+	}
+
+	return group, nil
+}
+
+// UpdateGroup sends the command to every member of the group through the same calls as the device topics.
+// A failure of one member does not stop the others, and the result of every member is published to the result topic.
+func (s *service) UpdateGroup(ctx context.Context, input *models.UpdateGroupInput) error {
+	group, err := s.readGroup(input.Name)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "group is not found",
+			slog.Any("err", err),
+			slog.Any("input", input))
+		return err
+	}
+
+	var update func(mac string) error
+	switch input.Property {
+	case "mode":
+		update = func(mac string) error {
+			return s.UpdateMode(ctx, &models.UpdateModeInput{Mac: mac, Mode: input.Value})
+		}
+	case "fan_mode":
+		update = func(mac string) error {
+			return s.UpdateFanMode(ctx, &models.UpdateFanModeInput{Mac: mac, FanMode: input.Value})
+		}
+	case "swing_mode":
+		update = func(mac string) error {
+			return s.UpdateSwingMode(ctx, &models.UpdateSwingModeInput{Mac: mac, SwingMode: input.Value})
+		}
+	case "temp":
+		temperature, err := strconv.ParseFloat(input.Value, 32)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to parse the temperature",
+				slog.Any("err", err),
+				slog.Any("input", input))
+			return models.ErrorInvalidParameterTemperature
+		}
+		update = func(mac string) error {
+			return s.UpdateTemperature(ctx, &models.UpdateTemperatureInput{Mac: mac, Temperature: float32(temperature)})
+		}
+	case "display/switch":
+		update = func(mac string) error {
+			return s.UpdateDisplaySwitch(ctx, &models.UpdateDisplaySwitchInput{Mac: mac, Status: input.Value})
+		}
+	case "scene":
+		update = func(mac string) error {
+			return s.ApplyScene(ctx, &models.ApplySceneInput{Mac: mac, Name: input.Value})
+		}
+	case "sleep":
+		update = func(mac string) error {
+			return s.UpdateSleep(ctx, &models.UpdateSleepInput{Mac: mac, Profile: input.Value})
+		}
+	default:
+		s.logger.ErrorContext(ctx, "unknown group property", slog.Any("input", input))
+		return models.ErrorInvalidParameterGroupProperty
+	}
+
+	result := modelsMqtt.GroupResult{
+		Property: input.Property,
+		Value:    input.Value,
+		Results:  make(map[string]string, len(group.Devices)),
+		Time:     time.Now(),
+	}
+	var errs []error
+	for _, mac := range group.Devices {
+		err = update(mac)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to update the group member",
+				slog.Any("err", err),
+				slog.String("device", mac),
+				slog.Any("input", input))
+			result.Results[mac] = err.Error()
+			errs = append(errs, err)
+			continue
+		}
+		result.Results[mac] = "ok"
+	}
+
+	publishGroupResultInput := &modelsMqtt.PublishGroupResultInput{
+		Name:   group.Name,
+		Result: result,
+	}
+	err = s.mqtt.PublishGroupResult(ctx, publishGroupResultInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the group result",
+			slog.Any("err", err),
+			slog.Any("input", publishGroupResultInput))
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// updateGroupStates publishes the aggregated states of the groups of the device
+func (s *service) updateGroupStates(ctx context.Context, mac string) error {
+	s.groupsMutex.RLock()
+	groups := make([]models.Group, 0)
+	for _, group := range s.groups {
+		for _, member := range group.Devices {
+			if member == mac {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	s.groupsMutex.RUnlock()
+
+	for _, group := range groups {
+		members := make([]models.GroupMember, 0, len(group.Devices))
+		for _, member := range group.Devices {
+			status, err := s.readDeviceStatusRaw(ctx, member)
+			if err != nil {
+				return err
+			}
+			availability, err := s.readDeviceAvailability(ctx, member)
+			if err != nil {
+				return err
+			}
+			members = append(members, models.GroupMember{Status: status, IsAvailable: availability == models.StatusOnline})
+		}
+
+		publishGroupStateInput := &modelsMqtt.PublishGroupStateInput{
+			Name:  group.Name,
+			State: models.GroupState(members),
+		}
+		err := s.mqtt.PublishGroupState(ctx, publishGroupStateInput)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to publish the group state",
+				slog.Any("err", err),
+				slog.Any("input", publishGroupStateInput))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishGroupDiscoveryTopic publishes the climate entity and the state sensor of the group.
+// The climate entity has no state topics, as the members may differ, so Home Assistant shows the last command.
+// The setpoint range and the temperature unit are taken from the first member.
+func (s *service) PublishGroupDiscoveryTopic(ctx context.Context, input *models.PublishGroupDiscoveryTopicInput) error {
+	group, err := s.readGroup(input.Name)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "group is not found",
+			slog.Any("err", err),
+			slog.Any("input", input))
+		return err
+	}
+	if !group.Discovery {
+		return nil
+	}
+
+	config, err := s.readDeviceConfig(ctx, group.Devices[0])
+	if err != nil {
+		return err
+	}
+
+	prefix := s.topicPrefix + "/group/" + group.Name
+
+	device := modelsMqtt.DiscoveryTopicDevice{
+		Model: "AirCon group",
+		Mf:    "broadlink",
+		Ids:   "group_" + group.Name,
+		Name:  group.Name,
+	}
+
+	availability := modelsMqtt.DiscoveryTopicAvailability{
+		PayloadAvailable:    models.StatusOnline,
+		PayloadNotAvailable: models.StatusOffline,
+		Topic:               prefix + "/availability/value",
+	}
+
+	swingModes := make([]string, 0, len(models.VerticalFixationStatusesInvert))
+	for swingMode := range models.VerticalFixationStatusesInvert {
+		swingModes = append(swingModes, swingMode)
+	}
+
+	publishClimateDiscoveryTopicInput := modelsMqtt.PublishClimateDiscoveryTopicInput{
+		Topic: modelsMqtt.ClimateDiscoveryTopic{
+			FanModeCommandTopic:     prefix + "/fan_mode/set",
+			FanModes:                []string{"auto", "low", "medium", "high", "turbo", "mute"},
+			ModeCommandTopic:        prefix + "/mode/set",
+			Modes:                   []string{"auto", "off", "cool", "heat", "dry", "fan_only"},
+			SwingModeCommandTopic:   prefix + "/swing_mode/set",
+			SwingModes:              swingModes,
+			MinTemp:                 converter.SetpointFromCelsius(config.TemperatureUnit, config.MinTemp),
+			MaxTemp:                 converter.SetpointFromCelsius(config.TemperatureUnit, config.MaxTemp),
+			TempStep:                converter.SetpointStep(config.TemperatureUnit),
+			TemperatureCommandTopic: prefix + "/temp/set",
+			Precision:               converter.Precision(config.TemperatureUnit),
+			Device:                  device,
+			UniqueId:                "group_" + group.Name + "_ac",
+			Availability:            availability,
+			Name:                    nil,
+			Icon:                    "mdi:air-conditioner",
+			TemperatureUnit:         config.TemperatureUnit,
+		},
+	}
+	err = s.mqtt.PublishClimateDiscoveryTopic(ctx, publishClimateDiscoveryTopicInput)
+	if err != nil {
+		return err
+	}
+
+	publishSensorDiscoveryTopicInput := modelsMqtt.PublishSensorDiscoveryTopicInput{
+		Topic: modelsMqtt.SensorDiscoveryTopic{
+			Device:       device,
+			Name:         "State",
+			UniqueId:     "group_" + group.Name + "_state",
+			StateTopic:   prefix + "/state/value",
+			Availability: availability,
+			Icon:         "mdi:air-conditioner",
+		},
+	}
+	err = s.mqtt.PublishSensorDiscoveryTopic(ctx, publishSensorDiscoveryTopicInput)
+	if err != nil {
+		return err
+	}
+
+	// The group is commanded by the bridge, so it is available while the bridge runs
+	return s.UpdateGroupAvailability(ctx, &models.UpdateGroupAvailabilityInput{
+		Name:         group.Name,
+		Availability: models.StatusOnline,
+	})
+}
+
+// UpdateGroupAvailability publishes the availability of the entities of the group.
+// The group without discovery has no entities, so nothing is published.
+func (s *service) UpdateGroupAvailability(ctx context.Context, input *models.UpdateGroupAvailabilityInput) error {
+	group, err := s.readGroup(input.Name)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "group is not found",
+			slog.Any("err", err),
+			slog.Any("input", input))
+		return err
+	}
+	if !group.Discovery {
+		return nil
+	}
+
+	publishGroupAvailabilityInput := &modelsMqtt.PublishGroupAvailabilityInput{
+		Name:         group.Name,
+		Availability: input.Availability,
+	}
+	err = s.mqtt.PublishGroupAvailability(ctx, publishGroupAvailabilityInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the group availability",
+			slog.Any("err", err),
+			slog.Any("input", publishGroupAvailabilityInput))
+		return err
+	}
+
+	return nil
+}
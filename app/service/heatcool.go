@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/converter"
+)
+
+// updateHeatCoolMode starts the heat_cool mode. The unit is run in heat or cool depending on the room temperature,
+// and the actor switches it later.
+func (s *service) updateHeatCoolMode(ctx context.Context, mac string) error {
+	config, err := s.readDeviceConfig(ctx, mac)
+	if err != nil {
+		return err
+	}
+	if !config.HeatCool {
+		s.logger.ErrorContext(ctx, "the heat_cool mode is not enabled", slog.String("device", mac))
+		return models.ErrorInvalidParameterMode
+	}
+
+	heatCool, err := s.readHeatCool(ctx, config)
+	if err != nil {
+		return err
+	}
+	room, err := s.readRoomTemp(ctx, mac, config)
+	if err != nil {
+		return err
+	}
+
+	// The mode is started only when the device actor accepts the command
+	err = s.postCommand(ctx, heatCool.Command(mac, heatCool.Mode(room)))
+	if err != nil {
+		return err
+	}
+
+	heatCool.IsActive = true
+	heatCool.SwitchedAt = time.Now()
+	err = s.upsertHeatCool(ctx, mac, heatCool)
+	if err != nil {
+		return err
+	}
+
+	publishModeInput := &modelsMqtt.PublishModeInput{
+		Mac:  mac,
+		Mode: models.ModeHeatCool,
+	}
+	err = s.mqtt.PublishMode(ctx, publishModeInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish mode to mqtt",
+			slog.Any("err", err),
+			slog.String("device", mac),
+			slog.Any("input", publishModeInput))
+		return err
+	}
+
+	return nil
+}
+
+// stopHeatCool leaves the heat_cool mode when another mode is commanded
+func (s *service) stopHeatCool(ctx context.Context, mac string) error {
+	readHeatCoolInput := &modelsRepo.ReadHeatCoolInput{Mac: mac}
+	readHeatCoolReturn, err := s.cache.ReadHeatCool(ctx, readHeatCoolInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceHeatCoolNotFound) {
+			return nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the heat_cool state",
+			slog.Any("err", err),
+			slog.Any("input", readHeatCoolInput))
+		return err
+	}
+
+	if !readHeatCoolReturn.HeatCool.IsActive {
+		return nil
+	}
+
+	heatCool := models.HeatCool(readHeatCoolReturn.HeatCool)
+	heatCool.IsActive = false
+	return s.upsertHeatCool(ctx, mac, heatCool)
+}
+
+func (s *service) UpdateTemperatureRange(ctx context.Context, input *models.UpdateTemperatureRangeInput) error {
+	config, err := s.readDeviceConfig(ctx, input.Mac)
+	if err != nil {
+		return err
+	}
+	if !config.HeatCool {
+		s.logger.ErrorContext(ctx, "the heat_cool mode is not enabled", slog.String("device", input.Mac))
+		return models.ErrorInvalidParameterMode
+	}
+
+	heatCool, err := s.readHeatCool(ctx, config)
+	if err != nil {
+		return err
+	}
+	if input.Low != nil {
+		heatCool.Low = converter.SetpointToCelsius(config.TemperatureUnit, *input.Low)
+	}
+	if input.High != nil {
+		heatCool.High = converter.SetpointToCelsius(config.TemperatureUnit, *input.High)
+	}
+
+	err = heatCool.Validate(config.MinTemp, config.MaxTemp)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "input data is not valid",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", input))
+		return err
+	}
+
+	err = s.upsertHeatCool(ctx, input.Mac, heatCool)
+	if err != nil {
+		return err
+	}
+
+	// The unit gets the new target of the mode it runs in
+	if heatCool.IsActive {
+		status, err := s.readDeviceStatusRaw(ctx, input.Mac)
+		if err != nil {
+			return err
+		}
+		if status != nil {
+			mode := models.ModeStatuses[int(status.Mode)]
+			if status.Power == models.StatusOn && (mode == "heat" || mode == "cool") {
+				command := heatCool.Command(input.Mac, mode)
+				command.Mode = nil
+				err = s.postCommand(ctx, command)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return s.publishTemperatureRange(ctx, config, heatCool)
+}
+
+// heatCoolCommand returns the command which switches the unit between heat and cool in the heat_cool mode or nil.
+// The switch is stored by switchHeatCool once the command is applied.
+func (s *service) heatCoolCommand(ctx context.Context, m *deviceMonitor) (*models.UpdateDeviceStatesInput, error) {
+	if !m.config.HeatCool || m.lastStatus == nil {
+		return nil, nil
+	}
+
+	heatCool, err := s.readHeatCool(ctx, m.config)
+	if err != nil || !heatCool.IsActive {
+		return nil, err
+	}
+	room, err := s.readRoomTemp(ctx, m.mac, m.config)
+	if err != nil || room == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	command := m.config.HeatCoolCommand(now, *room, heatCool, *m.lastStatus)
+	if command == nil {
+		return nil, nil
+	}
+	// The switch is made on a later check when the protection allows it
+	if wait, _ := m.shortCycleWait(now, *command); wait > 0 {
+		return nil, nil
+	}
+
+	return command, nil
+}
+
+// switchHeatCool stores the time of the switch made by the heat_cool mode and publishes the event about it
+func (s *service) switchHeatCool(ctx context.Context, m *deviceMonitor, command models.UpdateDeviceStatesInput) error {
+	heatCool, err := s.readHeatCool(ctx, m.config)
+	if err != nil {
+		return err
+	}
+
+	heatCool.SwitchedAt = time.Now()
+	err = s.upsertHeatCool(ctx, m.mac, heatCool)
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "the unit is switched by the heat_cool mode",
+		slog.String("device", m.mac),
+		slog.Any("command", command))
+
+	return s.publishDeviceEvent(ctx, m.mac, models.EventTypeHeatCool, models.EventSourceBridge,
+		"the heat_cool mode switches the unit to "+*command.Mode, nil)
+}
+
+// applyHeatCool shows the heat_cool mode instead of the heat or cool mode of the unit which it runs
+func (s *service) applyHeatCool(ctx context.Context, config models.DeviceConfig, status *models.DeviceStatusHass) error {
+	if !config.HeatCool || (status.Mode != "heat" && status.Mode != "cool") {
+		return nil
+	}
+
+	heatCool, err := s.readHeatCool(ctx, config)
+	if err != nil {
+		return err
+	}
+	if heatCool.IsActive {
+		status.Mode = models.ModeHeatCool
+	}
+
+	return nil
+}
+
+// readRoomTemp returns the fresh reading of the external sensor or the ambient temperature of the unit in Celsius,
+// or nil when neither is known
+func (s *service) readRoomTemp(ctx context.Context, mac string, config models.DeviceConfig) (*float32, error) {
+	externalTemp, err := s.readExternalTemp(ctx, mac, config)
+	if err != nil || externalTemp != nil {
+		return externalTemp, err
+	}
+
+	readAmbientTempInput := &modelsRepo.ReadAmbientTempInput{Mac: mac}
+	readAmbientTempReturn, err := s.cache.ReadAmbientTemp(ctx, readAmbientTempInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceStatusAmbientTempNotFound) {
+			return nil, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the ambient temperature",
+			slog.Any("err", err),
+			slog.Any("input", readAmbientTempInput))
+		return nil, err
+	}
+
+	return &readAmbientTempReturn.Temperature, nil
+}
+
+// readHeatCool returns the state of the heat_cool mode. It starts with the targets from the config.
+func (s *service) readHeatCool(ctx context.Context, config models.DeviceConfig) (models.HeatCool, error) {
+	readHeatCoolInput := &modelsRepo.ReadHeatCoolInput{Mac: config.Mac}
+	readHeatCoolReturn, err := s.cache.ReadHeatCool(ctx, readHeatCoolInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceHeatCoolNotFound) {
+			return models.HeatCool{Low: config.HeatCoolLow, High: config.HeatCoolHigh}, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the heat_cool state",
+			slog.Any("err", err),
+			slog.Any("input", readHeatCoolInput))
+		return models.HeatCool{}, err
+	}
+
+	return models.HeatCool(readHeatCoolReturn.HeatCool), nil
+}
+
+func (s *service) upsertHeatCool(ctx context.Context, mac string, heatCool models.HeatCool) error {
+	upsertHeatCoolInput := &modelsRepo.UpsertHeatCoolInput{
+		Mac:      mac,
+		HeatCool: modelsRepo.HeatCool(heatCool),
+	}
+	err := s.cache.UpsertHeatCool(ctx, upsertHeatCoolInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the heat_cool state",
+			slog.Any("err", err),
+			slog.Any("input", upsertHeatCoolInput))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) publishTemperatureRange(ctx context.Context, config models.DeviceConfig, heatCool models.HeatCool) error {
+	publishTemperatureRangeInput := &modelsMqtt.PublishTemperatureRangeInput{
+		Mac:  config.Mac,
+		Low:  converter.SetpointFromCelsius(config.TemperatureUnit, heatCool.Low),
+		High: converter.SetpointFromCelsius(config.TemperatureUnit, heatCool.High),
+	}
+	err := s.mqtt.PublishTemperatureRange(ctx, publishTemperatureRangeInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the temperature range",
+			slog.Any("err", err),
+			slog.Any("input", publishTemperatureRangeInput))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// UpdateContact stores the state of the interlock contact and wakes up the device actor, which switches the unit
+func (s *service) UpdateContact(ctx context.Context, input *models.UpdateContactInput) error {
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		return err
+	}
+
+	var contact *models.Contact
+	for i := range actor.contacts {
+		if actor.contacts[i].Topic == input.Topic {
+			contact = &actor.contacts[i]
+		}
+	}
+	if contact == nil {
+		s.logger.ErrorContext(ctx, "contact is not found",
+			slog.String("device", input.Mac),
+			slog.String("topic", input.Topic))
+		return models.ErrorContactNotFound
+	}
+
+	isOpen, err := contact.IsOpen(input.Payload)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to parse the contact state",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.String("topic", input.Topic),
+			slog.String("payload", string(input.Payload)))
+		return err
+	}
+
+	interlock, err := s.readInterlock(ctx, input.Mac)
+	if err != nil {
+		return err
+	}
+	if interlock.Contacts[input.Topic] == isOpen {
+		return nil
+	}
+
+	wasOpen := interlock.IsOpen()
+	// The map is shared with the cache, so it is copied before the change
+	interlock.Contacts = maps.Clone(interlock.Contacts)
+	interlock.Contacts[input.Topic] = isOpen
+	if !wasOpen && isOpen {
+		interlock.OpenedAt = time.Now()
+	}
+
+	upsertInterlockInput := &modelsRepo.UpsertInterlockInput{
+		Mac:       input.Mac,
+		Interlock: modelsRepo.Interlock(interlock),
+	}
+	err = s.cache.UpsertInterlock(ctx, upsertInterlockInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the interlock",
+			slog.Any("err", err),
+			slog.Any("input", upsertInterlockInput))
+		return err
+	}
+
+	select {
+	case actor.interlockChanged <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// readInterlock returns the states of the interlock contacts. The contacts without a message are closed.
+func (s *service) readInterlock(ctx context.Context, mac string) (models.Interlock, error) {
+	readInterlockInput := &modelsRepo.ReadInterlockInput{Mac: mac}
+	readInterlockReturn, err := s.cache.ReadInterlock(ctx, readInterlockInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceInterlockNotFound) {
+			return models.Interlock{Contacts: map[string]bool{}}, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the interlock",
+			slog.Any("err", err),
+			slog.Any("input", readInterlockInput))
+		return models.Interlock{}, err
+	}
+
+	return models.Interlock(readInterlockReturn.Interlock), nil
+}
+
+// interlockDue returns the time when the interlock has to switch the unit or the zero time when there is nothing to do.
+// The unit is switched after the grace period once a contact is open, and is restored at once when all the contacts are closed.
+func (s *service) interlockDue(ctx context.Context, m *deviceMonitor) (time.Time, error) {
+	// The unit is not switched before its state is known
+	if m.lastStatus == nil {
+		return time.Time{}, nil
+	}
+
+	interlock, err := s.readInterlock(ctx, m.mac)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch isOpen := interlock.IsOpen(); {
+	case isOpen && !m.interlock.IsActive:
+		return interlock.OpenedAt.Add(m.config.InterlockGrace), nil
+	case !isOpen && m.interlock.IsActive:
+		return time.Now(), nil
+	}
+
+	return time.Time{}, nil
+}
+
+// applyInterlock switches the unit off or to the fan when a contact is open, and restores it when all are closed.
+// The mode is saved when the unit is switched and is restored, as the interlock changes only the mode.
+// It returns the time to wait when the compressor protection does not allow the switch yet.
+func (s *service) applyInterlock(ctx context.Context, m *deviceMonitor) (time.Duration, error) {
+	var (
+		command models.UpdateDeviceStatesInput
+		message string
+	)
+	if !m.interlock.IsActive {
+		mode := m.config.InterlockAction
+		command = models.UpdateDeviceStatesInput{Mac: m.mac, Mode: &mode}
+		message = "the unit is switched to " + mode + " as a contact is open"
+	} else {
+		// The unknown mode of the unit is not restored
+		if m.interlock.Mode != nil && *m.interlock.Mode != "error" {
+			command = models.UpdateDeviceStatesInput{Mac: m.mac, Mode: m.interlock.Mode}
+		}
+		message = "the unit is restored as all the contacts are closed"
+	}
+
+	// A unit which is already off is not switched, and nothing is restored when the unit has not been switched
+	isSwitched := command.Mode != nil && (m.interlock.IsActive || m.lastStatus.Power == models.StatusOn)
+	interlock := models.InterlockSwitch{IsActive: !m.interlock.IsActive}
+	if isSwitched {
+		now := time.Now()
+		if wait, _ := m.shortCycleWait(now, command); wait > 0 {
+			return wait, nil
+		}
+
+		// The mode is saved before the command changes it
+		mode := m.lastStatus.ConvertToDeviceStatusHass().Mode
+
+		err := s.applyCommand(ctx, m, command, false)
+		if err != nil {
+			return 0, err
+		}
+		if interlock.IsActive {
+			interlock.Mode = &mode
+		}
+	}
+
+	err := s.upsertInterlockSwitch(ctx, m, interlock)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.InfoContext(ctx, message,
+		slog.String("device", m.mac),
+		slog.Bool("switched", isSwitched))
+
+	err = s.publishInterlock(ctx, m.mac, m.interlock.IsActive)
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, s.publishDeviceEvent(ctx, m.mac, models.EventTypeInterlock, models.EventSourceContact, message, nil)
+}
+
+// deferToInterlock keeps the mode of the command while the unit is switched by the interlock.
+// The mode replaces the one of the saved status and is applied when the interlock restores the unit.
+func (s *service) deferToInterlock(ctx context.Context, m *deviceMonitor, command *models.UpdateDeviceStatesInput) error {
+	if !m.interlock.IsActive || command.Mode == nil {
+		return nil
+	}
+
+	s.logger.InfoContext(ctx, "the mode is kept until the interlock is released",
+		slog.String("device", m.mac),
+		slog.String("mode", *command.Mode))
+
+	err := s.updateDesiredState(ctx, models.UpdateDeviceStatesInput{Mac: m.mac, Mode: command.Mode})
+	if err != nil {
+		return err
+	}
+	// The unit which was already off is switched to the mode on the restore as well
+	err = s.upsertInterlockSwitch(ctx, m, models.InterlockSwitch{IsActive: true, Mode: command.Mode})
+	if err != nil {
+		return err
+	}
+	command.Mode = nil
+
+	// The mode has already been shown as commanded, so the current one is published again
+	return s.publishCurrentMode(ctx, m)
+}
+
+// readInterlockSwitch returns the state of the unit switched by the interlock. The interlock is not active without the state.
+func (s *service) readInterlockSwitch(ctx context.Context, mac string) (models.InterlockSwitch, error) {
+	readInterlockSwitchInput := &modelsRepo.ReadInterlockSwitchInput{Mac: mac}
+	readInterlockSwitchReturn, err := s.cache.ReadInterlockSwitch(ctx, readInterlockSwitchInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceInterlockSwitchNotFound) {
+			return models.InterlockSwitch{}, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the interlock switch",
+			slog.Any("err", err),
+			slog.Any("input", readInterlockSwitchInput))
+		return models.InterlockSwitch{}, err
+	}
+
+	return models.InterlockSwitch(readInterlockSwitchReturn.InterlockSwitch), nil
+}
+
+// upsertInterlockSwitch stores the state of the unit switched by the interlock and updates the copy of the actor
+func (s *service) upsertInterlockSwitch(ctx context.Context, m *deviceMonitor, interlock models.InterlockSwitch) error {
+	upsertInterlockSwitchInput := &modelsRepo.UpsertInterlockSwitchInput{
+		Mac:             m.mac,
+		InterlockSwitch: modelsRepo.InterlockSwitch(interlock),
+	}
+	err := s.cache.UpsertInterlockSwitch(ctx, upsertInterlockSwitchInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the interlock switch",
+			slog.Any("err", err),
+			slog.Any("input", upsertInterlockSwitchInput))
+		return err
+	}
+
+	m.interlock = interlock
+	return nil
+}
+
+func (s *service) publishInterlock(ctx context.Context, mac string, isActive bool) error {
+	publishInterlockInput := &modelsMqtt.PublishInterlockInput{
+		Mac:    mac,
+		Status: "OFF",
+	}
+	if isActive {
+		publishInterlockInput.Status = "ON"
+	}
+
+	err := s.mqtt.PublishInterlock(ctx, publishInterlockInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the interlock",
+			slog.Any("err", err),
+			slog.Any("input", publishInterlockInput))
+		return err
+	}
+
+	return nil
+}
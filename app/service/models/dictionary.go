@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 const (
 	StatusOn  byte = 1
 	StatusOff byte = 0
@@ -9,6 +11,217 @@ const (
 
 	Fahrenheit = "F"
 	Celsius    = "C"
+
+	// DefaultMinTemp and DefaultMaxTemp are the default setpoint range in Celsius
+	DefaultMinTemp float32 = 16
+	DefaultMaxTemp float32 = 32
+	// DeviceMinTemp and DeviceMaxTemp are the widest setpoint range in Celsius which the protocol supports
+	DeviceMinTemp float32 = 8
+	DeviceMaxTemp float32 = 32
+
+	AmbientSmoothingNone          = "none"
+	AmbientSmoothingMovingAverage = "moving_average"
+	AmbientSmoothingMedian        = "median"
+
+	// DefaultAmbientOutlierThreshold is the default largest jump of the ambient temperature in Celsius
+	DefaultAmbientOutlierThreshold float32 = 4
+	DefaultAmbientSamples                  = 5
+	// AmbientOutliersLimit is the number of outliers in a row after which the jump is accepted as a real change
+	AmbientOutliersLimit = 3
+
+	// The defaults of the polling in seconds and milliseconds
+	DefaultAmbientPollInterval = 180
+	DefaultOfflineIntervals    = 3
+	DefaultCommandDelay        = 500
+	DefaultCommandDebounce     = 300
+	DefaultFastPollInterval    = 2
+	DefaultFastPollWindow      = 60
+	DefaultIdlePollInterval    = 60
+
+	ThermostatControlNone     = "none"
+	ThermostatControlSetpoint = "setpoint"
+	ThermostatControlPower    = "power"
+
+	// The defaults of the external sensor and the virtual thermostat in seconds and Celsius
+	DefaultExternalTempMaxAge           = 900
+	DefaultThermostatHysteresis float32 = 0.5
+	DefaultThermostatMaxOffset  float32 = 3
+	DefaultThermostatInterval           = 300
+	// ThermostatStep is the change of the unit setpoint in one adjustment in Celsius
+	ThermostatStep float32 = 0.5
+
+	// ModeHeatCool is the mode emulated by the bridge, which switches the unit between heat and cool
+	ModeHeatCool = "heat_cool"
+	// The defaults of the heat_cool mode in seconds and Celsius
+	DefaultHeatCoolLow      float32 = 20
+	DefaultHeatCoolHigh     float32 = 24
+	DefaultHeatCoolDeadband float32 = 1
+	DefaultHeatCoolMinDwell         = 900
+
+	InterlockActionOff     = "off"
+	InterlockActionFanOnly = "fan_only"
+	// DefaultInterlockGrace is the grace period of the interlock in seconds
+	DefaultInterlockGrace = 30
+
+	ShortCycleActionQueue  = "queue"
+	ShortCycleActionReject = "reject"
+
+	EnforceModeOff        = "off"
+	EnforceModeAlways     = "always"
+	EnforceModeQuietHours = "quiet_hours"
+
+	// SleepOff cancels the sleep curve and is published when no curve runs
+	SleepOff = "OFF"
+
+	GroupStateAllOff  = "all_off"
+	GroupStateAll     = "all_"
+	GroupStateMixed   = "mixed"
+	GroupStateUnknown = "unknown"
+
+	EventTypeChanged    = "changed"
+	EventTypeOverridden = "overridden"
+	EventTypeScheduled  = "scheduled"
+	EventTypeTimer      = "timer"
+	EventTypeHeatCool   = "heat_cool"
+	EventTypeDelayed    = "delayed"
+	EventTypeRejected   = "rejected"
+	EventTypeInterlock  = "interlock"
+	EventTypeScene      = "scene"
+	EventTypeSleep      = "sleep"
+
+	EventSourceBridge   = "bridge"
+	EventSourceRemote   = "remote"
+	EventSourceStartup  = "startup"
+	EventSourceSchedule = "schedule"
+	EventSourceTimer    = "timer"
+	EventSourceContact  = "contact"
+	EventSourceMqtt     = "mqtt"
+
+	TimerOff = "off"
+	TimerOn  = "on"
+	// TimerMaxMinutes is the largest timer which can be set with the number entity
+	TimerMaxMinutes = 1440
+	// TimerMaxDelay is the latest end of a timer
+	TimerMaxDelay = time.Hour * 24 * 7
+	// TimerPublishInterval is the interval of the remaining time updates
+	TimerPublishInterval = time.Minute
+	// NativeTimerStep is the resolution of the native timer of the unit
+	NativeTimerStep = time.Minute * 15
+	// NativeTimerMaxDelay is the longest native timer, a longer one is run by the bridge only
+	NativeTimerMaxDelay = NativeTimerStep * 0x7f
+
+	// ScheduleCheckInterval is the longest sleep of the scheduler, so the changes of the switches are noticed
+	ScheduleCheckInterval = time.Minute
+	// CatchUpWindow is the longest time for which a schedule run, a sleep step or a timer missed while the bridge
+	// was stopped is still made at the start. The older ones are dropped.
+	CatchUpWindow = time.Minute * 15
+	// RetryInterval is the delay of the next attempt of a schedule run, a timer or a sleep step which has failed,
+	// e.g. as the device is not ready. The failure does not restart the device worker.
+	RetryInterval = time.Second * 10
+
+	HvacActionOff        = "off"
+	HvacActionIdle       = "idle"
+	HvacActionCooling    = "cooling"
+	HvacActionHeating    = "heating"
+	HvacActionDrying     = "drying"
+	HvacActionFan        = "fan"
+	HvacActionDefrosting = "defrosting"
+
+	DiagnosticAmbientTempRaw      = "ambient_temp_raw"
+	DiagnosticOutdoorTemp         = "outdoor_temp"
+	DiagnosticIndoorCoilTemp      = "indoor_coil_temp"
+	DiagnosticOutdoorCoilTemp     = "outdoor_coil_temp"
+	DiagnosticCompressorFrequency = "compressor_frequency"
+	DiagnosticErrorCode           = "error_code"
+	DiagnosticOutdoorErrorCode    = "outdoor_error_code"
+	DiagnosticCompressor          = "compressor"
+	DiagnosticIndoorFan           = "indoor_fan"
+	DiagnosticDefrost             = "defrost"
+)
+
+const (
+	FaultIndoorError   = "indoor_error"
+	FaultOutdoorError  = "outdoor_error"
+	FaultCommunication = "communication"
+	FaultSensor        = "sensor"
+
+	FaultEventRaised  = "raised"
+	FaultEventCleared = "cleared"
+
+	// FaultInvalidPacketsLimit is the number of invalid packets in a row which raises the communication fault
+	FaultInvalidPacketsLimit = 3
+
+	// Plausible temperature ranges in Celsius. The readings outside them raise the sensor fault
+	PlausibleAmbientTempMin float32 = -20
+	PlausibleAmbientTempMax float32 = 60
+	PlausibleOutdoorTempMin float32 = -45
+	PlausibleOutdoorTempMax float32 = 60
+	PlausibleCoilTempMin    float32 = -45
+	PlausibleCoilTempMax    float32 = 90
+)
+
+// Weekdays are the short names of the days of the schedules
+var Weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// TimerKind describes a timer for the discovery
+type TimerKind struct {
+	Name  string
+	Title string
+}
+
+// TimerKinds are the timers of every device
+var TimerKinds = []TimerKind{
+	{Name: TimerOff, Title: "Off timer"},
+	{Name: TimerOn, Title: "On timer"},
+}
+
+// FaultKind describes a fault type for the discovery
+type FaultKind struct {
+	Name  string
+	Title string
+}
+
+var (
+	FaultKinds = []FaultKind{
+		{Name: FaultIndoorError, Title: "Indoor unit fault"},
+		{Name: FaultOutdoorError, Title: "Outdoor unit fault"},
+		{Name: FaultCommunication, Title: "Communication fault"},
+		{Name: FaultSensor, Title: "Sensor fault"},
+	}
+)
+
+// Diagnostic describes a value of the extended status frame for the discovery
+type Diagnostic struct {
+	Name        string
+	Title       string
+	DeviceClass string
+	Unit        string
+	Icon        string
+	IsBinary    bool
+}
+
+var (
+	// Diagnostics are published as diagnostic sensors. The temperature sensors get the unit of the device.
+	Diagnostics = []Diagnostic{
+		{Name: DiagnosticAmbientTempRaw, Title: "Raw ambient temperature", DeviceClass: "temperature"},
+		{Name: DiagnosticOutdoorTemp, Title: "Outdoor temperature", DeviceClass: "temperature"},
+		{Name: DiagnosticIndoorCoilTemp, Title: "Indoor coil temperature", DeviceClass: "temperature"},
+		{Name: DiagnosticOutdoorCoilTemp, Title: "Outdoor coil temperature", DeviceClass: "temperature"},
+		{Name: DiagnosticCompressorFrequency, Title: "Compressor frequency", DeviceClass: "frequency", Unit: "Hz"},
+		{Name: DiagnosticErrorCode, Title: "Error code", Icon: "mdi:alert-circle-outline"},
+		{Name: DiagnosticOutdoorErrorCode, Title: "Outdoor error code", Icon: "mdi:alert-circle-outline"},
+		{Name: DiagnosticCompressor, Title: "Compressor", DeviceClass: "running", IsBinary: true},
+		{Name: DiagnosticIndoorFan, Title: "Indoor fan", DeviceClass: "running", IsBinary: true},
+		{Name: DiagnosticDefrost, Title: "Defrost", Icon: "mdi:snowflake-melt", IsBinary: true},
+	}
 )
 
 var (
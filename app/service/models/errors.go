@@ -3,12 +3,25 @@ package models
 import "errors"
 
 var (
+	ErrorDeviceNotFound       = errors.New("ErrorDeviceNotFound")
+	ErrorScheduleNotFound     = errors.New("ErrorScheduleNotFound")
+	ErrorContactNotFound      = errors.New("ErrorContactNotFound")
+	ErrorSceneNotFound        = errors.New("ErrorSceneNotFound")
+	ErrorGroupNotFound        = errors.New("ErrorGroupNotFound")
+	ErrorSleepProfileNotFound = errors.New("ErrorSleepProfileNotFound")
+
+	ErrorDeviceNotReady = errors.New("ErrorDeviceNotReady")
+	ErrorDeviceBusy     = errors.New("ErrorDeviceBusy")
+
 	ErrorInvalidResultPacket       = errors.New("ErrorInvalidResultPacket")
 	ErrorInvalidResultPacketLength = errors.New("ErrorInvalidResultPacketLength")
 
-	ErrorInvalidParameterTemperature   = errors.New("ErrorInvalidParameterTemperature")
-	ErrorInvalidParameterSwingMode     = errors.New("ErrorInvalidParameterSwingMode")
-	ErrorInvalidParameterFanMode       = errors.New("ErrorInvalidParameterFanMode")
-	ErrorInvalidParameterMode          = errors.New("ErrorInvalidParameterMode")
-	ErrorInvalidParameterDisplayStatus = errors.New("ErrorInvalidParameterDisplayStatus")
+	ErrorInvalidParameterTemperature    = errors.New("ErrorInvalidParameterTemperature")
+	ErrorInvalidParameterSwingMode      = errors.New("ErrorInvalidParameterSwingMode")
+	ErrorInvalidParameterFanMode        = errors.New("ErrorInvalidParameterFanMode")
+	ErrorInvalidParameterMode           = errors.New("ErrorInvalidParameterMode")
+	ErrorInvalidParameterDisplayStatus  = errors.New("ErrorInvalidParameterDisplayStatus")
+	ErrorInvalidParameterScheduleStatus = errors.New("ErrorInvalidParameterScheduleStatus")
+	ErrorInvalidParameterTimer          = errors.New("ErrorInvalidParameterTimer")
+	ErrorInvalidParameterGroupProperty  = errors.New("ErrorInvalidParameterGroupProperty")
 )
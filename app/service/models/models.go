@@ -1,8 +1,17 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/converter"
 )
 
 type Device struct {
@@ -16,6 +25,47 @@ type DeviceConfig struct {
 	Name            string
 	Port            uint16
 	TemperatureUnit string
+	MinTemp         float32
+	MaxTemp         float32
+
+	AmbientOffset           float32
+	AmbientOutlierThreshold float32
+	AmbientSmoothing        string
+	AmbientSamples          int
+	RestoreState            bool
+	EnforceMode             string
+	EnforceQuietStart       time.Duration
+	EnforceQuietEnd         time.Duration
+	EnforceMinTemp          *float32
+	EnforceMaxTemp          *float32
+	ExternalTempTopic       string
+	ExternalTempKey         string
+	ExternalTempMaxAge      time.Duration
+	ThermostatControl       string
+	ThermostatHysteresis    float32
+	ThermostatMaxOffset     float32
+	ThermostatInterval      time.Duration
+	HeatCool                bool
+	HeatCoolLow             float32
+	HeatCoolHigh            float32
+	HeatCoolDeadband        float32
+	HeatCoolMinDwell        time.Duration
+	ShortCycleMinOff        time.Duration
+	ShortCycleMinRun        time.Duration
+	ShortCycleAction        string
+	InterlockAction         string
+	InterlockGrace          time.Duration
+	NativeTimer             bool
+
+	PollInterval        time.Duration
+	AmbientPollInterval time.Duration
+	OfflineIntervals    int
+	CommandDelay        time.Duration
+	CommandDebounce     time.Duration
+	AdaptivePolling     bool
+	FastPollInterval    time.Duration
+	FastPollWindow      time.Duration
+	IdlePollInterval    time.Duration
 }
 
 func (input *DeviceConfig) Validate() error {
@@ -27,9 +77,505 @@ func (input *DeviceConfig) Validate() error {
 		return errors.New("unknown temperature unit")
 	}
 
+	if input.MinTemp < DeviceMinTemp || input.MaxTemp > DeviceMaxTemp || input.MinTemp >= input.MaxTemp {
+		return errors.New("setpoint range is wrong")
+	}
+
+	if input.AmbientSmoothing != AmbientSmoothingNone &&
+		input.AmbientSmoothing != AmbientSmoothingMovingAverage &&
+		input.AmbientSmoothing != AmbientSmoothingMedian {
+		return errors.New("unknown ambient smoothing")
+	}
+
+	if input.AmbientSamples < 1 || input.AmbientOutlierThreshold < 0 {
+		return errors.New("ambient settings are wrong")
+	}
+
+	if input.PollInterval <= 0 || input.AmbientPollInterval <= 0 || input.OfflineIntervals < 1 ||
+		input.CommandDelay < 0 || input.CommandDebounce < 0 {
+		return errors.New("polling settings are wrong")
+	}
+
+	if input.AdaptivePolling && (input.FastPollInterval <= 0 || input.FastPollWindow < 0 || input.IdlePollInterval <= 0) {
+		return errors.New("adaptive polling settings are wrong")
+	}
+
+	if input.EnforceMode != EnforceModeOff && input.EnforceMode != EnforceModeAlways && input.EnforceMode != EnforceModeQuietHours {
+		return errors.New("unknown enforce mode")
+	}
+
+	if input.EnforceMinTemp != nil && input.EnforceMaxTemp != nil && *input.EnforceMinTemp > *input.EnforceMaxTemp {
+		return errors.New("enforced setpoint range is wrong")
+	}
+
+	if input.ThermostatControl != ThermostatControlNone &&
+		input.ThermostatControl != ThermostatControlSetpoint &&
+		input.ThermostatControl != ThermostatControlPower {
+		return errors.New("unknown thermostat control")
+	}
+
+	if input.ThermostatControl != ThermostatControlNone && len(input.ExternalTempTopic) == 0 {
+		return errors.New("thermostat control requires the external sensor topic")
+	}
+
+	if input.ExternalTempMaxAge <= 0 || input.ThermostatHysteresis < 0 || input.ThermostatMaxOffset < 0 || input.ThermostatInterval < 0 {
+		return errors.New("external sensor settings are wrong")
+	}
+
+	if (input.InterlockAction != InterlockActionOff && input.InterlockAction != InterlockActionFanOnly) || input.InterlockGrace < 0 {
+		return errors.New("interlock settings are wrong")
+	}
+
+	if (input.ShortCycleAction != ShortCycleActionQueue && input.ShortCycleAction != ShortCycleActionReject) ||
+		input.ShortCycleMinOff < 0 || input.ShortCycleMinRun < 0 {
+		return errors.New("protection settings are wrong")
+	}
+
+	if input.HeatCool {
+		heatCool := HeatCool{Low: input.HeatCoolLow, High: input.HeatCoolHigh}
+		if heatCool.Validate(input.MinTemp, input.MaxTemp) != nil || input.HeatCoolDeadband < 0 || input.HeatCoolMinDwell < 0 {
+			return errors.New("heat_cool settings are wrong")
+		}
+	}
+
+	return nil
+}
+
+// EnforceCommand returns the command which reverts the change made by the IR remote, or nil when
+// the change is allowed. The setpoint limits are applied whatever the enforce mode is.
+func (input *DeviceConfig) EnforceCommand(now time.Time, desired *DesiredState, status DeviceStatusHass) *UpdateDeviceStatesInput {
+	command := UpdateDeviceStatesInput{Mac: input.Mac}
+
+	isEnforced := input.EnforceMode == EnforceModeAlways ||
+		(input.EnforceMode == EnforceModeQuietHours && IsInDailyWindow(now, input.EnforceQuietStart, input.EnforceQuietEnd))
+	if isEnforced && desired != nil && desired.Differs(status) {
+		command = desired.Command(input.Mac)
+	}
+
+	mode := status.Mode
+	if command.Mode != nil {
+		mode = *command.Mode
+	}
+	if mode != "off" {
+		temperature := status.Temperature
+		if command.Temperature != nil {
+			temperature = *command.Temperature
+		}
+		switch {
+		case input.EnforceMinTemp != nil && temperature < *input.EnforceMinTemp:
+			command.Temperature = input.EnforceMinTemp
+		case input.EnforceMaxTemp != nil && temperature > *input.EnforceMaxTemp:
+			command.Temperature = input.EnforceMaxTemp
+		}
+	}
+
+	if command == (UpdateDeviceStatesInput{Mac: input.Mac}) {
+		return nil
+	}
+	return &command
+}
+
+// ThermostatCommand returns the command of the virtual thermostat for the room temperature or nil when
+// the unit is left as it is, and whether the unit is idle, i.e. switched off by the thermostat.
+// The target is the last commanded setpoint. The setpoint control changes the unit setpoint
+// by one step when canAdjust is set, the power control switches the unit off and on in the heat and cool modes.
+func (input *DeviceConfig) ThermostatCommand(room float32, desired *DesiredState, status DeviceStatusRaw, isIdle, canAdjust bool) (*UpdateDeviceStatesInput, bool) {
+	if desired == nil || desired.Temperature == nil || desired.Mode == nil {
+		return nil, false
+	}
+	target, mode := *desired.Temperature, *desired.Mode
+
+	switch input.ThermostatControl {
+	case ThermostatControlSetpoint:
+		if status.Power != StatusOn || !canAdjust || (mode != "heat" && mode != "cool" && mode != "auto") {
+			return nil, false
+		}
+
+		var setpoint float32
+		switch {
+		case room > target+input.ThermostatHysteresis:
+			setpoint = status.Temperature - ThermostatStep
+		case room < target-input.ThermostatHysteresis:
+			setpoint = status.Temperature + ThermostatStep
+		default:
+			return nil, false
+		}
+
+		setpoint = max(setpoint, target-input.ThermostatMaxOffset, input.MinTemp)
+		setpoint = min(setpoint, target+input.ThermostatMaxOffset, input.MaxTemp)
+		if setpoint == status.Temperature {
+			return nil, false
+		}
+		return &UpdateDeviceStatesInput{Mac: input.Mac, Temperature: &setpoint}, false
+
+	case ThermostatControlPower:
+		var isSatisfied, isNeeded bool
+		switch mode {
+		case "heat":
+			isSatisfied, isNeeded = room >= target+input.ThermostatHysteresis, room <= target-input.ThermostatHysteresis
+		case "cool":
+			isSatisfied, isNeeded = room <= target-input.ThermostatHysteresis, room >= target+input.ThermostatHysteresis
+		default:
+			return nil, false
+		}
+
+		if status.Power == StatusOn {
+			if !isSatisfied {
+				return nil, false
+			}
+			off := "off"
+			return &UpdateDeviceStatesInput{Mac: input.Mac, Mode: &off}, true
+		}
+
+		// The unit switched off by the user is not switched on
+		if !isIdle || !isNeeded {
+			return nil, isIdle
+		}
+		return &UpdateDeviceStatesInput{Mac: input.Mac, Mode: &mode}, false
+	}
+
+	return nil, false
+}
+
+// HeatCoolCommand returns the command which switches the unit between heat and cool in the heat_cool mode or nil.
+// The unit is switched when the room temperature has passed the other target by the deadband
+// and the current mode has been kept for the minimum dwell time. Other modes set via the IR remote are left as they are.
+func (input *DeviceConfig) HeatCoolCommand(now time.Time, room float32, heatCool HeatCool, status DeviceStatusRaw) *UpdateDeviceStatesInput {
+	if !input.HeatCool || !heatCool.IsActive || status.Power != StatusOn || now.Sub(heatCool.SwitchedAt) < input.HeatCoolMinDwell {
+		return nil
+	}
+
+	switch ModeStatuses[int(status.Mode)] {
+	case "heat":
+		if room < heatCool.High+input.HeatCoolDeadband {
+			return nil
+		}
+		command := heatCool.Command(input.Mac, "cool")
+		return &command
+	case "cool":
+		if room > heatCool.Low-input.HeatCoolDeadband {
+			return nil
+		}
+		command := heatCool.Command(input.Mac, "heat")
+		return &command
+	}
+
 	return nil
 }
 
+// ShortCycleWait returns how long the power or mode change of the command has to wait to protect the compressor,
+// and the reason. The change is not delayed when the start or the stop of the unit is not known.
+func (input *DeviceConfig) ShortCycleWait(now time.Time, command UpdateDeviceStatesInput, status DeviceStatusRaw, cycle Cycle) (time.Duration, string) {
+	if command.Mode == nil {
+		return 0, ""
+	}
+
+	if status.Power != StatusOn {
+		if *command.Mode == "off" {
+			return 0, ""
+		}
+		return max(cycle.StoppedAt.Add(input.ShortCycleMinOff).Sub(now), 0), "minimum off time"
+	}
+
+	if *command.Mode == ModeStatuses[int(status.Mode)] {
+		return 0, ""
+	}
+	return max(cycle.StartedAt.Add(input.ShortCycleMinRun).Sub(now), 0), "minimum run time"
+}
+
+// Cycle is the times of the last start and stop of the unit which are used by the compressor protection.
+// It is kept in the cache, so a restart of the device worker does not reset the protection.
+type Cycle struct {
+	// StartedAt is the time when the unit has been switched on or to another mode
+	StartedAt time.Time
+	// StoppedAt is the time when the unit has been switched off
+	StoppedAt time.Time
+}
+
+// Update remembers the start or the stop of the unit between the statuses and reports whether the cycle is changed
+func (cycle *Cycle) Update(previous, status DeviceStatusRaw, now time.Time) bool {
+	switch {
+	case previous.Power != StatusOn && status.Power == StatusOn:
+		cycle.StartedAt = now
+	case previous.Power == StatusOn && status.Power != StatusOn:
+		cycle.StoppedAt = now
+	case status.Power == StatusOn && previous.Mode != status.Mode:
+		cycle.StartedAt = now
+	default:
+		return false
+	}
+	return true
+}
+
+// ParseExternalTemperature reads the temperature from the payload of the external sensor. The key is the field
+// of the JSON payload, nested fields are separated by dots. An empty key means a plain number payload.
+func ParseExternalTemperature(payload []byte, key string) (float32, error) {
+	var (
+		temperature float64
+		err         error
+	)
+	if len(key) == 0 {
+		temperature, err = strconv.ParseFloat(strings.TrimSpace(string(payload)), 32)
+	} else {
+		var value any
+		value, err = payloadField(payload, key)
+		if err != nil {
+			return 0, err
+		}
+
+		switch field := value.(type) {
+		case float64:
+			temperature = field
+		case string:
+			temperature, err = strconv.ParseFloat(field, 32)
+		default:
+			err = errors.New("temperature is not a number")
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// NaN and Inf are parsed from the strings
+	if math.IsNaN(temperature) || math.IsInf(temperature, 0) {
+		return 0, errors.New("temperature is not a finite number")
+	}
+	return float32(temperature), nil
+}
+
+// payloadField returns the field of the JSON payload. Nested fields are separated by dots.
+func payloadField(payload []byte, key string) (any, error) {
+	var value any
+	err := json.Unmarshal(payload, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range strings.Split(key, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, errors.New("field " + field + " is not found")
+		}
+		value, ok = object[field]
+		if !ok {
+			return nil, errors.New("field " + field + " is not found")
+		}
+	}
+
+	return value, nil
+}
+
+// Contact is a sensor of the interlock, e.g. a window contact or a presence sensor
+type Contact struct {
+	Topic string
+	// Key is the field of the JSON payload, nested fields are separated by dots. Empty means the whole payload
+	Key string
+	// Open is the value which means open
+	Open string
+}
+
+func (contact Contact) Validate() error {
+	if len(contact.Topic) == 0 || strings.ContainsAny(contact.Topic, "+#") {
+		return errors.New("contact topic must be set without wildcards")
+	}
+	if len(contact.Open) == 0 {
+		return errors.New("contact open value is not set")
+	}
+	return nil
+}
+
+// IsOpen reports whether the payload of the sensor means open. The values are compared case-insensitively.
+func (contact Contact) IsOpen(payload []byte) (bool, error) {
+	value := strings.TrimSpace(string(payload))
+	if len(contact.Key) != 0 {
+		field, err := payloadField(payload, contact.Key)
+		if err != nil {
+			return false, err
+		}
+		value = fmt.Sprint(field)
+	}
+
+	return strings.EqualFold(value, contact.Open), nil
+}
+
+// Interlock keeps the states of the interlock contacts
+type Interlock struct {
+	// Contacts are the open states by the topics of the contacts
+	Contacts map[string]bool
+	// OpenedAt is the time when the first contact has been opened
+	OpenedAt time.Time
+}
+
+// IsOpen reports whether any contact is open
+func (interlock Interlock) IsOpen() bool {
+	for _, isOpen := range interlock.Contacts {
+		if isOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// InterlockSwitch is the state of the unit switched by the interlock.
+// It is kept in the cache, so a restart of the device worker does not lose it.
+type InterlockSwitch struct {
+	// IsActive is set while a contact is open and the interlock is applied
+	IsActive bool
+	// Mode is the mode of the unit saved when it has been switched, or the mode commanded while it is switched.
+	// It is nil when the unit was already off.
+	Mode *string
+}
+
+type UpdateContactInput struct {
+	Mac     string
+	Topic   string
+	Payload []byte
+}
+
+// ParseDailyWindow parses the daily window like 22:00-07:00 into the offsets from the midnight
+func ParseDailyWindow(window string) (start, end time.Duration, err error) {
+	parts := strings.Split(window, "-")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("daily window must be like 22:00-07:00")
+	}
+
+	start, err = ParseTimeOfDay(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err = ParseTimeOfDay(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// ParseTimeOfDay parses the time like 07:30 into the offset from the midnight
+func ParseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsInDailyWindow reports whether the time is in the daily window. The window may cross the midnight.
+func IsInDailyWindow(now time.Time, start, end time.Duration) bool {
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+	if start <= end {
+		return offset >= start && offset < end
+	}
+	return offset >= start || offset < end
+}
+
+// Schedule is a weekly rule which sends the command at the time of day
+type Schedule struct {
+	Name string
+	// Days are the weekdays of the runs. Empty means every day
+	Days    []time.Weekday
+	At      time.Duration
+	Enabled bool
+	Command UpdateDeviceStatesInput
+	// Sleep is the sleep profile which is started after the command, if set
+	Sleep string
+}
+
+// Validate checks the schedule against the device. The temperature of the command is in Celsius.
+func (schedule Schedule) Validate(config DeviceConfig) error {
+	if len(schedule.Name) == 0 || strings.ContainsAny(schedule.Name, "/+#") {
+		return errors.New("schedule name is wrong")
+	}
+
+	if schedule.Command == (UpdateDeviceStatesInput{Mac: schedule.Command.Mac}) && len(schedule.Sleep) == 0 {
+		return errors.New("schedule has no states")
+	}
+
+	return schedule.Command.Validate(config.MinTemp, config.MaxTemp)
+}
+
+// NextRun returns the first run of the schedule after the time. The time of day is taken in the location of the time.
+func (schedule Schedule) NextRun(after time.Time) time.Time {
+	hour, minute := int(schedule.At/time.Hour), int(schedule.At%time.Hour/time.Minute)
+
+	for day := 0; day <= 7; day++ {
+		date := after.AddDate(0, 0, day)
+		run := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, after.Location())
+		if run.After(after) && schedule.isOnDay(run.Weekday()) {
+			return run
+		}
+	}
+
+	return time.Time{}
+}
+
+func (schedule Schedule) isOnDay(weekday time.Weekday) bool {
+	if len(schedule.Days) == 0 {
+		return true
+	}
+
+	for _, day := range schedule.Days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduleState is the persisted state of the schedule
+type ScheduleState struct {
+	Enabled bool
+	NextRun time.Time
+	LastRun *time.Time
+}
+
+// Scene is a named set of states which is applied to the device with one command
+type Scene struct {
+	Name    string
+	Command UpdateDeviceStatesInput
+}
+
+// Validate checks the scene against the device. The temperature of the command is in Celsius.
+func (scene Scene) Validate(config DeviceConfig) error {
+	if len(scene.Name) == 0 || strings.ContainsAny(scene.Name, "/+#") {
+		return errors.New("scene name is wrong")
+	}
+
+	if scene.Command == (UpdateDeviceStatesInput{Mac: scene.Command.Mac}) {
+		return errors.New("scene has no states")
+	}
+
+	return scene.Command.Validate(config.MinTemp, config.MaxTemp)
+}
+
+// ParseWeekday parses the short weekday name like mon
+func ParseWeekday(value string) (time.Weekday, error) {
+	weekday, ok := Weekdays[strings.ToLower(strings.TrimSpace(value))]
+	if !ok {
+		return 0, errors.New("unknown weekday " + value)
+	}
+	return weekday, nil
+}
+
+// StatePollInterval returns the interval of the state requests. With the adaptive polling the device
+// is polled fast for FastPollWindow after the last command or change by the IR remote and slowly when it is off.
+func (input *DeviceConfig) StatePollInterval(power byte, lastActivity time.Time) time.Duration {
+	if !input.AdaptivePolling {
+		return input.PollInterval
+	}
+
+	if time.Since(lastActivity) < input.FastPollWindow {
+		return input.FastPollInterval
+	}
+
+	if power == StatusOff {
+		return input.IdlePollInterval
+	}
+
+	return input.PollInterval
+}
+
 type DeviceAuth struct {
 	LastMessageId int
 	DevType       int
@@ -46,6 +592,95 @@ type DeviceStatusHass struct {
 	DisplaySwitch string
 }
 
+// DesiredState is the last state commanded via MQTT. It is kept apart from the reported state
+// to restore the unit after a power loss.
+type DesiredState struct {
+	UpdatedAt   time.Time
+	FanMode     *string
+	SwingMode   *string
+	Mode        *string
+	Temperature *float32
+	IsDisplayOn *bool
+}
+
+// Merge applies the command over the desired state
+func (state *DesiredState) Merge(command UpdateDeviceStatesInput) {
+	state.UpdatedAt = time.Now()
+	if command.FanMode != nil {
+		state.FanMode = command.FanMode
+	}
+	if command.SwingMode != nil {
+		state.SwingMode = command.SwingMode
+	}
+	if command.Mode != nil {
+		state.Mode = command.Mode
+	}
+	if command.Temperature != nil {
+		state.Temperature = command.Temperature
+	}
+	if command.IsDisplayOn != nil {
+		state.IsDisplayOn = command.IsDisplayOn
+	}
+}
+
+// Differs reports whether the reported status does not match the desired state
+func (state DesiredState) Differs(status DeviceStatusHass) bool {
+	switch {
+	case state.Mode != nil && *state.Mode != status.Mode:
+		return true
+	case state.Mode != nil && *state.Mode == "off":
+		// The other states of a switched off unit are not important
+		return false
+	case state.FanMode != nil && *state.FanMode != status.FanMode,
+		state.SwingMode != nil && *state.SwingMode != status.SwingMode,
+		state.Temperature != nil && *state.Temperature != status.Temperature,
+		state.IsDisplayOn != nil && *state.IsDisplayOn != (status.DisplaySwitch == "ON"):
+		return true
+	default:
+		return false
+	}
+}
+
+// IsReset reports whether the unit looks reset by a power loss: it is off while the desired mode is not off.
+// A unit which is on in other states is left as it is, as the states may have been changed with the IR remote.
+func (state DesiredState) IsReset(status DeviceStatusHass) bool {
+	return state.Mode != nil && *state.Mode != "off" && status.Mode == "off"
+}
+
+// Command returns the command which sets the desired state
+func (state DesiredState) Command(mac string) UpdateDeviceStatesInput {
+	return UpdateDeviceStatesInput{
+		Mac:         mac,
+		FanMode:     state.FanMode,
+		SwingMode:   state.SwingMode,
+		Mode:        state.Mode,
+		Temperature: state.Temperature,
+		IsDisplayOn: state.IsDisplayOn,
+	}
+}
+
+// Changes returns the states which differ from the previous status. All the states are returned
+// when there is no previous status. The temperature is converted to the unit.
+func (status DeviceStatusHass) Changes(previous *DeviceStatusHass, unit string) map[string]string {
+	changes := make(map[string]string)
+	if previous == nil || previous.Mode != status.Mode {
+		changes["mode"] = status.Mode
+	}
+	if previous == nil || previous.FanMode != status.FanMode {
+		changes["fan_mode"] = status.FanMode
+	}
+	if previous == nil || previous.SwingMode != status.SwingMode {
+		changes["swing_mode"] = status.SwingMode
+	}
+	if previous == nil || previous.Temperature != status.Temperature {
+		changes["temperature"] = fmt.Sprintf("%g", converter.SetpointFromCelsius(unit, status.Temperature))
+	}
+	if previous == nil || previous.DisplaySwitch != status.DisplaySwitch {
+		changes["display"] = status.DisplaySwitch
+	}
+	return changes
+}
+
 type DeviceStatusRaw struct {
 	UpdatedAt          time.Time
 	Temperature        float32
@@ -64,6 +699,46 @@ type DeviceStatusRaw struct {
 	Clean              byte
 }
 
+// DecodeDeviceStatusRaw decodes the decrypted payload of the state frame
+func DecodeDeviceStatusRaw(payload []byte, now time.Time) (DeviceStatusRaw, error) {
+	if len(payload) < 0x19 {
+		return DeviceStatusRaw{}, ErrorInvalidResultPacketLength
+	}
+	if payload[4] != 0x07 {
+		return DeviceStatusRaw{}, ErrorInvalidResultPacket
+	}
+	if payload[0] != 0x19 {
+		return DeviceStatusRaw{}, ErrorInvalidResultPacketLength
+	}
+
+	//Drop leading stuff as don't need
+	payload = payload[2:]
+
+	return DeviceStatusRaw{
+		UpdatedAt:          now,
+		Temperature:        float32(8+(payload[10]>>3)) + 0.5*float32(payload[12]>>7),
+		Power:              payload[18] >> 5 & 0b00000001,
+		FixationVertical:   payload[10] & 0b00000111,
+		Mode:               payload[15] >> 5 & 0b00001111,
+		Sleep:              payload[15] >> 2 & 0b00000001,
+		Display:            payload[20] >> 4 & 0b00000001,
+		Mildew:             payload[20] >> 3 & 0b00000001,
+		Health:             payload[18] >> 1 & 0b00000001,
+		FixationHorizontal: payload[10] & 0b00000111,
+		FanSpeed:           payload[13] >> 5 & 0b00000111,
+		IFeel:              payload[15] >> 3 & 0b00000001,
+		Mute:               payload[14] >> 7 & 0b00000001,
+		Turbo:              payload[14] >> 6 & 0b00000001,
+		Clean:              payload[18] >> 2 & 0b00000001,
+	}, nil
+}
+
+// Equal reports whether the statuses are the same apart from the update time
+func (raw DeviceStatusRaw) Equal(status DeviceStatusRaw) bool {
+	status.UpdatedAt = raw.UpdatedAt
+	return raw == status
+}
+
 func (raw DeviceStatusRaw) ConvertToDeviceStatusHass() (mqttStatus DeviceStatusHass) {
 	var deviceStatusMqtt DeviceStatusHass
 
@@ -116,8 +791,208 @@ func (raw DeviceStatusRaw) ConvertToDeviceStatusHass() (mqttStatus DeviceStatusH
 	return deviceStatusMqtt
 }
 
+// SmoothAmbientTemp filters the ambient temperature samples with the method
+func SmoothAmbientTemp(method string, samples []float32) float32 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	switch method {
+	case AmbientSmoothingMovingAverage:
+		var sum float32
+		for _, sample := range samples {
+			sum += sample
+		}
+		return sum / float32(len(samples))
+	case AmbientSmoothingMedian:
+		sorted := append([]float32(nil), samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		if len(sorted)%2 == 0 {
+			return (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+		}
+		return sorted[len(sorted)/2]
+	default:
+		return samples[len(samples)-1]
+	}
+}
+
+// DeviceInfoRaw is the decoded extended status (0x21 info) frame
+type DeviceInfoRaw struct {
+	UpdatedAt           time.Time
+	IndoorFanRunning    byte
+	CompressorRunning   byte
+	Defrost             byte
+	AmbientTemp         float32
+	IndoorCoilTemp      *float32
+	OutdoorCoilTemp     *float32
+	OutdoorTemp         *float32
+	CompressorFrequency byte
+	ErrorCode           byte
+	OutdoorErrorCode    byte
+}
+
+// DecodeDeviceInfoRaw decodes the decrypted payload of the info frame, which is 40 bytes after the 2 leading bytes
+func DecodeDeviceInfoRaw(payload []byte, now time.Time) (DeviceInfoRaw, error) {
+	if len(payload) < 42 {
+		return DeviceInfoRaw{}, ErrorInvalidResultPacketLength
+	}
+
+	//Drop leading stuff as don't need
+	payload = payload[2:]
+
+	// Info frame layout (after the leading length bytes):
+	//  12      - running flags: bit 4 indoor fan, bit 5 compressor, bit 6 defrost
+	//  15 + 31 - indoor ambient temperature (integer part + 0x20, tenths)
+	//  16      - indoor coil temperature + 0x20
+	//  17      - outdoor coil temperature + 0x20
+	//  18      - outdoor temperature + 0x20
+	//  21      - compressor frequency, Hz
+	//  23      - indoor unit error code
+	//  24      - outdoor unit error code
+	// The temperature bytes are zero when the unit does not have the sensor.
+	return DeviceInfoRaw{
+		UpdatedAt:           now,
+		IndoorFanRunning:    payload[12] >> 4 & 0b00000001,
+		CompressorRunning:   payload[12] >> 5 & 0b00000001,
+		Defrost:             payload[12] >> 6 & 0b00000001,
+		AmbientTemp:         float32(payload[15]-0b00100000) + (float32(payload[31]) / 10),
+		IndoorCoilTemp:      decodeInfoTemperature(payload[16]),
+		OutdoorCoilTemp:     decodeInfoTemperature(payload[17]),
+		OutdoorTemp:         decodeInfoTemperature(payload[18]),
+		CompressorFrequency: payload[21],
+		ErrorCode:           payload[23],
+		OutdoorErrorCode:    payload[24],
+	}, nil
+}
+
+func decodeInfoTemperature(value byte) *float32 {
+	if value == 0 {
+		return nil
+	}
+
+	temperature := float32(int(value) - 0b00100000)
+	return &temperature
+}
+
+// DecodeDeviceAuth returns the device id and the new key from the decrypted payload of the auth response
+func DecodeDeviceAuth(payload []byte) ([4]byte, []byte, error) {
+	if len(payload) < 0x14 {
+		return [4]byte{}, nil, ErrorInvalidResultPacketLength
+	}
+
+	return [4]byte{payload[0], payload[1], payload[2], payload[3]}, payload[0x04:0x14], nil
+}
+
+// ConvertToDiagnostics returns the diagnostic values of the info frame by their names.
+// Temperatures which are not reported by the unit are omitted.
+func (info DeviceInfoRaw) ConvertToDiagnostics(temperatureUnit string) map[string]string {
+	diagnostics := map[string]string{
+		DiagnosticAmbientTempRaw:      fmt.Sprintf("%.1f", converter.Temperature(Celsius, temperatureUnit, info.AmbientTemp)),
+		DiagnosticCompressorFrequency: strconv.Itoa(int(info.CompressorFrequency)),
+		DiagnosticErrorCode:           strconv.Itoa(int(info.ErrorCode)),
+		DiagnosticOutdoorErrorCode:    strconv.Itoa(int(info.OutdoorErrorCode)),
+		DiagnosticCompressor:          convertToOnOff(info.CompressorRunning),
+		DiagnosticIndoorFan:           convertToOnOff(info.IndoorFanRunning),
+		DiagnosticDefrost:             convertToOnOff(info.Defrost),
+	}
+
+	temperatures := map[string]*float32{
+		DiagnosticOutdoorTemp:     info.OutdoorTemp,
+		DiagnosticIndoorCoilTemp:  info.IndoorCoilTemp,
+		DiagnosticOutdoorCoilTemp: info.OutdoorCoilTemp,
+	}
+	for name, temperature := range temperatures {
+		if temperature != nil {
+			diagnostics[name] = fmt.Sprintf("%.1f", converter.Temperature(Celsius, temperatureUnit, *temperature))
+		}
+	}
+
+	return diagnostics
+}
+
+func convertToOnOff(status byte) string {
+	if status == StatusOn {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// ConvertToHvacAction derives what the unit is actually doing from the requested state and
+// the running flags of the extended status frame. Without the info frame the action follows the mode.
+func (raw DeviceStatusRaw) ConvertToHvacAction(info *DeviceInfoRaw, ambientTemp *float32) string {
+	if raw.Power == StatusOff {
+		return HvacActionOff
+	}
+
+	mode := ModeStatuses[int(raw.Mode)]
+
+	if info == nil {
+		switch mode {
+		case "cool":
+			return HvacActionCooling
+		case "heat":
+			return HvacActionHeating
+		case "dry":
+			return HvacActionDrying
+		case "fan_only":
+			return HvacActionFan
+		default:
+			return HvacActionIdle
+		}
+	}
+
+	if mode == "fan_only" {
+		if info.IndoorFanRunning == StatusOn {
+			return HvacActionFan
+		}
+		return HvacActionIdle
+	}
+
+	if info.CompressorRunning != StatusOn {
+		return HvacActionIdle
+	}
+
+	switch mode {
+	case "cool":
+		return HvacActionCooling
+	case "heat":
+		if info.Defrost == StatusOn {
+			return HvacActionDefrosting
+		}
+		return HvacActionHeating
+	case "dry":
+		return HvacActionDrying
+	case "auto":
+		if ambientTemp == nil {
+			return HvacActionIdle
+		}
+		if *ambientTemp > raw.Temperature {
+			return HvacActionCooling
+		}
+		if info.Defrost == StatusOn {
+			return HvacActionDefrosting
+		}
+		return HvacActionHeating
+	default:
+		return HvacActionIdle
+	}
+}
+
+// Fault is an active or cleared fault of the device. The fault is active while ClearedAt is nil
+type Fault struct {
+	Kind      string
+	Code      int
+	Message   string
+	FirstSeen time.Time
+	ClearedAt *time.Time
+}
+
 type CreateDeviceInput struct {
-	Config DeviceConfig
+	Config        DeviceConfig
+	Schedules     []Schedule
+	Contacts      []Contact
+	Scenes        []Scene
+	SleepProfiles []SleepProfile
 }
 
 type CreateDeviceReturn struct {
@@ -128,6 +1003,18 @@ type AuthDeviceInput struct {
 	Mac string
 }
 
+type GetDeviceFirmwareInput struct {
+	Mac string
+}
+
+type UpdateWorkerStatusInput struct {
+	Mac       string
+	State     string
+	Restarts  int
+	Error     string
+	UpdatedAt time.Time
+}
+
 type SendCommandInput struct {
 	Command byte
 	Payload []byte
@@ -204,13 +1091,56 @@ type UpdateTemperatureInput struct {
 	Temperature float32
 }
 
-func (input UpdateTemperatureInput) Validate() error {
-	if input.Temperature > 32 || input.Temperature < 16 {
+// Validate checks the temperature in Celsius against the setpoint range of the device
+func (input UpdateTemperatureInput) Validate(minTemp, maxTemp float32) error {
+	if input.Temperature > maxTemp || input.Temperature < minTemp {
 		return ErrorInvalidParameterTemperature
 	}
 	return nil
 }
 
+// UpdateTemperatureRangeInput sets the low or the high target of the heat_cool mode in the unit of the device
+type UpdateTemperatureRangeInput struct {
+	Mac  string
+	Low  *float32
+	High *float32
+}
+
+// HeatCool is the state of the heat_cool mode. The targets are in Celsius.
+type HeatCool struct {
+	IsActive bool
+	Low      float32
+	High     float32
+	// SwitchedAt is the time of the last switch between heat and cool
+	SwitchedAt time.Time
+}
+
+// Validate checks the targets against the setpoint range of the device
+func (heatCool HeatCool) Validate(minTemp, maxTemp float32) error {
+	if heatCool.Low < minTemp || heatCool.High > maxTemp || heatCool.Low >= heatCool.High {
+		return ErrorInvalidParameterTemperature
+	}
+	return nil
+}
+
+// Mode returns the unit mode to start the heat_cool mode with: cool when the room is warmer
+// than the middle of the targets, and heat otherwise or when the room temperature is unknown
+func (heatCool HeatCool) Mode(room *float32) string {
+	if room != nil && *room > (heatCool.Low+heatCool.High)/2 {
+		return "cool"
+	}
+	return "heat"
+}
+
+// Command returns the command which runs the unit in the mode with the target of this mode
+func (heatCool HeatCool) Command(mac, mode string) UpdateDeviceStatesInput {
+	temperature := heatCool.Low
+	if mode == "cool" {
+		temperature = heatCool.High
+	}
+	return UpdateDeviceStatesInput{Mac: mac, Mode: &mode, Temperature: &temperature}
+}
+
 type UpdateDeviceStatesInput struct {
 	Mac         string
 	FanMode     *string
@@ -218,6 +1148,75 @@ type UpdateDeviceStatesInput struct {
 	Mode        *string
 	Temperature *float32
 	IsDisplayOn *bool
+	// IsTimerChanged sends the set frame with the native timers even when no state is changed
+	IsTimerChanged bool
+}
+
+// Validate checks the states of the command. The temperature is in Celsius.
+func (input *UpdateDeviceStatesInput) Validate(minTemp, maxTemp float32) error {
+	if input.FanMode != nil {
+		err := (&UpdateFanModeInput{Mac: input.Mac, FanMode: *input.FanMode}).Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if input.SwingMode != nil {
+		err := (&UpdateSwingModeInput{Mac: input.Mac, SwingMode: *input.SwingMode}).Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if input.Mode != nil {
+		err := UpdateModeInput{Mac: input.Mac, Mode: *input.Mode}.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if input.Temperature != nil {
+		err := UpdateTemperatureInput{Mac: input.Mac, Temperature: *input.Temperature}.Validate(minTemp, maxTemp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Merge applies the newer command over the pending one
+func (input *UpdateDeviceStatesInput) Merge(command UpdateDeviceStatesInput) {
+	if command.FanMode != nil {
+		input.FanMode = command.FanMode
+	}
+	if command.SwingMode != nil {
+		input.SwingMode = command.SwingMode
+	}
+	if command.Mode != nil {
+		input.Mode = command.Mode
+	}
+	if command.Temperature != nil {
+		input.Temperature = command.Temperature
+	}
+	if command.IsDisplayOn != nil {
+		input.IsDisplayOn = command.IsDisplayOn
+	}
+	input.IsTimerChanged = input.IsTimerChanged || command.IsTimerChanged
+}
+
+// Sets reports whether the command has set the changed state, named like in Changes, to the reported value
+func (input UpdateDeviceStatesInput) Sets(name string, status DeviceStatusHass) bool {
+	switch name {
+	case "mode":
+		return input.Mode != nil && *input.Mode == status.Mode
+	case "fan_mode":
+		return input.FanMode != nil && *input.FanMode == status.FanMode
+	case "swing_mode":
+		return input.SwingMode != nil && *input.SwingMode == status.SwingMode
+	case "temperature":
+		return input.Temperature != nil && *input.Temperature == status.Temperature
+	case "display":
+		return input.IsDisplayOn != nil && *input.IsDisplayOn == (status.DisplaySwitch == "ON")
+	default:
+		return false
+	}
 }
 
 type CreateCommandPayloadReturn struct {
@@ -233,6 +1232,290 @@ type StartDeviceMonitoringInput struct {
 	Mac string
 }
 
+type StartSchedulerInput struct {
+	Mac string
+}
+
+type UpdateExternalTemperatureInput struct {
+	Mac     string
+	Payload []byte
+}
+
+// ExternalTemp is the last reading of the external sensor in Celsius
+type ExternalTemp struct {
+	Temperature float32
+	UpdatedAt   time.Time
+}
+
+// Thermostat is the state of the virtual thermostat
+type Thermostat struct {
+	IsIdle bool
+}
+
+type StartTimersInput struct {
+	Mac string
+}
+
+// UpdateTimerInput sets the timer of the kind. The value is the delay in minutes, a duration like 1h30m,
+// the time of day like 23:30 or an RFC 3339 time. An empty value or 0 cancels the timer.
+type UpdateTimerInput struct {
+	Mac   string
+	Kind  string
+	Value string
+}
+
+// At returns the time when the timer expires or the zero time when the timer is canceled
+func (input *UpdateTimerInput) At(now time.Time) (time.Time, error) {
+	isKnown := false
+	for _, kind := range TimerKinds {
+		isKnown = isKnown || kind.Name == input.Kind
+	}
+	if !isKnown {
+		return time.Time{}, ErrorInvalidParameterTimer
+	}
+
+	value := strings.TrimSpace(input.Value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	// NaN, infinities and too large numbers cannot be converted to a duration
+	if minutes, err := strconv.ParseFloat(value, 64); err == nil {
+		if math.IsNaN(minutes) || minutes < 0 || minutes > TimerMaxDelay.Minutes() {
+			return time.Time{}, ErrorInvalidParameterTimer
+		}
+		if minutes == 0 {
+			return time.Time{}, nil
+		}
+		return now.Add(time.Duration(minutes * float64(time.Minute))), nil
+	}
+
+	if delay, err := time.ParseDuration(value); err == nil {
+		if delay < 0 || delay > TimerMaxDelay {
+			return time.Time{}, ErrorInvalidParameterTimer
+		}
+		if delay == 0 {
+			return time.Time{}, nil
+		}
+		return now.Add(delay), nil
+	}
+
+	if at, err := ParseTimeOfDay(value); err == nil {
+		return Schedule{At: at}.NextRun(now), nil
+	}
+
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		if !at.After(now) || at.Sub(now) > TimerMaxDelay {
+			return time.Time{}, ErrorInvalidParameterTimer
+		}
+		return at, nil
+	}
+
+	return time.Time{}, ErrorInvalidParameterTimer
+}
+
+// TimerState is the persisted timer
+type TimerState struct {
+	At time.Time
+}
+
+// NativeTimer encodes the timer for the set frame of the unit: bit 7 turns the timer on and bits 0-6 are
+// the remaining time in NativeTimerStep steps rounded up. A timer which is not set, has expired or ends after
+// NativeTimerMaxDelay is encoded as zero and is run by the bridge only.
+func NativeTimer(timer *TimerState, now time.Time) byte {
+	if timer == nil || !timer.At.After(now) || timer.At.Sub(now) > NativeTimerMaxDelay {
+		return 0
+	}
+
+	steps := (timer.At.Sub(now) + NativeTimerStep - 1) / NativeTimerStep
+	return 0b10000000 | byte(steps)
+}
+
+// SleepStep is a point of the sleep curve
+type SleepStep struct {
+	// After is the time from the start of the curve
+	After time.Duration
+	// Offset is added to the setpoint at the start of the curve, in the temperature unit of the device
+	Offset      *float32
+	FanMode     *string
+	IsDisplayOn *bool
+}
+
+// SleepProfile is a curve of the setpoint over the night. The last setpoint is held after the last step.
+type SleepProfile struct {
+	Name  string
+	Steps []SleepStep
+}
+
+// Validate checks the sleep profile. The steps go in time order.
+func (profile SleepProfile) Validate() error {
+	if len(profile.Name) == 0 || strings.ContainsAny(profile.Name, "/+#") || profile.Name == SleepOff {
+		return errors.New("sleep profile name is wrong")
+	}
+
+	if len(profile.Steps) == 0 {
+		return errors.New("sleep profile has no steps")
+	}
+
+	for i, step := range profile.Steps {
+		if step.After < 0 || (i > 0 && step.After <= profile.Steps[i-1].After) {
+			return errors.New("sleep steps are not in time order")
+		}
+		if step.Offset == nil && step.FanMode == nil && step.IsDisplayOn == nil {
+			return errors.New("sleep step has no states")
+		}
+		if step.FanMode != nil {
+			err := (&UpdateFanModeInput{FanMode: *step.FanMode}).Validate()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SleepCommand returns the command of the sleep step for the setpoint at the start in Celsius.
+// The setpoint is rounded to the resolution of the device and is kept in its range.
+func (input *DeviceConfig) SleepCommand(step SleepStep, base float32) UpdateDeviceStatesInput {
+	command := UpdateDeviceStatesInput{
+		Mac:         input.Mac,
+		FanMode:     step.FanMode,
+		IsDisplayOn: step.IsDisplayOn,
+	}
+	if step.Offset != nil {
+		temperature := converter.SetpointToCelsius(input.TemperatureUnit, converter.SetpointFromCelsius(input.TemperatureUnit, base)+*step.Offset)
+		temperature = min(max(temperature, input.MinTemp), input.MaxTemp)
+		command.Temperature = &temperature
+	}
+
+	return command
+}
+
+// SleepState is the progress of the sleep curve of the device
+type SleepState struct {
+	Profile   string
+	StartedAt time.Time
+	// Base is the setpoint at the start in Celsius
+	Base float32
+	// Step is the number of the applied steps
+	Step int
+}
+
+type StartSleepCurveInput struct {
+	Mac string
+}
+
+type UpdateSleepInput struct {
+	Mac string
+	// Profile is the name of the sleep profile which is started, or OFF which cancels the curve
+	Profile string
+}
+
+// Group is a set of devices which are commanded together
+type Group struct {
+	Name      string
+	Devices   []string
+	Discovery bool
+}
+
+// Validate checks the group against the MAC addresses of the devices
+func (group Group) Validate(macs []string) error {
+	if len(group.Name) == 0 || strings.ContainsAny(group.Name, "/+#") {
+		return errors.New("group name is wrong")
+	}
+
+	if len(group.Devices) == 0 {
+		return errors.New("group has no devices")
+	}
+
+	for i, mac := range group.Devices {
+		if !slices.Contains(macs, mac) {
+			return errors.New("group device " + mac + " is not found")
+		}
+		if slices.Contains(group.Devices[:i], mac) {
+			return errors.New("group device " + mac + " is not unique")
+		}
+	}
+
+	return nil
+}
+
+// GroupMember is the last known state of a member of the group
+type GroupMember struct {
+	// Status is nil when the member has not answered yet
+	Status      *DeviceStatusRaw
+	IsAvailable bool
+}
+
+// GroupState returns the aggregated state of the members: all_off, all_<mode> when all of them run in the same mode,
+// mixed, or unknown when a member is not available or its state is not known yet, as its last status may be stale
+func GroupState(members []GroupMember) string {
+	state := ""
+	for _, member := range members {
+		if member.Status == nil || !member.IsAvailable {
+			return GroupStateUnknown
+		}
+
+		memberState := GroupStateAllOff
+		if member.Status.Power == StatusOn {
+			memberState = GroupStateAll + ModeStatuses[int(member.Status.Mode)]
+		}
+		if state == "" {
+			state = memberState
+		} else if state != memberState {
+			state = GroupStateMixed
+		}
+	}
+
+	if state == "" {
+		return GroupStateUnknown
+	}
+	return state
+}
+
+type CreateGroupInput struct {
+	Group Group
+}
+
+type UpdateGroupInput struct {
+	Name string
+	// Property is the part of the command topic of a device, e.g. mode or display/switch
+	Property string
+	Value    string
+}
+
+type PublishGroupDiscoveryTopicInput struct {
+	Name string
+}
+
+type UpdateGroupAvailabilityInput struct {
+	Name         string
+	Availability string
+}
+
+type ApplySceneInput struct {
+	Mac  string
+	Name string
+}
+
+type ApplySceneToAllInput struct {
+	Name string
+}
+
+type UpdateScheduleSwitchInput struct {
+	Mac    string
+	Name   string
+	Status string
+}
+
+func (input *UpdateScheduleSwitchInput) Validate() error {
+	if input.Status != "ON" && input.Status != "OFF" {
+		return ErrorInvalidParameterScheduleStatus
+	}
+	return nil
+}
+
 type PublishStatesOnHomeAssistantRestartInput struct {
 	Status string
 }
@@ -0,0 +1,675 @@
+package models
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+	// The zones of the schedule tests do not depend on the system database
+	_ "time/tzdata"
+
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/coder"
+)
+
+// The responses captured from a device, see the comments of AuthDevice and GetDeviceAmbientTemperature.
+// The auth response is encrypted with the default key and the info response with the key which it returns.
+const (
+	authResponse    = "5aa5aa555aa5aa5500000000000000000000000000000000000000000000000028dc00002a4ee90363f734ea34dadac800000000c1c70000bb6cbbbb34585cd442b9cfbbdb303eea55afe062cdd638164b81cc384084ef9e"
+	ambientResponse = "5aa5aa555aa5aa5500000000000000000000000000000000000000000000000040e300002a4eee03907c34ea34dadac801000000cfc100002c4fa6c565f78b46829220a36fbf6524a68a0497eb37efe6a6422a4f6b8aed81d167c38db269c50ae4e29105bc525e60"
+
+	// statePayload is a decrypted state frame: on, cool mode, 24 °C, fan auto
+	statePayload = "190000000700000000000000800000a0002000002000000000"
+)
+
+var (
+	defaultKey = []byte{0x09, 0x76, 0x28, 0x34, 0x3f, 0xe9, 0x9e, 0x23, 0x76, 0x5c, 0x15, 0x13, 0xac, 0xcf, 0x8b, 0x02}
+	defaultIv  = []byte{0x56, 0x2e, 0x17, 0x99, 0x6d, 0x09, 0x3d, 0x28, 0xdd, 0xb3, 0xba, 0x69, 0x5a, 0x2e, 0x6f, 0x58}
+)
+
+func decryptResponse(tb testing.TB, response string, key []byte) []byte {
+	tb.Helper()
+
+	packet, err := hex.DecodeString(response)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	payload, err := coder.Decrypt(key, defaultIv, packet[coder.HeaderLength:])
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return payload
+}
+
+func TestDecodeCapturedResponses(t *testing.T) {
+	id, key, err := DecodeDeviceAuth(decryptResponse(t, authResponse, defaultKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != [4]byte{1, 0, 0, 0} {
+		t.Fatalf("the device id is %x, want 01000000", id)
+	}
+
+	info, err := DecodeDeviceInfoRaw(decryptResponse(t, ambientResponse, key), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(info.AmbientTemp)-25.3) > 0.01 {
+		t.Fatalf("the ambient temperature is %v, want 25.3", info.AmbientTemp)
+	}
+}
+
+func TestDecodeDeviceStatusRaw(t *testing.T) {
+	payload, err := hex.DecodeString(statePayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := DecodeDeviceStatusRaw(payload, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Power != StatusOn || ModeStatuses[int(status.Mode)] != "cool" ||
+		FanStatuses[int(status.FanSpeed)] != "auto" || status.Temperature != 24 {
+		t.Fatalf("the status is %+v, want on, cool, auto and 24", status)
+	}
+}
+
+func FuzzDecodePayload(f *testing.F) {
+	authPayload := decryptResponse(f, authResponse, defaultKey)
+	_, key, err := DecodeDeviceAuth(authPayload)
+	if err != nil {
+		f.Fatal(err)
+	}
+	state, err := hex.DecodeString(statePayload)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(authPayload)
+	f.Add(decryptResponse(f, ambientResponse, key))
+	f.Add(state)
+	f.Add(state[:0x18])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		now := time.Now()
+
+		status, err := DecodeDeviceStatusRaw(payload, now)
+		if err == nil {
+			status.ConvertToDeviceStatusHass()
+		}
+
+		info, err := DecodeDeviceInfoRaw(payload, now)
+		if err == nil {
+			info.ConvertToDiagnostics(Celsius)
+			info.ConvertToDiagnostics(Fahrenheit)
+			status.ConvertToHvacAction(&info, &info.AmbientTemp)
+		}
+
+		_, key, err := DecodeDeviceAuth(payload)
+		if err == nil && len(key) != 16 {
+			t.Fatalf("the key is %d bytes long", len(key))
+		}
+	})
+}
+
+func TestScheduleNextRun(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Hour*7 + time.Minute*30
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		after    time.Time
+		want     time.Time
+	}{
+		{
+			name:     "later the same day",
+			schedule: Schedule{At: at},
+			after:    time.Date(2024, 5, 1, 6, 0, 0, 0, berlin),
+			want:     time.Date(2024, 5, 1, 7, 30, 0, 0, berlin),
+		},
+		{
+			name:     "at the time of the run",
+			schedule: Schedule{At: at},
+			after:    time.Date(2024, 5, 1, 7, 30, 0, 0, berlin),
+			want:     time.Date(2024, 5, 2, 7, 30, 0, 0, berlin),
+		},
+		{
+			// 2024-05-03 is a Friday
+			name:     "next weekday",
+			schedule: Schedule{At: at, Days: []time.Weekday{time.Monday, time.Wednesday}},
+			after:    time.Date(2024, 5, 3, 6, 0, 0, 0, berlin),
+			want:     time.Date(2024, 5, 6, 7, 30, 0, 0, berlin),
+		},
+		{
+			name:     "the same weekday a week later",
+			schedule: Schedule{At: at, Days: []time.Weekday{time.Friday}},
+			after:    time.Date(2024, 5, 3, 8, 0, 0, 0, berlin),
+			want:     time.Date(2024, 5, 10, 7, 30, 0, 0, berlin),
+		},
+		{
+			// The clocks go forward on 2024-03-31, the run keeps the local time
+			name:     "summer time",
+			schedule: Schedule{At: at},
+			after:    time.Date(2024, 3, 30, 8, 0, 0, 0, berlin),
+			want:     time.Date(2024, 3, 31, 7, 30, 0, 0, berlin),
+		},
+		{
+			// The clocks go back on 2024-10-27
+			name:     "winter time",
+			schedule: Schedule{At: at},
+			after:    time.Date(2024, 10, 26, 8, 0, 0, 0, berlin),
+			want:     time.Date(2024, 10, 27, 7, 30, 0, 0, berlin),
+		},
+		{
+			// 01:30 local time is 23:30 UTC of the day before, the run is planned in the zone of the time
+			name:     "time zone",
+			schedule: Schedule{At: time.Hour + time.Minute*30, Days: []time.Weekday{time.Wednesday}},
+			after:    time.Date(2024, 5, 1, 0, 0, 0, 0, berlin),
+			want:     time.Date(2024, 4, 30, 23, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.schedule.NextRun(tt.after)
+			if !got.Equal(tt.want) {
+				t.Fatalf("NextRun(%v) = %v, want %v", tt.after, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("fixed offset", func(t *testing.T) {
+		// The time read from the state file has the offset of the winter time, the next run is in the summer time
+		schedule := Schedule{At: at}
+		after := time.Date(2024, 3, 30, 7, 30, 0, 0, time.FixedZone("", 3600))
+
+		want := time.Date(2024, 3, 31, 7, 30, 0, 0, berlin)
+		if got := schedule.NextRun(after.In(berlin)); !got.Equal(want) {
+			t.Fatalf("NextRun(%v) = %v, want %v", after.In(berlin), got, want)
+		}
+		if got := schedule.NextRun(after); got.Equal(want) {
+			t.Fatalf("NextRun(%v) = %v, the offset of the winter time is expected", after, got)
+		}
+	})
+}
+
+func TestIsInDailyWindow(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		at         time.Duration
+		want       bool
+	}{
+		{name: "inside", start: time.Hour * 9, end: time.Hour * 17, at: time.Hour * 12, want: true},
+		{name: "at the start", start: time.Hour * 9, end: time.Hour * 17, at: time.Hour * 9, want: true},
+		{name: "at the end", start: time.Hour * 9, end: time.Hour * 17, at: time.Hour * 17, want: false},
+		{name: "before", start: time.Hour * 9, end: time.Hour * 17, at: time.Hour*8 + time.Minute*59, want: false},
+		{name: "midnight evening", start: time.Hour * 22, end: time.Hour * 7, at: time.Hour * 23, want: true},
+		{name: "midnight", start: time.Hour * 22, end: time.Hour * 7, at: 0, want: true},
+		{name: "midnight morning", start: time.Hour * 22, end: time.Hour * 7, at: time.Hour*6 + time.Minute*59, want: true},
+		{name: "midnight end", start: time.Hour * 22, end: time.Hour * 7, at: time.Hour * 7, want: false},
+		{name: "midnight day", start: time.Hour * 22, end: time.Hour * 7, at: time.Hour * 12, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsInDailyWindow(day.Add(tt.at), tt.start, tt.end); got != tt.want {
+				t.Fatalf("IsInDailyWindow(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnforceCommand(t *testing.T) {
+	var (
+		night = time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+		day   = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+		cool, off     = "cool", "off"
+		low, high     = float32(18), float32(28)
+		target, limit = float32(24), float32(26)
+	)
+	desired := &DesiredState{Mode: &cool, Temperature: &target}
+	status := DeviceStatusHass{Mode: "heat", Temperature: 30}
+
+	config := func(mode string, minTemp, maxTemp *float32) DeviceConfig {
+		return DeviceConfig{
+			Mac:               "34ea34dadac8",
+			EnforceMode:       mode,
+			EnforceQuietStart: time.Hour * 22,
+			EnforceQuietEnd:   time.Hour * 7,
+			EnforceMinTemp:    minTemp,
+			EnforceMaxTemp:    maxTemp,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		config  DeviceConfig
+		now     time.Time
+		desired *DesiredState
+		status  DeviceStatusHass
+		want    *UpdateDeviceStatesInput
+	}{
+		{name: "off", config: config(EnforceModeOff, nil, nil), now: night, desired: desired, status: status},
+		{
+			name: "always", config: config(EnforceModeAlways, nil, nil), now: day, desired: desired, status: status,
+			want: &UpdateDeviceStatesInput{Mac: "34ea34dadac8", Mode: &cool, Temperature: &target},
+		},
+		{
+			name: "quiet hours", config: config(EnforceModeQuietHours, nil, nil), now: night, desired: desired, status: status,
+			want: &UpdateDeviceStatesInput{Mac: "34ea34dadac8", Mode: &cool, Temperature: &target},
+		},
+		{name: "out of the quiet hours", config: config(EnforceModeQuietHours, nil, nil), now: day, desired: desired, status: status},
+		{name: "no desired state", config: config(EnforceModeAlways, nil, nil), now: day, status: status},
+		{
+			name: "same state", config: config(EnforceModeAlways, nil, nil), now: day, desired: desired,
+			status: DeviceStatusHass{Mode: "cool", Temperature: 24},
+		},
+		{
+			name: "max temperature", config: config(EnforceModeOff, &low, &limit), now: day, desired: desired, status: status,
+			want: &UpdateDeviceStatesInput{Mac: "34ea34dadac8", Temperature: &limit},
+		},
+		{
+			name: "min temperature", config: config(EnforceModeOff, &low, &high), now: day, desired: desired,
+			status: DeviceStatusHass{Mode: "heat", Temperature: 16},
+			want:   &UpdateDeviceStatesInput{Mac: "34ea34dadac8", Temperature: &low},
+		},
+		{
+			name: "limits of a unit which is off", config: config(EnforceModeOff, &low, &limit), now: day, desired: desired,
+			status: DeviceStatusHass{Mode: off, Temperature: 30},
+		},
+		{
+			// The desired setpoint is above the limit, so the limit wins
+			name: "limits of the reverted state", config: config(EnforceModeAlways, &low, &limit), now: day,
+			desired: &DesiredState{Mode: &cool, Temperature: &high}, status: status,
+			want: &UpdateDeviceStatesInput{Mac: "34ea34dadac8", Mode: &cool, Temperature: &limit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.EnforceCommand(tt.now, tt.desired, tt.status)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil:
+				t.Fatalf("EnforceCommand() = %v, want %v", got, tt.want)
+			case !reflect.DeepEqual(*got, *tt.want):
+				t.Fatalf("EnforceCommand() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestShortCycleWait(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	config := DeviceConfig{ShortCycleMinOff: time.Minute * 3, ShortCycleMinRun: time.Minute * 5}
+
+	var (
+		cool, heat, off = "cool", "heat", "off"
+		coolOn          = DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["cool"])}
+		unitOff         = DeviceStatusRaw{Power: StatusOff, Mode: byte(ModeStatusesInvert["cool"])}
+		temperature     = float32(24)
+	)
+
+	tests := []struct {
+		name       string
+		command    UpdateDeviceStatesInput
+		status     DeviceStatusRaw
+		cycle      Cycle
+		want       time.Duration
+		wantReason string
+	}{
+		{
+			name: "on before the minimum off time", command: UpdateDeviceStatesInput{Mode: &cool}, status: unitOff,
+			cycle: Cycle{StoppedAt: now.Add(-time.Minute)}, want: time.Minute * 2, wantReason: "minimum off time",
+		},
+		{
+			name: "on after the minimum off time", command: UpdateDeviceStatesInput{Mode: &cool}, status: unitOff,
+			cycle: Cycle{StoppedAt: now.Add(-time.Minute * 4)},
+		},
+		{
+			name: "mode before the minimum run time", command: UpdateDeviceStatesInput{Mode: &heat}, status: coolOn,
+			cycle: Cycle{StartedAt: now.Add(-time.Minute)}, want: time.Minute * 4, wantReason: "minimum run time",
+		},
+		{
+			name: "off before the minimum run time", command: UpdateDeviceStatesInput{Mode: &off}, status: coolOn,
+			cycle: Cycle{StartedAt: now.Add(-time.Minute * 4)}, want: time.Minute, wantReason: "minimum run time",
+		},
+		{
+			name: "same mode", command: UpdateDeviceStatesInput{Mode: &cool}, status: coolOn,
+			cycle: Cycle{StartedAt: now.Add(-time.Minute)},
+		},
+		{
+			name: "off while off", command: UpdateDeviceStatesInput{Mode: &off}, status: unitOff,
+			cycle: Cycle{StoppedAt: now.Add(-time.Minute)},
+		},
+		{
+			name: "no mode", command: UpdateDeviceStatesInput{Temperature: &temperature}, status: coolOn,
+			cycle: Cycle{StartedAt: now.Add(-time.Minute)},
+		},
+		{name: "unknown start", command: UpdateDeviceStatesInput{Mode: &heat}, status: coolOn},
+		{name: "unknown stop", command: UpdateDeviceStatesInput{Mode: &cool}, status: unitOff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, reason := config.ShortCycleWait(now, tt.command, tt.status, tt.cycle)
+			if wait != tt.want {
+				t.Fatalf("ShortCycleWait() = %v, want %v", wait, tt.want)
+			}
+			if wait != 0 && reason != tt.wantReason {
+				t.Fatalf("ShortCycleWait() reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCycleUpdate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := Cycle{StartedAt: now.Add(-time.Hour), StoppedAt: now.Add(-time.Hour * 2)}
+
+	var (
+		coolOn  = DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["cool"])}
+		heatOn  = DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["heat"])}
+		coolOff = DeviceStatusRaw{Power: StatusOff, Mode: byte(ModeStatusesInvert["cool"])}
+		heatOff = DeviceStatusRaw{Power: StatusOff, Mode: byte(ModeStatusesInvert["heat"])}
+	)
+
+	tests := []struct {
+		name             string
+		previous, status DeviceStatusRaw
+		want             Cycle
+		wantChanged      bool
+	}{
+		{name: "on", previous: coolOff, status: coolOn, want: Cycle{StartedAt: now, StoppedAt: before.StoppedAt}, wantChanged: true},
+		{name: "off", previous: coolOn, status: coolOff, want: Cycle{StartedAt: before.StartedAt, StoppedAt: now}, wantChanged: true},
+		{name: "mode", previous: coolOn, status: heatOn, want: Cycle{StartedAt: now, StoppedAt: before.StoppedAt}, wantChanged: true},
+		{name: "mode while off", previous: coolOff, status: heatOff, want: before},
+		{name: "no change", previous: coolOn, status: coolOn, want: before},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cycle := before
+			changed := cycle.Update(tt.previous, tt.status, now)
+			if changed != tt.wantChanged {
+				t.Fatalf("Update() = %v, want %v", changed, tt.wantChanged)
+			}
+			if cycle != tt.want {
+				t.Fatalf("Update() cycle = %+v, want %+v", cycle, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeatCoolCommand(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	config := DeviceConfig{Mac: "34ea34dadac8", HeatCool: true, HeatCoolDeadband: 1, HeatCoolMinDwell: time.Minute * 10}
+	heatCool := HeatCool{IsActive: true, Low: 20, High: 24, SwitchedAt: now.Add(-time.Hour)}
+
+	var (
+		heatOn  = DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["heat"])}
+		coolOn  = DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["cool"])}
+		dryOn   = DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["dry"])}
+		heatOff = DeviceStatusRaw{Power: StatusOff, Mode: byte(ModeStatusesInvert["heat"])}
+	)
+
+	tests := []struct {
+		name     string
+		config   DeviceConfig
+		heatCool HeatCool
+		room     float32
+		status   DeviceStatusRaw
+		wantMode string
+	}{
+		{name: "heat within the deadband", config: config, heatCool: heatCool, room: 24.5, status: heatOn},
+		{name: "heat past the deadband", config: config, heatCool: heatCool, room: 25, status: heatOn, wantMode: "cool"},
+		{name: "cool within the deadband", config: config, heatCool: heatCool, room: 19.5, status: coolOn},
+		{name: "cool past the deadband", config: config, heatCool: heatCool, room: 19, status: coolOn, wantMode: "heat"},
+		{
+			name: "minimum dwell", config: config, room: 30, status: heatOn,
+			heatCool: HeatCool{IsActive: true, Low: 20, High: 24, SwitchedAt: now.Add(-time.Minute * 5)},
+		},
+		{name: "not active", config: config, heatCool: HeatCool{Low: 20, High: 24}, room: 30, status: heatOn},
+		{name: "not enabled", config: DeviceConfig{Mac: "34ea34dadac8"}, heatCool: heatCool, room: 30, status: heatOn},
+		{name: "unit off", config: config, heatCool: heatCool, room: 30, status: heatOff},
+		{name: "other mode", config: config, heatCool: heatCool, room: 30, status: dryOn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := tt.config.HeatCoolCommand(now, tt.room, tt.heatCool, tt.status)
+			if len(tt.wantMode) == 0 {
+				if command != nil {
+					t.Fatalf("HeatCoolCommand() = %+v, want nil", *command)
+				}
+				return
+			}
+			if command == nil || command.Mode == nil || *command.Mode != tt.wantMode {
+				t.Fatalf("HeatCoolCommand() = %+v, want the mode %s", command, tt.wantMode)
+			}
+			want := tt.heatCool.Command(tt.config.Mac, tt.wantMode)
+			if *command.Temperature != *want.Temperature {
+				t.Fatalf("HeatCoolCommand() temperature = %v, want %v", *command.Temperature, *want.Temperature)
+			}
+		})
+	}
+}
+
+func TestThermostatCommand(t *testing.T) {
+	var (
+		heat, cool, dry, off = "heat", "cool", "dry", "off"
+		target               = float32(22)
+	)
+	setpoint := DeviceConfig{
+		Mac: "34ea34dadac8", MinTemp: 16, MaxTemp: 32,
+		ThermostatControl: ThermostatControlSetpoint, ThermostatHysteresis: 0.5, ThermostatMaxOffset: 2,
+	}
+	power := DeviceConfig{Mac: "34ea34dadac8", ThermostatControl: ThermostatControlPower, ThermostatHysteresis: 0.5}
+	narrow := setpoint
+	narrow.MaxTemp = 22.5
+
+	tests := []struct {
+		name            string
+		config          DeviceConfig
+		room            float32
+		mode            string
+		status          DeviceStatusRaw
+		isIdle          bool
+		canAdjust       bool
+		wantTemperature *float32
+		wantMode        *string
+		wantIdle        bool
+	}{
+		{
+			name: "setpoint up", config: setpoint, room: 21, mode: heat, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 22}, wantTemperature: ptr(float32(22.5)),
+		},
+		{
+			name: "setpoint down", config: setpoint, room: 23, mode: cool, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 22}, wantTemperature: ptr(float32(21.5)),
+		},
+		{
+			name: "setpoint within the hysteresis", config: setpoint, room: 22.4, mode: cool, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 22},
+		},
+		{
+			name: "setpoint at the max offset", config: setpoint, room: 18, mode: heat, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 24},
+		},
+		{
+			name: "setpoint clamped to the max offset", config: setpoint, room: 26, mode: cool, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 19}, wantTemperature: ptr(float32(20)),
+		},
+		{
+			name: "setpoint clamped to the range", config: narrow, room: 18, mode: heat, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 22.5},
+		},
+		{
+			name: "setpoint before the interval", config: setpoint, room: 21, mode: heat,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 22},
+		},
+		{
+			name: "setpoint of a unit which is off", config: setpoint, room: 21, mode: heat, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOff, Temperature: 22},
+		},
+		{
+			name: "setpoint in the dry mode", config: setpoint, room: 21, mode: dry, canAdjust: true,
+			status: DeviceStatusRaw{Power: StatusOn, Temperature: 22},
+		},
+		{
+			name: "power off when warm enough", config: power, room: 22.5, mode: heat,
+			status: DeviceStatusRaw{Power: StatusOn}, wantMode: &off, wantIdle: true,
+		},
+		{
+			name: "power off when cool enough", config: power, room: 21.5, mode: cool,
+			status: DeviceStatusRaw{Power: StatusOn}, wantMode: &off, wantIdle: true,
+		},
+		{name: "power kept on", config: power, room: 22, mode: heat, status: DeviceStatusRaw{Power: StatusOn}},
+		{
+			name: "power resumed", config: power, room: 21.5, mode: heat, isIdle: true,
+			status: DeviceStatusRaw{Power: StatusOff}, wantMode: &heat,
+		},
+		{
+			name: "power kept idle", config: power, room: 22, mode: heat, isIdle: true,
+			status: DeviceStatusRaw{Power: StatusOff}, wantIdle: true,
+		},
+		{
+			// The unit switched off by the user is not switched on
+			name: "power off by the user", config: power, room: 18, mode: heat,
+			status: DeviceStatusRaw{Power: StatusOff},
+		},
+		{name: "power in the dry mode", config: power, room: 30, mode: dry, status: DeviceStatusRaw{Power: StatusOn}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := &DesiredState{Mode: &tt.mode, Temperature: &target}
+			command, isIdle := tt.config.ThermostatCommand(tt.room, desired, tt.status, tt.isIdle, tt.canAdjust)
+			if isIdle != tt.wantIdle {
+				t.Fatalf("ThermostatCommand() idle = %v, want %v", isIdle, tt.wantIdle)
+			}
+
+			var want *UpdateDeviceStatesInput
+			if tt.wantTemperature != nil || tt.wantMode != nil {
+				want = &UpdateDeviceStatesInput{Mac: tt.config.Mac, Temperature: tt.wantTemperature, Mode: tt.wantMode}
+			}
+			switch {
+			case command == nil && want == nil:
+			case command == nil || want == nil:
+				t.Fatalf("ThermostatCommand() = %v, want %v", command, want)
+			case !reflect.DeepEqual(*command, *want):
+				t.Fatalf("ThermostatCommand() = %+v, want %+v", *command, *want)
+			}
+		})
+	}
+
+	t.Run("no desired state", func(t *testing.T) {
+		command, _ := setpoint.ThermostatCommand(18, nil, DeviceStatusRaw{Power: StatusOn, Temperature: 22}, false, true)
+		if command != nil {
+			t.Fatalf("ThermostatCommand() = %+v, want nil", *command)
+		}
+	})
+}
+
+func TestParseExternalTemperature(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		key     string
+		want    float32
+		wantErr bool
+	}{
+		{name: "plain", payload: "21.5", want: 21.5},
+		{name: "plain with spaces", payload: " 21.5\n", want: 21.5},
+		{name: "json", payload: `{"temperature": 21.5, "humidity": 40}`, key: "temperature", want: 21.5},
+		{name: "nested", payload: `{"sensor": {"temperature": -3.5}}`, key: "sensor.temperature", want: -3.5},
+		{name: "string number", payload: `{"temperature": "21.5"}`, key: "temperature", want: 21.5},
+		{name: "missing key", payload: `{"humidity": 40}`, key: "temperature", wantErr: true},
+		{name: "missing nested key", payload: `{"sensor": 21.5}`, key: "sensor.temperature", wantErr: true},
+		{name: "not a number", payload: `{"temperature": true}`, key: "temperature", wantErr: true},
+		{name: "not json", payload: "21.5", key: "temperature", wantErr: true},
+		{name: "text", payload: "warm", wantErr: true},
+		{name: "plain NaN", payload: "NaN", wantErr: true},
+		{name: "plain Inf", payload: "+Inf", wantErr: true},
+		{name: "string NaN", payload: `{"temperature": "NaN"}`, key: "temperature", wantErr: true},
+		{name: "out of float32", payload: "1e300", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExternalTemperature([]byte(tt.payload), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExternalTemperature(%q, %q) error = %v, wantErr %v", tt.payload, tt.key, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseExternalTemperature(%q, %q) = %v, want %v", tt.payload, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestDesiredStateIsReset(t *testing.T) {
+	cool, off := "cool", "off"
+	temperature := float32(24)
+
+	tests := []struct {
+		name   string
+		state  DesiredState
+		status DeviceStatusHass
+		want   bool
+	}{
+		{name: "off after a power loss", state: DesiredState{Mode: &cool, Temperature: &temperature}, status: DeviceStatusHass{Mode: "off"}, want: true},
+		{name: "changed by the remote", state: DesiredState{Mode: &cool, Temperature: &temperature}, status: DeviceStatusHass{Mode: "heat", Temperature: 28}},
+		{name: "same state", state: DesiredState{Mode: &cool, Temperature: &temperature}, status: DeviceStatusHass{Mode: "cool", Temperature: 24}},
+		{name: "commanded off", state: DesiredState{Mode: &off}, status: DeviceStatusHass{Mode: "off"}},
+		{name: "no mode", state: DesiredState{Temperature: &temperature}, status: DeviceStatusHass{Mode: "off"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.IsReset(tt.status); got != tt.want {
+				t.Fatalf("IsReset(%+v) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupState(t *testing.T) {
+	off := &DeviceStatusRaw{Power: StatusOff}
+	cool := &DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["cool"])}
+	heat := &DeviceStatusRaw{Power: StatusOn, Mode: byte(ModeStatusesInvert["heat"])}
+
+	tests := []struct {
+		name    string
+		members []GroupMember
+		want    string
+	}{
+		{name: "no members", members: nil, want: GroupStateUnknown},
+		{name: "all off", members: []GroupMember{{Status: off, IsAvailable: true}, {Status: off, IsAvailable: true}}, want: GroupStateAllOff},
+		{name: "all cool", members: []GroupMember{{Status: cool, IsAvailable: true}, {Status: cool, IsAvailable: true}}, want: GroupStateAll + "cool"},
+		{name: "mixed", members: []GroupMember{{Status: cool, IsAvailable: true}, {Status: heat, IsAvailable: true}}, want: GroupStateMixed},
+		{name: "not answered", members: []GroupMember{{Status: cool, IsAvailable: true}, {IsAvailable: true}}, want: GroupStateUnknown},
+		{name: "offline member", members: []GroupMember{{Status: cool, IsAvailable: true}, {Status: cool}}, want: GroupStateUnknown},
+		{name: "offline and mixed", members: []GroupMember{{Status: heat, IsAvailable: true}, {Status: cool}}, want: GroupStateUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := GroupState(tt.members); got != tt.want {
+			t.Fatalf("%s: GroupState() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateTimerInputAt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "90", want: now.Add(time.Minute * 90)},
+		{value: "1h30m", want: now.Add(time.Minute * 90)},
+		{value: "0", want: time.Time{}},
+		{value: "0s", want: time.Time{}},
+		{value: "0h0m", want: time.Time{}},
+		{value: "", want: time.Time{}},
+		{value: "NaN", wantErr: true},
+		{value: "Inf", wantErr: true},
+		{value: "-Inf", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "1e300", wantErr: true},
+		{value: "10081", wantErr: true},
+		{value: "200h", wantErr: true},
+		{value: "2024-06-01T10:00:00Z", wantErr: true},
+		{value: "2024-05-01T09:00:00Z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		at, err := (&UpdateTimerInput{Kind: TimerOff, Value: tt.value}).At(now)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("At(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if !at.Equal(tt.want) {
+			t.Fatalf("At(%q) = %v, want %v", tt.value, at, tt.want)
+		}
+	}
+}
+
+func TestNativeTimer(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		timer *TimerState
+		want  byte
+	}{
+		{name: "not set", timer: nil, want: 0},
+		{name: "expired", timer: &TimerState{At: now}, want: 0},
+		{name: "one step", timer: &TimerState{At: now.Add(NativeTimerStep)}, want: 0b10000001},
+		{name: "rounded up", timer: &TimerState{At: now.Add(time.Minute * 16)}, want: 0b10000010},
+		{name: "longest", timer: &TimerState{At: now.Add(NativeTimerMaxDelay)}, want: 0xff},
+		{name: "too long", timer: &TimerState{At: now.Add(NativeTimerMaxDelay + time.Second)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := NativeTimer(tt.timer, now); got != tt.want {
+			t.Fatalf("%s: NativeTimer() = %08b, want %08b", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateDeviceStatesInputMerge(t *testing.T) {
+	command := UpdateDeviceStatesInput{Mac: "34ea34dadac8", IsTimerChanged: true}
+	command.Merge(UpdateDeviceStatesInput{Mac: "34ea34dadac8"})
+	if !command.IsTimerChanged {
+		t.Fatal("the changed timer is dropped by the merge")
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// updateCycle remembers when the unit has been switched on or to another mode and when it has been switched off
+func (s *service) updateCycle(ctx context.Context, m *deviceMonitor, previous, status models.DeviceStatusRaw, now time.Time) error {
+	cycle := m.cycle
+	if !cycle.Update(previous, status, now) {
+		return nil
+	}
+
+	upsertCycleInput := &modelsRepo.UpsertCycleInput{
+		Mac:   m.mac,
+		Cycle: modelsRepo.Cycle(cycle),
+	}
+	err := s.cache.UpsertCycle(ctx, upsertCycleInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the cycle",
+			slog.Any("err", err),
+			slog.Any("input", upsertCycleInput))
+		return err
+	}
+
+	m.cycle = cycle
+	return nil
+}
+
+// readCycle returns the times of the last start and stop of the unit, they are zero before the first change
+func (s *service) readCycle(ctx context.Context, mac string) (models.Cycle, error) {
+	readCycleInput := &modelsRepo.ReadCycleInput{Mac: mac}
+	readCycleReturn, err := s.cache.ReadCycle(ctx, readCycleInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceCycleNotFound) {
+			return models.Cycle{}, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the cycle",
+			slog.Any("err", err),
+			slog.Any("input", readCycleInput))
+		return models.Cycle{}, err
+	}
+
+	return models.Cycle(readCycleReturn.Cycle), nil
+}
+
+// shortCycleWait returns how long the power or mode change of the command has to wait and the reason.
+// Nothing is known about the unit before the first state read, so the command is not delayed then.
+func (m *deviceMonitor) shortCycleWait(now time.Time, command models.UpdateDeviceStatesInput) (time.Duration, string) {
+	if m.lastStatus == nil {
+		return 0, ""
+	}
+
+	return m.config.ShortCycleWait(now, command, *m.lastStatus, m.cycle)
+}
+
+// holdCommand removes the mode from the command which would short cycle the compressor and reports it to the events topic.
+// It returns the command with the mode which is queued, or nil when the mode is rejected.
+func (s *service) holdCommand(ctx context.Context, m *deviceMonitor, command *models.UpdateDeviceStatesInput, wait time.Duration, reason string) (*models.UpdateDeviceStatesInput, error) {
+	held := &models.UpdateDeviceStatesInput{Mac: command.Mac, Mode: command.Mode}
+	command.Mode = nil
+
+	seconds := strconv.Itoa(int(wait.Round(time.Second).Seconds()))
+	changes := map[string]string{"mode": *held.Mode}
+
+	if m.config.ShortCycleAction == models.ShortCycleActionReject {
+		s.logger.InfoContext(ctx, "the mode change is rejected by the protection",
+			slog.String("device", m.mac),
+			slog.String("reason", reason),
+			slog.Any("command", held))
+
+		err := s.publishDeviceEvent(ctx, m.mac, models.EventTypeRejected, models.EventSourceBridge,
+			"the mode change is rejected: "+reason+", allowed in "+seconds+" s", changes)
+		if err != nil {
+			return nil, err
+		}
+
+		// The mode has already been shown as commanded, so the current one is published again
+		return nil, s.publishCurrentMode(ctx, m)
+	}
+
+	s.logger.InfoContext(ctx, "the mode change is delayed by the protection",
+		slog.String("device", m.mac),
+		slog.String("reason", reason),
+		slog.Duration("wait", wait),
+		slog.Any("command", held))
+
+	err := s.publishDeviceEvent(ctx, m.mac, models.EventTypeDelayed, models.EventSourceBridge,
+		"the mode change is delayed by "+seconds+" s: "+reason, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+// publishCurrentMode publishes the mode of the unit as it is shown by GetDeviceStates
+func (s *service) publishCurrentMode(ctx context.Context, m *deviceMonitor) error {
+	if m.lastStatus == nil {
+		return nil
+	}
+
+	status := m.lastStatus.ConvertToDeviceStatusHass()
+	err := s.applyThermostat(ctx, m.config, &status)
+	if err != nil {
+		return err
+	}
+	err = s.applyHeatCool(ctx, m.config, &status)
+	if err != nil {
+		return err
+	}
+
+	publishModeInput := &modelsMqtt.PublishModeInput{
+		Mac:  m.mac,
+		Mode: status.Mode,
+	}
+	err = s.mqtt.PublishMode(ctx, publishModeInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish mode to mqtt",
+			slog.Any("err", err),
+			slog.Any("input", publishModeInput))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// updateDesiredState merges the command sent to the device into the desired state
+func (s *service) updateDesiredState(ctx context.Context, command models.UpdateDeviceStatesInput) error {
+	state, err := s.readDesiredState(ctx, command.Mac)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &models.DesiredState{}
+	}
+	state.Merge(command)
+
+	upsertDesiredStateInput := &modelsRepo.UpsertDesiredStateInput{
+		Mac:   command.Mac,
+		State: modelsRepo.DesiredState(*state),
+	}
+	err = s.cache.UpsertDesiredState(ctx, upsertDesiredStateInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the desired state",
+			slog.Any("err", err),
+			slog.Any("input", upsertDesiredStateInput))
+		return err
+	}
+
+	return nil
+}
+
+// readDesiredState returns the desired state or nil when nothing has been commanded yet
+func (s *service) readDesiredState(ctx context.Context, mac string) (*models.DesiredState, error) {
+	readDesiredStateInput := &modelsRepo.ReadDesiredStateInput{Mac: mac}
+	readDesiredStateReturn, err := s.cache.ReadDesiredState(ctx, readDesiredStateInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceDesiredStateNotFound) {
+			return nil, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the desired state",
+			slog.Any("err", err),
+			slog.Any("input", readDesiredStateInput))
+		return nil, err
+	}
+
+	state := models.DesiredState(readDesiredStateReturn.State)
+	return &state, nil
+}
+
+// enforceCommand returns the command which reverts the change made by the IR remote according to
+// the enforce policy of the device and publishes the event about it. Otherwise, it returns nil.
+func (s *service) enforceCommand(ctx context.Context, m *deviceMonitor, status *models.DeviceStatusRaw) (*models.UpdateDeviceStatesInput, error) {
+	desired, err := s.readDesiredState(ctx, m.mac)
+	if err != nil {
+		return nil, err
+	}
+
+	// The changes by the virtual thermostat are not reverted
+	statusHass := status.ConvertToDeviceStatusHass()
+	err = s.applyThermostat(ctx, m.config, &statusHass)
+	if err != nil {
+		return nil, err
+	}
+
+	command := m.config.EnforceCommand(time.Now().In(s.location), desired, statusHass)
+	if command == nil {
+		return nil, nil
+	}
+
+	s.logger.InfoContext(ctx, "the change by the IR remote is overridden",
+		slog.String("device", m.mac),
+		slog.Any("status", status),
+		slog.Any("command", command))
+
+	err = s.publishDeviceEvent(ctx, m.mac, models.EventTypeOverridden, models.EventSourceRemote,
+		"the change by the remote is reverted by the enforce policy", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return command, nil
+}
+
+// restoreCommand returns the command which restores the desired state when the unit looks reset by a power loss,
+// i.e. it is off while it has been commanded on. Otherwise, it returns nil.
+func (s *service) restoreCommand(ctx context.Context, mac string, status *models.DeviceStatusRaw) (*models.UpdateDeviceStatesInput, error) {
+	if status == nil {
+		return nil, nil
+	}
+
+	state, err := s.readDesiredState(ctx, mac)
+	if err != nil || state == nil {
+		return nil, err
+	}
+
+	if !state.IsReset(status.ConvertToDeviceStatusHass()) {
+		return nil, nil
+	}
+
+	s.logger.InfoContext(ctx, "device is off after the reconnection as after a power loss, restoring the desired state",
+		slog.String("device", mac),
+		slog.Any("desiredState", state),
+		slog.Any("status", status))
+
+	command := state.Command(mac)
+	return &command, nil
+}
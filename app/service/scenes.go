@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// ApplyScene sends the states of the scene to the device as one command
+func (s *service) ApplyScene(ctx context.Context, input *models.ApplySceneInput) error {
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find the device actor",
+			slog.Any("err", err),
+			slog.String("device", input.Mac))
+		return err
+	}
+
+	scene, ok := findScene(actor.scenes, input.Name)
+	if !ok {
+		s.logger.ErrorContext(ctx, "scene is not found",
+			slog.String("device", input.Mac),
+			slog.String("scene", input.Name))
+		return models.ErrorSceneNotFound
+	}
+
+	s.logger.InfoContext(ctx, "the scene is applied",
+		slog.String("device", input.Mac),
+		slog.String("scene", scene.Name))
+
+	err = s.updateStates(ctx, scene.Command)
+	if err != nil {
+		return err
+	}
+
+	return s.publishDeviceEvent(ctx, input.Mac, models.EventTypeScene, models.EventSourceMqtt,
+		"the scene "+scene.Name+" is applied", nil)
+}
+
+// ApplySceneToAll applies the scene to every device which supports it. A failure of one device does not stop the others.
+func (s *service) ApplySceneToAll(ctx context.Context, input *models.ApplySceneToAllInput) error {
+	s.actorsMutex.RLock()
+	macs := make([]string, 0, len(s.actors))
+	for mac, actor := range s.actors {
+		if _, ok := findScene(actor.scenes, input.Name); ok {
+			macs = append(macs, mac)
+		}
+	}
+	s.actorsMutex.RUnlock()
+	slices.Sort(macs)
+
+	if len(macs) == 0 {
+		s.logger.ErrorContext(ctx, "scene is not found",
+			slog.String("scene", input.Name))
+		return models.ErrorSceneNotFound
+	}
+
+	var errs []error
+	for _, mac := range macs {
+		err := s.ApplyScene(ctx, &models.ApplySceneInput{Mac: mac, Name: input.Name})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to apply the scene",
+				slog.Any("err", err),
+				slog.String("device", mac),
+				slog.String("scene", input.Name))
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func findScene(scenes []models.Scene, name string) (models.Scene, bool) {
+	for _, scene := range scenes {
+		if scene.Name == name {
+			return scene, true
+		}
+	}
+
+	return models.Scene{}, false
+}
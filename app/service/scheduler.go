@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// StartScheduler runs the schedules of the device. The commands are sent to the device actor like the MQTT commands.
+// The last run missed while the bridge was stopped is made once at the start if it is within the catch-up window.
+func (s *service) StartScheduler(ctx context.Context, input *models.StartSchedulerInput) error {
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find the device actor",
+			slog.Any("err", err),
+			slog.String("device", input.Mac))
+		return err
+	}
+
+	if len(actor.schedules) == 0 {
+		return nil
+	}
+
+	type dueSchedule struct {
+		schedule models.Schedule
+		state    models.ScheduleState
+	}
+
+	// The schedules which failed to initialize are retried, so a failure does not restart the device worker
+	initialized := make(map[string]bool, len(actor.schedules))
+	for {
+		now := time.Now().In(s.location)
+		next := now.Add(models.ScheduleCheckInterval)
+		retryAt := now.Add(models.RetryInterval)
+
+		var due []dueSchedule
+		for _, schedule := range actor.schedules {
+			if !initialized[schedule.Name] {
+				err = s.initScheduleState(ctx, input.Mac, schedule, now)
+				if err != nil {
+					s.logger.ErrorContext(ctx, "failed to initialize the schedule, it is retried",
+						slog.Any("err", err),
+						slog.String("device", input.Mac),
+						slog.String("schedule", schedule.Name))
+					next = earliest(next, retryAt)
+					continue
+				}
+				initialized[schedule.Name] = true
+			}
+
+			state, err := s.readScheduleState(ctx, input.Mac, schedule.Name)
+			if err != nil {
+				return err
+			}
+			if state == nil {
+				continue
+			}
+
+			if state.NextRun.After(now) {
+				if state.NextRun.Before(next) {
+					next = state.NextRun
+				}
+				continue
+			}
+			due = append(due, dueSchedule{schedule: schedule, state: *state})
+		}
+
+		// The older runs are made first, so the latest one wins
+		sort.Slice(due, func(i, j int) bool {
+			return due[i].state.NextRun.Before(due[j].state.NextRun)
+		})
+		for _, d := range due {
+			state, err := s.runSchedule(ctx, input.Mac, d.schedule, d.state, now)
+			if err != nil {
+				// The run is kept as due, so it is made on the next check
+				s.logger.ErrorContext(ctx, "failed to run the schedule, it is retried",
+					slog.Any("err", err),
+					slog.String("device", input.Mac),
+					slog.String("schedule", d.schedule.Name))
+				next = earliest(next, retryAt)
+				continue
+			}
+			next = earliest(next, state.NextRun)
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// initScheduleState creates the state of a new schedule and publishes the switch state.
+// A future run is recalculated as the rule may have been changed in the config.
+// Of the missed runs only the latest one is kept, and only when it is within the catch-up window.
+func (s *service) initScheduleState(ctx context.Context, mac string, schedule models.Schedule, now time.Time) error {
+	state, err := s.readScheduleState(ctx, mac, schedule.Name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case state == nil:
+		state = &models.ScheduleState{Enabled: schedule.Enabled, NextRun: schedule.NextRun(now)}
+	case state.NextRun.After(now):
+		state.NextRun = schedule.NextRun(now)
+	default:
+		// The time read from the state file has a fixed offset, so it is moved to the zone of the schedules
+		latest := state.NextRun.In(s.location)
+		for run := schedule.NextRun(latest); !run.IsZero() && !run.After(now); run = schedule.NextRun(run) {
+			latest = run
+		}
+
+		if now.Sub(latest) > models.CatchUpWindow {
+			s.logger.InfoContext(ctx, "the missed schedule run is too old, it is skipped",
+				slog.String("device", mac),
+				slog.String("schedule", schedule.Name),
+				slog.Time("planned", latest))
+			latest = schedule.NextRun(now)
+		}
+		state.NextRun = latest
+	}
+
+	err = s.upsertScheduleState(ctx, mac, schedule.Name, *state)
+	if err != nil {
+		return err
+	}
+
+	return s.publishScheduleSwitch(ctx, mac, schedule.Name, state.Enabled)
+}
+
+// runSchedule sends the command of the enabled schedule and plans the next run
+func (s *service) runSchedule(ctx context.Context, mac string, schedule models.Schedule, state models.ScheduleState, now time.Time) (models.ScheduleState, error) {
+	if state.Enabled {
+		s.logger.InfoContext(ctx, "the schedule is run",
+			slog.String("device", mac),
+			slog.String("schedule", schedule.Name),
+			slog.Time("planned", state.NextRun))
+
+		if schedule.Command != (models.UpdateDeviceStatesInput{Mac: schedule.Command.Mac}) {
+			err := s.updateStates(ctx, schedule.Command)
+			if err != nil {
+				return state, err
+			}
+		}
+
+		// The curve starts from the setpoint of the schedule when it has one
+		if len(schedule.Sleep) != 0 {
+			err := s.startSleep(ctx, mac, schedule.Sleep, schedule.Command.Temperature, models.EventSourceSchedule)
+			if err != nil {
+				return state, err
+			}
+		}
+
+		lastRun := now
+		state.LastRun = &lastRun
+
+		err := s.publishDeviceEvent(ctx, mac, models.EventTypeScheduled, models.EventSourceSchedule,
+			"the schedule "+schedule.Name+" is run", nil)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	state.NextRun = schedule.NextRun(now)
+
+	return state, s.upsertScheduleState(ctx, mac, schedule.Name, state)
+}
+
+func (s *service) UpdateScheduleSwitch(ctx context.Context, input *models.UpdateScheduleSwitchInput) error {
+	err := input.Validate()
+	if err != nil {
+		s.logger.ErrorContext(ctx, "input data is not valid",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", input))
+		return err
+	}
+
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		return err
+	}
+
+	var schedule *models.Schedule
+	for i := range actor.schedules {
+		if actor.schedules[i].Name == input.Name {
+			schedule = &actor.schedules[i]
+		}
+	}
+	if schedule == nil {
+		s.logger.ErrorContext(ctx, "schedule is not found",
+			slog.String("device", input.Mac),
+			slog.String("schedule", input.Name))
+		return models.ErrorScheduleNotFound
+	}
+
+	state, err := s.readScheduleState(ctx, input.Mac, input.Name)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &models.ScheduleState{NextRun: schedule.NextRun(time.Now().In(s.location))}
+	}
+	state.Enabled = input.Status == "ON"
+
+	err = s.upsertScheduleState(ctx, input.Mac, input.Name, *state)
+	if err != nil {
+		return err
+	}
+
+	return s.publishScheduleSwitch(ctx, input.Mac, input.Name, state.Enabled)
+}
+
+// readScheduleState returns the state of the schedule or nil when it is not created yet
+func (s *service) readScheduleState(ctx context.Context, mac, name string) (*models.ScheduleState, error) {
+	readScheduleStateInput := &modelsRepo.ReadScheduleStateInput{Mac: mac, Name: name}
+	readScheduleStateReturn, err := s.storage.ReadScheduleState(ctx, readScheduleStateInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorScheduleStateNotFound) {
+			return nil, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the schedule state",
+			slog.Any("err", err),
+			slog.Any("input", readScheduleStateInput))
+		return nil, err
+	}
+
+	state := models.ScheduleState(readScheduleStateReturn.State)
+	return &state, nil
+}
+
+func (s *service) upsertScheduleState(ctx context.Context, mac, name string, state models.ScheduleState) error {
+	upsertScheduleStateInput := &modelsRepo.UpsertScheduleStateInput{
+		Mac:   mac,
+		Name:  name,
+		State: modelsRepo.ScheduleState(state),
+	}
+	err := s.storage.UpsertScheduleState(ctx, upsertScheduleStateInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the schedule state",
+			slog.Any("err", err),
+			slog.Any("input", upsertScheduleStateInput))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) publishScheduleSwitch(ctx context.Context, mac, name string, isEnabled bool) error {
+	publishScheduleSwitchInput := &modelsMqtt.PublishScheduleSwitchInput{
+		Mac:    mac,
+		Name:   name,
+		Status: "OFF",
+	}
+	if isEnabled {
+		publishScheduleSwitchInput.Status = "ON"
+	}
+
+	err := s.mqtt.PublishScheduleSwitch(ctx, publishScheduleSwitchInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the schedule switch",
+			slog.Any("err", err),
+			slog.Any("input", publishScheduleSwitchInput))
+		return err
+	}
+
+	return nil
+}
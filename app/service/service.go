@@ -3,9 +3,13 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"math/rand"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/ArtemVladimirov/broadlinkac2mqtt/app"
@@ -19,22 +23,32 @@ import (
 )
 
 type service struct {
-	updateInterval int
-	topicPrefix    string
-	mqtt           app.MqttPublisher
-	webClient      app.WebClient
-	cache          app.Cache
-	logger         *slog.Logger
+	topicPrefix string
+	mqtt        app.MqttPublisher
+	webClient   app.WebClient
+	cache       app.Cache
+	storage     app.Storage
+	location    *time.Location
+	logger      *slog.Logger
+
+	actorsMutex sync.RWMutex
+	actors      map[string]*deviceActor
+
+	groupsMutex sync.RWMutex
+	groups      map[string]models.Group
 }
 
-func NewService(logger *slog.Logger, topicPrefix string, updateInterval int, mqtt app.MqttPublisher, webClient app.WebClient, cache app.Cache) app.Service {
+func NewService(logger *slog.Logger, topicPrefix string, location *time.Location, mqtt app.MqttPublisher, webClient app.WebClient, cache app.Cache, storage app.Storage) app.Service {
 	return &service{
-		logger:         logger,
-		topicPrefix:    topicPrefix,
-		updateInterval: updateInterval,
-		mqtt:           mqtt,
-		webClient:      webClient,
-		cache:          cache,
+		logger:      logger,
+		topicPrefix: topicPrefix,
+		location:    location,
+		mqtt:        mqtt,
+		webClient:   webClient,
+		cache:       cache,
+		storage:     storage,
+		actors:      make(map[string]*deviceActor),
+		groups:      make(map[string]models.Group),
 	}
 }
 
@@ -69,6 +83,10 @@ func (s *service) CreateDevice(ctx context.Context, input *models.CreateDeviceIn
 		return err
 	}
 
+	s.actorsMutex.Lock()
+	s.actors[input.Config.Mac] = newDeviceActor(input.Schedules, input.Contacts, input.Scenes, input.SleepProfiles)
+	s.actorsMutex.Unlock()
+
 	return nil
 }
 
@@ -140,13 +158,10 @@ func (s *service) AuthDevice(ctx context.Context, input *models.AuthDeviceInput)
 		return err
 	}
 
-	// Decode message
-	if len(response.Payload) >= 0x38 {
-		response.Payload = response.Payload[0x38:]
-	} else {
-		const msg = "response is too short"
-		s.logger.ErrorContext(ctx, msg, slog.Any("input", input), slog.Any("payload", response.Payload))
-		return errors.New(msg)
+	// The payload contains the device id and the new key
+	response.Payload, err = s.decryptResponse(ctx, input.Mac, response.Payload, 0x14)
+	if err != nil {
+		return err
 	}
 
 	// Read the saved value in repo if no
@@ -160,17 +175,16 @@ func (s *service) AuthDevice(ctx context.Context, input *models.AuthDeviceInput)
 	}
 	auth := readDeviceAuthReturn.Auth
 
-	response.Payload, err = coder.Decrypt(auth.Key, auth.Iv, response.Payload)
+	id, key, err := models.DecodeDeviceAuth(response.Payload)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to decode response", slog.Any("err", err), slog.Any("input", input))
 		return err
 	}
 
 	auth = modelsRepo.DeviceAuth{
 		LastMessageId: auth.LastMessageId,
 		DevType:       auth.DevType,
-		Id:            [4]byte{response.Payload[0], response.Payload[1], response.Payload[2], response.Payload[3]},
-		Key:           response.Payload[0x04:0x14],
+		Id:            id,
+		Key:           key,
 		Iv:            auth.Iv,
 	}
 
@@ -179,7 +193,139 @@ func (s *service) AuthDevice(ctx context.Context, input *models.AuthDeviceInput)
 		Mac:  input.Mac,
 		Auth: auth,
 	}
-	return s.cache.UpsertDeviceAuth(ctx, upsertDeviceAuthInput)
+	err = s.cache.UpsertDeviceAuth(ctx, upsertDeviceAuthInput)
+	if err != nil {
+		return err
+	}
+
+	// The commands wait in the queue of the actor until it is started
+	s.setActorReady(input.Mac, true)
+
+	return nil
+}
+
+// GetDeviceFirmware queries the firmware version of the device, saves it in the cache
+// and updates the bridge device inventory. The firmware is queried once, not after every reauthorization.
+func (s *service) GetDeviceFirmware(ctx context.Context, input *models.GetDeviceFirmwareInput) error {
+	readDeviceFirmwareInput := &modelsRepo.ReadDeviceFirmwareInput{Mac: input.Mac}
+	_, err := s.cache.ReadDeviceFirmware(ctx, readDeviceFirmwareInput)
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, modelsRepo.ErrorDeviceFirmwareNotFound):
+		s.logger.ErrorContext(ctx, "failed to read the device firmware",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceFirmwareInput))
+		return err
+	}
+
+	sendCommandInput := &models.SendCommandInput{
+		Command: 0x6a,
+		Payload: []byte{0x68, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		Mac:     input.Mac,
+	}
+	response, err := s.sendCommand(ctx, sendCommandInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to send a command", slog.Any("err", err), slog.Any("input", sendCommandInput))
+		return err
+	}
+
+	payload, err := s.decryptResponse(ctx, input.Mac, response.Payload, 6)
+	if err != nil {
+		return err
+	}
+
+	firmware := modelsRepo.DeviceFirmware{
+		Version: int(payload[4]) | int(payload[5])<<8,
+	}
+
+	s.logger.InfoContext(ctx, "Device firmware",
+		slog.String("device", input.Mac),
+		slog.Int("version", firmware.Version))
+
+	upsertDeviceFirmwareInput := &modelsRepo.UpsertDeviceFirmwareInput{
+		Mac:      input.Mac,
+		Firmware: firmware,
+	}
+	err = s.cache.UpsertDeviceFirmware(ctx, upsertDeviceFirmwareInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the device firmware",
+			slog.Any("err", err),
+			slog.Any("input", upsertDeviceFirmwareInput))
+		return err
+	}
+
+	return s.publishBridgeDevices(ctx)
+}
+
+// publishBridgeDevices publishes the inventory of all devices of the bridge.
+// A device which cannot be resolved is left out, so it does not keep the others stale.
+func (s *service) publishBridgeDevices(ctx context.Context) error {
+	readAuthedDevicesReturn, err := s.cache.ReadAuthedDevices(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read authed devices",
+			slog.Any("err", err))
+		return err
+	}
+
+	devices := make([]modelsMqtt.BridgeDevice, 0, len(readAuthedDevicesReturn.Macs))
+	for _, mac := range readAuthedDevicesReturn.Macs {
+		readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{
+			Mac: mac,
+		}
+		readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to read device config",
+				slog.Any("err", err),
+				slog.Any("input", readDeviceConfigInput))
+			continue
+		}
+
+		device := modelsMqtt.BridgeDevice{
+			Mac:  mac,
+			Name: readDeviceConfigReturn.Config.Name,
+			Ip:   readDeviceConfigReturn.Config.Ip,
+		}
+
+		// The firmware is left empty when it cannot be read
+		device.Firmware, _ = s.readFirmwareVersion(ctx, mac)
+
+		devices = append(devices, device)
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Mac < devices[j].Mac
+	})
+
+	publishBridgeDevicesInput := &modelsMqtt.PublishBridgeDevicesInput{
+		Devices: devices,
+	}
+	err = s.mqtt.PublishBridgeDevices(ctx, publishBridgeDevicesInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the bridge devices",
+			slog.Any("err", err),
+			slog.Any("input", publishBridgeDevicesInput))
+		return err
+	}
+
+	return nil
+}
+
+// readFirmwareVersion returns the cached firmware version or an empty string if it is unknown yet
+func (s *service) readFirmwareVersion(ctx context.Context, mac string) (string, error) {
+	readDeviceFirmwareInput := &modelsRepo.ReadDeviceFirmwareInput{Mac: mac}
+	readDeviceFirmwareReturn, err := s.cache.ReadDeviceFirmware(ctx, readDeviceFirmwareInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceFirmwareNotFound) {
+			return "", nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the device firmware",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceFirmwareInput))
+		return "", err
+	}
+
+	return strconv.Itoa(readDeviceFirmwareReturn.Firmware.Version), nil
 }
 
 /*
@@ -221,43 +367,95 @@ func (s *service) GetDeviceAmbientTemperature(ctx context.Context, input *models
 		return err
 	}
 
-	if uint16(response.Payload[0x22])|(uint16(response.Payload[0x23])<<8) != 0 {
-		s.logger.ErrorContext(ctx, "Checksum is incorrect", slog.Any("input", sendCommandInput))
-		return models.ErrorInvalidResultPacket
+	// The info frame is 40 bytes after the 2 leading bytes
+	response.Payload, err = s.decryptResponse(ctx, input.Mac, response.Payload, 42)
+	if err != nil {
+		return err
+	}
+
+	info, err := models.DecodeDeviceInfoRaw(response.Payload, time.Now())
+	if err != nil {
+		return err
 	}
+	ambientTemp := info.AmbientTemp
 
-	// Decode message
-	if len(response.Payload) >= 0x38 {
-		response.Payload = response.Payload[0x38:]
-	} else {
-		s.logger.ErrorContext(ctx, "response is too short", slog.Any("input", sendCommandInput))
-		return models.ErrorInvalidResultPacketLength
+	err = s.updateDeviceInfo(ctx, input.Mac, info)
+	if err != nil {
+		return err
 	}
 
-	// Read the saved value in repo if no
-	readDeviceAuthInput := &modelsRepo.ReadDeviceAuthInput{
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{
 		Mac: input.Mac,
 	}
-	readDeviceAuthReturn, err := s.cache.ReadDeviceAuth(ctx, readDeviceAuthInput)
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "device not found", slog.Any("err", err), slog.Any("input", readDeviceAuthInput))
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", readDeviceConfigInput))
 		return err
 	}
+	config := readDeviceConfigReturn.Config
 
-	response.Payload, err = coder.Decrypt(readDeviceAuthReturn.Auth.Key, readDeviceAuthReturn.Auth.Iv, response.Payload)
+	readAmbientFilterInput := &modelsRepo.ReadAmbientFilterInput{Mac: input.Mac}
+	readAmbientFilterReturn, err := s.cache.ReadAmbientFilter(ctx, readAmbientFilterInput)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to decrypt response", slog.Any("err", err), slog.Any("input", response.Payload))
+		s.logger.ErrorContext(ctx, "failed to read the ambient filter",
+			slog.Any("err", err),
+			slog.Any("input", readAmbientFilterInput))
 		return err
 	}
+	filter := readAmbientFilterReturn.Filter
+
+	calibratedTemp := ambientTemp + config.AmbientOffset
+
+	// Sometimes there is strange temperature. A jump is accepted only if it repeats AmbientOutliersLimit times in a row,
+	// then the old samples are dropped so the filter follows the real change at once.
+	if len(filter.Samples) != 0 && config.AmbientOutlierThreshold > 0 {
+		lastTemp := filter.Samples[len(filter.Samples)-1]
+		if calibratedTemp-lastTemp > config.AmbientOutlierThreshold || lastTemp-calibratedTemp > config.AmbientOutlierThreshold {
+			filter.Outliers++
+			if filter.Outliers < models.AmbientOutliersLimit {
+				upsertAmbientFilterInput := &modelsRepo.UpsertAmbientFilterInput{Mac: input.Mac, Filter: filter}
+				err = s.cache.UpsertAmbientFilter(ctx, upsertAmbientFilterInput)
+				if err != nil {
+					s.logger.ErrorContext(ctx, "failed to upsert the ambient filter",
+						slog.Any("input", upsertAmbientFilterInput),
+						slog.Any("err", err))
+					return err
+				}
+
+				s.logger.ErrorContext(ctx, "failed to read the ambient temperature",
+					slog.String("device", input.Mac),
+					slog.Any("ambientTemp", calibratedTemp),
+					slog.Any("lastAmbientTemp", lastTemp))
+				return models.ErrorInvalidParameterTemperature
+			}
+			filter.Samples = nil
+		}
+	}
 
-	//Drop leading stuff as don't need
-	response.Payload = response.Payload[2:]
+	filter.Outliers = 0
+	filter.Samples = append(filter.Samples, calibratedTemp)
+	if len(filter.Samples) > config.AmbientSamples {
+		filter.Samples = filter.Samples[len(filter.Samples)-config.AmbientSamples:]
+	}
 
-	if len(response.Payload) < 40 {
-		return models.ErrorInvalidResultPacketLength
+	upsertAmbientFilterInput := &modelsRepo.UpsertAmbientFilterInput{Mac: input.Mac, Filter: filter}
+	err = s.cache.UpsertAmbientFilter(ctx, upsertAmbientFilterInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the ambient filter",
+			slog.Any("input", upsertAmbientFilterInput),
+			slog.Any("err", err))
+		return err
 	}
 
-	ambientTemp := float32(response.Payload[15]-0b00100000) + (float32(response.Payload[31]) / 10)
+	ambientTemp = float32(math.Round(float64(models.SmoothAmbientTemp(config.AmbientSmoothing, filter.Samples))*10) / 10)
+
+	s.logger.DebugContext(ctx, "Ambient temperature",
+		slog.Any("ambientTemp", ambientTemp),
+		slog.Any("rawAmbientTemp", info.AmbientTemp),
+		slog.String("device", input.Mac))
 
 	readAmbientTempInput := &modelsRepo.ReadAmbientTempInput{Mac: input.Mac}
 	readAmbientTempReturn, err := s.cache.ReadAmbientTemp(ctx, readAmbientTempInput)
@@ -273,35 +471,18 @@ func (s *service) GetDeviceAmbientTemperature(ctx context.Context, input *models
 		}
 	}
 
-	if readAmbientTempReturn != nil {
-		// Sometimes there is strange temperature
-		if readAmbientTempReturn.Temperature-ambientTemp > 4 || ambientTemp-readAmbientTempReturn.Temperature > 4 {
-			s.logger.ErrorContext(ctx, "failed to read the ambient temperature", slog.Any("input", readAmbientTempInput))
-			return models.ErrorInvalidParameterTemperature
-		}
+	// The reading of the unit is published only when there is no fresh reading of the external sensor.
+	// It is published every time the external sensor is stale, as the topic has been overwritten by the sensor.
+	externalTemp, err := s.readExternalTemp(ctx, input.Mac, models.DeviceConfig(config))
+	if err != nil {
+		return err
 	}
-
-	s.logger.DebugContext(ctx, "Ambient temperature",
-		slog.Any("ambientTemp", ambientTemp),
-		slog.String("device", input.Mac))
-
-	if readAmbientTempReturn == nil || readAmbientTempReturn.Temperature != ambientTemp {
-		readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{
-			Mac: input.Mac,
-		}
-		readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
-		if err != nil {
-			s.logger.ErrorContext(ctx, "failed to read device config",
-				slog.Any("err", err),
-				slog.String("device", input.Mac),
-				slog.Any("input", readDeviceConfigInput))
-			return err
-		}
-
+	isChanged := readAmbientTempReturn == nil || readAmbientTempReturn.Temperature != ambientTemp
+	if externalTemp == nil && (isChanged || len(config.ExternalTempTopic) != 0) {
 		// Sent  temperature to MQTT
 		publishAmbientTempInput := &modelsMqtt.PublishAmbientTempInput{
 			Mac:         input.Mac,
-			Temperature: converter.Temperature(models.Celsius, readDeviceConfigReturn.Config.TemperatureUnit, ambientTemp),
+			Temperature: converter.Temperature(models.Celsius, config.TemperatureUnit, ambientTemp),
 		}
 		err = s.mqtt.PublishAmbientTemp(ctx, publishAmbientTempInput)
 		if err != nil {
@@ -310,7 +491,9 @@ func (s *service) GetDeviceAmbientTemperature(ctx context.Context, input *models
 				slog.Any("err", err))
 			return err
 		}
+	}
 
+	if isChanged {
 		// Save the new value in storage
 		upsertAmbientTempInput := &modelsRepo.UpsertAmbientTempInput{Temperature: ambientTemp, Mac: input.Mac}
 		err = s.cache.UpsertAmbientTemp(ctx, upsertAmbientTempInput)
@@ -322,7 +505,73 @@ func (s *service) GetDeviceAmbientTemperature(ctx context.Context, input *models
 		}
 	}
 
-	return nil
+	return s.updateHvacAction(ctx, input.Mac)
+}
+
+// updateDeviceInfo publishes the changed diagnostic values of the info frame and saves it in the cache
+func (s *service) updateDeviceInfo(ctx context.Context, mac string, info models.DeviceInfoRaw) error {
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{
+		Mac: mac,
+	}
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.String("device", mac),
+			slog.Any("input", readDeviceConfigInput))
+		return err
+	}
+
+	var previousDiagnostics map[string]string
+	readDeviceInfoRawInput := &modelsRepo.ReadDeviceInfoRawInput{Mac: mac}
+	readDeviceInfoRawReturn, err := s.cache.ReadDeviceInfoRaw(ctx, readDeviceInfoRawInput)
+	if err != nil {
+		if !errors.Is(err, modelsRepo.ErrorDeviceInfoRawNotFound) {
+			s.logger.ErrorContext(ctx, "failed to read the device info",
+				slog.Any("err", err),
+				slog.Any("input", readDeviceInfoRawInput))
+			return err
+		}
+	} else {
+		previousDiagnostics = models.DeviceInfoRaw(readDeviceInfoRawReturn.Info).ConvertToDiagnostics(readDeviceConfigReturn.Config.TemperatureUnit)
+	}
+
+	s.logger.DebugContext(ctx, "Device info",
+		slog.Any("info", info),
+		slog.String("device", mac))
+
+	for name, value := range info.ConvertToDiagnostics(readDeviceConfigReturn.Config.TemperatureUnit) {
+		if previousValue, ok := previousDiagnostics[name]; ok && previousValue == value {
+			continue
+		}
+
+		publishDiagnosticInput := &modelsMqtt.PublishDiagnosticInput{
+			Mac:   mac,
+			Name:  name,
+			Value: value,
+		}
+		err = s.mqtt.PublishDiagnostic(ctx, publishDiagnosticInput)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to publish the diagnostic value",
+				slog.Any("err", err),
+				slog.Any("input", publishDiagnosticInput))
+			return err
+		}
+	}
+
+	upsertDeviceInfoRawInput := &modelsRepo.UpsertDeviceInfoRawInput{
+		Mac:  mac,
+		Info: modelsRepo.DeviceInfoRaw(info),
+	}
+	err = s.cache.UpsertDeviceInfoRaw(ctx, upsertDeviceInfoRawInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the device info",
+			slog.Any("input", upsertDeviceInfoRawInput),
+			slog.Any("err", err))
+		return err
+	}
+
+	return s.updateInfoFaults(ctx, mac, info)
 }
 
 // GetDeviceStates returns devices states
@@ -345,86 +594,21 @@ func (s *service) GetDeviceStates(ctx context.Context, input *models.GetDeviceSt
 	//                 DECODE RESPONSE                        //
 	////////////////////////////////////////////////////////////
 
-	if uint16(response.Payload[0x22])|(uint16(response.Payload[0x23])<<8) != 0 {
-		s.logger.ErrorContext(ctx, "Checksum is incorrect",
-			slog.Any("input", response.Payload))
-		return models.ErrorInvalidResultPacket
-	}
-
-	// Read the saved value in repo if no
-	readDeviceAuthInput := &modelsRepo.ReadDeviceAuthInput{
-		Mac: input.Mac,
-	}
-	readDeviceAuthReturn, err := s.cache.ReadDeviceAuth(ctx, readDeviceAuthInput)
+	// The state frame is 0x19 bytes
+	response.Payload, err = s.decryptResponse(ctx, input.Mac, response.Payload, 0x19)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "device not found",
-			slog.Any("input", readDeviceAuthInput),
-			slog.String("device", input.Mac),
-			slog.Any("err", err))
 		return err
 	}
 
-	// Decode message
-	if len(response.Payload) >= 0x38 {
-		response.Payload = response.Payload[0x38:]
-	} else {
-		s.logger.ErrorContext(ctx, "response is too short",
-			slog.String("device", input.Mac),
-			slog.Any("input", response.Payload))
-		return models.ErrorInvalidResultPacketLength
-	}
-
-	response.Payload, err = coder.Decrypt(readDeviceAuthReturn.Auth.Key, readDeviceAuthReturn.Auth.Iv, response.Payload)
+	raw, err := models.DecodeDeviceStatusRaw(response.Payload, time.Now())
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to decrypt the response",
-			slog.Any("input", response.Payload),
+		s.logger.ErrorContext(ctx, "the state frame is invalid",
 			slog.String("device", input.Mac),
+			slog.Any("input", response.Payload),
 			slog.Any("err", err))
 		return err
 	}
 
-	if response.Payload[4] != 0x07 {
-		s.logger.ErrorContext(ctx, "it is not a result packet",
-			slog.String("device", input.Mac),
-			slog.Any("input", response.Payload))
-		return models.ErrorInvalidResultPacket
-	}
-
-	if response.Payload[0] != 0x19 {
-		s.logger.ErrorContext(ctx, "the length of the packet is incorrect. Must be 25",
-			slog.String("device", input.Mac),
-			slog.Any("input", response.Payload))
-		return models.ErrorInvalidResultPacketLength
-	}
-
-	//Drop leading stuff as don't need
-	response.Payload = response.Payload[2:]
-
-	var raw = models.DeviceStatusRaw{
-		UpdatedAt:          time.Now(),
-		Temperature:        float32(8+(response.Payload[10]>>3)) + 0.5*float32(response.Payload[12]>>7),
-		Power:              response.Payload[18] >> 5 & 0b00000001,
-		FixationVertical:   response.Payload[10] & 0b00000111,
-		Mode:               response.Payload[15] >> 5 & 0b00001111,
-		Sleep:              response.Payload[15] >> 2 & 0b00000001,
-		Display:            response.Payload[20] >> 4 & 0b00000001,
-		Mildew:             response.Payload[20] >> 3 & 0b00000001,
-		Health:             response.Payload[18] >> 1 & 0b00000001,
-		FixationHorizontal: response.Payload[10] & 0b00000111,
-		FanSpeed:           response.Payload[13] >> 5 & 0b00000111,
-		IFeel:              response.Payload[15] >> 3 & 0b00000001,
-		Mute:               response.Payload[14] >> 7 & 0b00000001,
-		Turbo:              response.Payload[14] >> 6 & 0b00000001,
-		Clean:              response.Payload[18] >> 2 & 0b00000001,
-	}
-
-	if raw.Temperature < 16.0 {
-		s.logger.ErrorContext(ctx, "wrong temperature, skip package",
-			slog.String("device", input.Mac),
-			slog.Any("input", raw.Temperature))
-		return models.ErrorInvalidResultPacketLength
-	}
-
 	//////////////////////////////////////////////////////////////////
 	//  Compare new statuses with old statuses and update  MQTT     //
 	//////////////////////////////////////////////////////////////////
@@ -446,7 +630,27 @@ func (s *service) GetDeviceStates(ctx context.Context, input *models.GetDeviceSt
 		}
 	}
 
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{
+		Mac: input.Mac,
+	}
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", readDeviceConfigInput))
+		return err
+	}
+
 	deviceStatusHass := raw.ConvertToDeviceStatusHass()
+	err = s.applyThermostat(ctx, models.DeviceConfig(readDeviceConfigReturn.Config), &deviceStatusHass)
+	if err != nil {
+		return err
+	}
+	err = s.applyHeatCool(ctx, models.DeviceConfig(readDeviceConfigReturn.Config), &deviceStatusHass)
+	if err != nil {
+		return err
+	}
 	s.logger.DebugContext(ctx, "The converted current device status",
 		slog.String("device", input.Mac))
 
@@ -455,22 +659,9 @@ func (s *service) GetDeviceStates(ctx context.Context, input *models.GetDeviceSt
 		if readDeviceStatusRawReturn == nil ||
 			readDeviceStatusRawReturn.Status.Temperature != raw.Temperature {
 
-			readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{
-				Mac: input.Mac,
-			}
-
-			readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(gCtx, readDeviceConfigInput)
-			if err != nil {
-				s.logger.ErrorContext(gCtx, "failed to read device config",
-					slog.Any("err", err),
-					slog.String("device", input.Mac),
-					slog.Any("input", readDeviceConfigInput))
-				return err
-			}
-
 			publishTemperatureInput := &modelsMqtt.PublishTemperatureInput{
 				Mac:         input.Mac,
-				Temperature: converter.Temperature(models.Celsius, readDeviceConfigReturn.Config.TemperatureUnit, deviceStatusHass.Temperature),
+				Temperature: converter.SetpointFromCelsius(readDeviceConfigReturn.Config.TemperatureUnit, deviceStatusHass.Temperature),
 			}
 
 			err = s.mqtt.PublishTemperature(gCtx, publishTemperatureInput)
@@ -585,6 +776,95 @@ func (s *service) GetDeviceStates(ctx context.Context, input *models.GetDeviceSt
 			slog.Any("input", upsertDeviceStatusRawInput))
 		return err
 	}
+
+	return s.updateHvacAction(ctx, input.Mac)
+}
+
+// updateHvacAction derives the hvac action from the cached states and publishes it when it has been changed
+func (s *service) updateHvacAction(ctx context.Context, mac string) error {
+	readDeviceStatusRawInput := &modelsRepo.ReadDeviceStatusRawInput{Mac: mac}
+	readDeviceStatusRawReturn, err := s.cache.ReadDeviceStatusRaw(ctx, readDeviceStatusRawInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceStatusRawNotFound) {
+			return nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the device status",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceStatusRawInput))
+		return err
+	}
+
+	var info *models.DeviceInfoRaw
+	readDeviceInfoRawInput := &modelsRepo.ReadDeviceInfoRawInput{Mac: mac}
+	readDeviceInfoRawReturn, err := s.cache.ReadDeviceInfoRaw(ctx, readDeviceInfoRawInput)
+	if err != nil {
+		if !errors.Is(err, modelsRepo.ErrorDeviceInfoRawNotFound) {
+			s.logger.ErrorContext(ctx, "failed to read the device info",
+				slog.Any("err", err),
+				slog.Any("input", readDeviceInfoRawInput))
+			return err
+		}
+	} else {
+		deviceInfo := models.DeviceInfoRaw(readDeviceInfoRawReturn.Info)
+		info = &deviceInfo
+	}
+
+	var ambientTemp *float32
+	readAmbientTempInput := &modelsRepo.ReadAmbientTempInput{Mac: mac}
+	readAmbientTempReturn, err := s.cache.ReadAmbientTemp(ctx, readAmbientTempInput)
+	if err != nil {
+		if !errors.Is(err, modelsRepo.ErrorDeviceStatusAmbientTempNotFound) {
+			s.logger.ErrorContext(ctx, "failed to read the ambient temperature",
+				slog.Any("err", err),
+				slog.Any("input", readAmbientTempInput))
+			return err
+		}
+	} else {
+		ambientTemp = &readAmbientTempReturn.Temperature
+	}
+
+	hvacAction := models.DeviceStatusRaw(readDeviceStatusRawReturn.Status).ConvertToHvacAction(info, ambientTemp)
+
+	// The unit switched off by the virtual thermostat is idle
+	if hvacAction == models.HvacActionOff {
+		thermostat, err := s.readThermostat(ctx, mac)
+		if err != nil {
+			return err
+		}
+		if thermostat.IsIdle {
+			hvacAction = models.HvacActionIdle
+		}
+	}
+
+	readHvacActionReturn, err := s.cache.ReadHvacAction(ctx, &modelsRepo.ReadHvacActionInput{Mac: mac})
+	if err == nil && readHvacActionReturn.HvacAction == hvacAction {
+		return nil
+	}
+
+	publishHvacActionInput := &modelsMqtt.PublishHvacActionInput{
+		Mac:        mac,
+		HvacAction: hvacAction,
+	}
+	err = s.mqtt.PublishHvacAction(ctx, publishHvacActionInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the hvac action",
+			slog.Any("err", err),
+			slog.Any("input", publishHvacActionInput))
+		return err
+	}
+
+	upsertHvacActionInput := &modelsRepo.UpsertHvacActionInput{
+		Mac:        mac,
+		HvacAction: hvacAction,
+	}
+	err = s.cache.UpsertHvacAction(ctx, upsertHvacActionInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the hvac action",
+			slog.Any("err", err),
+			slog.Any("input", upsertHvacActionInput))
+		return err
+	}
+
 	return nil
 }
 
@@ -605,16 +885,12 @@ func (s *service) sendCommand(ctx context.Context, input *models.SendCommandInpu
 
 	auth.LastMessageId = (auth.LastMessageId + 1) & 0xffff
 
-	macByteSlice := make([]byte, 0, len(input.Mac)/2)
-	for i := 0; i < len(input.Mac); i = i + 2 {
-		val, err := strconv.ParseUint(input.Mac[i:i+2], 16, 8)
-		if err != nil {
-			s.logger.ErrorContext(ctx, "mac address is not correct",
-				slog.Any("err", err),
-				slog.Any("input", input.Mac))
-			return nil, err
-		}
-		macByteSlice = append(macByteSlice, byte(val))
+	macByteSlice, err := parseMac(input.Mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "mac address is not correct",
+			slog.Any("err", err),
+			slog.Any("input", input.Mac))
+		return nil, err
 	}
 
 	var packet [0x38]byte
@@ -644,11 +920,7 @@ func (s *service) sendCommand(ctx context.Context, input *models.SendCommandInpu
 	packet[0x32] = auth.Id[2]
 	packet[0x33] = auth.Id[3]
 
-	checksum := 0xbeaf
-	for i := range input.Payload {
-		checksum += int(input.Payload[i])
-		checksum = checksum & 0xffff
-	}
+	checksum := coder.Checksum(input.Payload)
 
 	input.Payload, err = coder.Encrypt(auth.Key, auth.Iv, input.Payload)
 	if err != nil {
@@ -667,11 +939,7 @@ func (s *service) sendCommand(ctx context.Context, input *models.SendCommandInpu
 	packetSlice = append(packetSlice, input.Payload...)
 
 	// Create and insert Checksum
-	checksum = 0xbeaf
-	for i := range packetSlice {
-		checksum += int(packetSlice[i])
-		checksum = checksum & 0xffff
-	}
+	checksum = coder.Checksum(packetSlice)
 	packetSlice[0x20] = byte(checksum & 0xff)
 	packetSlice[0x21] = byte(checksum >> 8)
 
@@ -723,13 +991,95 @@ func (s *service) sendCommand(ctx context.Context, input *models.SendCommandInpu
 	return &models.SendCommandReturn{Payload: sendCommandReturn.Payload}, nil
 }
 
+// decryptResponse validates the packet received from the device and returns its decrypted payload,
+// which is at least minLength bytes long. The packet errors are wrapped into ErrorInvalidResultPacket
+// or ErrorInvalidResultPacketLength, so they are counted by the communication fault.
+func (s *service) decryptResponse(ctx context.Context, mac string, packet []byte, minLength int) ([]byte, error) {
+	readDeviceAuthInput := &modelsRepo.ReadDeviceAuthInput{
+		Mac: mac,
+	}
+	readDeviceAuthReturn, err := s.cache.ReadDeviceAuth(ctx, readDeviceAuthInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "device not found",
+			slog.Any("input", readDeviceAuthInput),
+			slog.String("device", mac),
+			slog.Any("err", err))
+		return nil, err
+	}
+	auth := readDeviceAuthReturn.Auth
+
+	macBytes, err := parseMac(mac)
+	if err != nil {
+		return nil, err
+	}
+
+	err = coder.ValidatePacket(packet, uint16(auth.DevType), macBytes)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "the response packet is invalid",
+			slog.String("device", mac),
+			slog.Any("input", packet),
+			slog.Any("err", err))
+
+		switch {
+		case errors.Is(err, coder.ErrorPacketTooShort),
+			errors.Is(err, coder.ErrorPacketPayloadIsEmpty),
+			errors.Is(err, coder.ErrorPacketPayloadNotAligned):
+			return nil, fmt.Errorf("%w: %w", models.ErrorInvalidResultPacketLength, err)
+		default:
+			return nil, fmt.Errorf("%w: %w", models.ErrorInvalidResultPacket, err)
+		}
+	}
+
+	payload, err := coder.Decrypt(auth.Key, auth.Iv, packet[coder.HeaderLength:])
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to decrypt the response",
+			slog.Any("input", packet),
+			slog.String("device", mac),
+			slog.Any("err", err))
+		return nil, fmt.Errorf("%w: %w", models.ErrorInvalidResultPacketLength, err)
+	}
+
+	if len(payload) < minLength {
+		s.logger.ErrorContext(ctx, "the response payload is too short",
+			slog.String("device", mac),
+			slog.Int("length", len(payload)),
+			slog.Int("minLength", minLength))
+		return nil, models.ErrorInvalidResultPacketLength
+	}
+
+	return payload, nil
+}
+
+// parseMac converts the mac address in the hex format to bytes
+func parseMac(mac string) ([]byte, error) {
+	if len(mac) != 12 {
+		return nil, errors.New("mac address is wrong")
+	}
+
+	macBytes := make([]byte, 0, len(mac)/2)
+	for i := 0; i < len(mac); i = i + 2 {
+		val, err := strconv.ParseUint(mac[i:i+2], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		macBytes = append(macBytes, byte(val))
+	}
+
+	return macBytes, nil
+}
+
 func (s *service) PublishDiscoveryTopic(ctx context.Context, input *models.PublishDiscoveryTopicInput) error {
 	prefix := s.topicPrefix + "/" + input.Device.Mac
 
+	firmware, err := s.readFirmwareVersion(ctx, input.Device.Mac)
+	if err != nil {
+		return err
+	}
+
 	device := modelsMqtt.DiscoveryTopicDevice{
 		Model: "AirCon",
 		Mf:    "broadlink",
-		Sw:    "v1.5.3",
+		Sw:    firmware,
 		Ids:   input.Device.Mac,
 		Name:  input.Device.Name,
 	}
@@ -752,16 +1102,17 @@ func (s *service) PublishDiscoveryTopic(ctx context.Context, input *models.Publi
 			FanModeStateTopic:       prefix + "/fan_mode/value",
 			ModeCommandTopic:        prefix + "/mode/set",
 			ModeStateTopic:          prefix + "/mode/value",
+			ActionTopic:             prefix + "/action/value",
 			Modes:                   []string{"auto", "off", "cool", "heat", "dry", "fan_only"},
 			SwingModeCommandTopic:   prefix + "/swing_mode/set",
 			SwingModeStateTopic:     prefix + "/swing_mode/value",
 			SwingModes:              swingModes,
-			MinTemp:                 16,
-			MaxTemp:                 32,
-			TempStep:                0.5,
+			MinTemp:                 converter.SetpointFromCelsius(input.Device.TemperatureUnit, input.Device.MinTemp),
+			MaxTemp:                 converter.SetpointFromCelsius(input.Device.TemperatureUnit, input.Device.MaxTemp),
+			TempStep:                converter.SetpointStep(input.Device.TemperatureUnit),
 			TemperatureStateTopic:   prefix + "/temp/value",
 			TemperatureCommandTopic: prefix + "/temp/set",
-			Precision:               0.1,
+			Precision:               converter.Precision(input.Device.TemperatureUnit),
 			Device:                  device,
 			UniqueId:                input.Device.Mac + "_ac",
 			Availability:            availability,
@@ -771,24 +1122,221 @@ func (s *service) PublishDiscoveryTopic(ctx context.Context, input *models.Publi
 			TemperatureUnit:         input.Device.TemperatureUnit,
 		},
 	}
-	err := s.mqtt.PublishClimateDiscoveryTopic(ctx, publishClimateDiscoveryTopicInput)
-	if err != nil {
-		return err
+
+	// The heat_cool mode is emulated by the bridge with the low and high targets
+	if input.Device.HeatCool {
+		climate := &publishClimateDiscoveryTopicInput.Topic
+		climate.Modes = append(climate.Modes, models.ModeHeatCool)
+		climate.TemperatureLowStateTopic = prefix + "/temp_low/value"
+		climate.TemperatureLowCommandTopic = prefix + "/temp_low/set"
+		climate.TemperatureHighStateTopic = prefix + "/temp_high/value"
+		climate.TemperatureHighCommandTopic = prefix + "/temp_high/set"
+	}
+
+	err = s.mqtt.PublishClimateDiscoveryTopic(ctx, publishClimateDiscoveryTopicInput)
+	if err != nil {
+		return err
+	}
+
+	publishSwitchScreenDiscoveryTopicInput := modelsMqtt.PublishSwitchDiscoveryTopicInput{
+		Topic: modelsMqtt.SwitchDiscoveryTopic{
+			Device:       device,
+			Name:         "Screen",
+			UniqueId:     input.Device.Mac + "_screen",
+			StateTopic:   prefix + "/display/switch/value",
+			CommandTopic: prefix + "/display/switch/set",
+			Availability: availability,
+			Icon:         "mdi:tablet-dashboard",
+		},
+	}
+
+	err = s.mqtt.PublishSwitchDiscoveryTopic(ctx, publishSwitchScreenDiscoveryTopicInput)
+	if err != nil {
+		return err
+	}
+
+	actor, err := s.readActor(input.Device.Mac)
+	if err != nil {
+		return err
+	}
+	for _, schedule := range actor.schedules {
+		publishSwitchScheduleDiscoveryTopicInput := modelsMqtt.PublishSwitchDiscoveryTopicInput{
+			Topic: modelsMqtt.SwitchDiscoveryTopic{
+				Device:       device,
+				Name:         "Schedule " + schedule.Name,
+				UniqueId:     input.Device.Mac + "_schedule_" + schedule.Name,
+				StateTopic:   prefix + "/schedule/" + schedule.Name + "/value",
+				CommandTopic: prefix + "/schedule/" + schedule.Name + "/set",
+				Availability: availability,
+				Icon:         "mdi:calendar-clock",
+			},
+		}
+		err = s.mqtt.PublishSwitchDiscoveryTopic(ctx, publishSwitchScheduleDiscoveryTopicInput)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(actor.contacts) != 0 {
+		publishBinarySensorDiscoveryTopicInput := modelsMqtt.PublishBinarySensorDiscoveryTopicInput{
+			Topic: modelsMqtt.BinarySensorDiscoveryTopic{
+				Device:       device,
+				Name:         "Interlock",
+				UniqueId:     input.Device.Mac + "_interlock",
+				StateTopic:   prefix + "/interlock/value",
+				Availability: availability,
+				Icon:         "mdi:window-open-variant",
+				PayloadOn:    "ON",
+				PayloadOff:   "OFF",
+			},
+		}
+		err = s.mqtt.PublishBinarySensorDiscoveryTopic(ctx, publishBinarySensorDiscoveryTopicInput)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(actor.sleepProfiles) != 0 {
+		sensors := []modelsMqtt.SensorDiscoveryTopic{
+			{Name: "Sleep", UniqueId: input.Device.Mac + "_sleep", StateTopic: prefix + "/sleep/value", Icon: "mdi:sleep"},
+			{Name: "Sleep progress", UniqueId: input.Device.Mac + "_sleep_progress", StateTopic: prefix + "/sleep/progress/value", Icon: "mdi:progress-clock", UnitOfMeasurement: "%"},
+			{Name: "Sleep next step", UniqueId: input.Device.Mac + "_sleep_next", StateTopic: prefix + "/sleep/next/value", Icon: "mdi:timer-sand", DeviceClass: "timestamp"},
+		}
+		for _, sensor := range sensors {
+			sensor.Device = device
+			sensor.Availability = availability
+			err = s.mqtt.PublishSensorDiscoveryTopic(ctx, modelsMqtt.PublishSensorDiscoveryTopicInput{Topic: sensor})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kind := range models.TimerKinds {
+		publishNumberDiscoveryTopicInput := modelsMqtt.PublishNumberDiscoveryTopicInput{
+			Topic: modelsMqtt.NumberDiscoveryTopic{
+				Device:            device,
+				Name:              kind.Title,
+				UniqueId:          input.Device.Mac + "_timer_" + kind.Name,
+				StateTopic:        prefix + "/timer/" + kind.Name + "/value",
+				CommandTopic:      prefix + "/timer/" + kind.Name + "/set",
+				Availability:      availability,
+				Icon:              "mdi:timer-outline",
+				Min:               0,
+				Max:               models.TimerMaxMinutes,
+				Step:              1,
+				Mode:              "box",
+				UnitOfMeasurement: "min",
+			},
+		}
+		err = s.mqtt.PublishNumberDiscoveryTopic(ctx, publishNumberDiscoveryTopicInput)
+		if err != nil {
+			return err
+		}
+
+		publishSensorDiscoveryTopicInput := modelsMqtt.PublishSensorDiscoveryTopicInput{
+			Topic: modelsMqtt.SensorDiscoveryTopic{
+				Device:       device,
+				Name:         kind.Title + " end",
+				UniqueId:     input.Device.Mac + "_timer_" + kind.Name + "_end",
+				StateTopic:   prefix + "/timer/" + kind.Name + "/end/value",
+				Availability: availability,
+				Icon:         "mdi:timer-sand",
+				DeviceClass:  "timestamp",
+			},
+		}
+		err = s.mqtt.PublishSensorDiscoveryTopic(ctx, publishSensorDiscoveryTopicInput)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, diagnostic := range models.Diagnostics {
+		if diagnostic.IsBinary {
+			publishBinarySensorDiscoveryTopicInput := modelsMqtt.PublishBinarySensorDiscoveryTopicInput{
+				Topic: modelsMqtt.BinarySensorDiscoveryTopic{
+					Device:         device,
+					Name:           diagnostic.Title,
+					UniqueId:       input.Device.Mac + "_" + diagnostic.Name,
+					StateTopic:     prefix + "/" + diagnostic.Name + "/value",
+					Availability:   availability,
+					Icon:           diagnostic.Icon,
+					DeviceClass:    diagnostic.DeviceClass,
+					PayloadOn:      "ON",
+					PayloadOff:     "OFF",
+					EntityCategory: modelsMqtt.EntityCategoryDiagnostic,
+				},
+			}
+			err = s.mqtt.PublishBinarySensorDiscoveryTopic(ctx, publishBinarySensorDiscoveryTopicInput)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		sensor := modelsMqtt.SensorDiscoveryTopic{
+			Device:            device,
+			Name:              diagnostic.Title,
+			UniqueId:          input.Device.Mac + "_" + diagnostic.Name,
+			StateTopic:        prefix + "/" + diagnostic.Name + "/value",
+			Availability:      availability,
+			Icon:              diagnostic.Icon,
+			DeviceClass:       diagnostic.DeviceClass,
+			UnitOfMeasurement: diagnostic.Unit,
+			EntityCategory:    modelsMqtt.EntityCategoryDiagnostic,
+		}
+		if diagnostic.DeviceClass != "" {
+			sensor.StateClass = "measurement"
+		}
+		if diagnostic.DeviceClass == "temperature" {
+			sensor.UnitOfMeasurement = "°" + input.Device.TemperatureUnit
+		}
+
+		err = s.mqtt.PublishSensorDiscoveryTopic(ctx, modelsMqtt.PublishSensorDiscoveryTopicInput{Topic: sensor})
+		if err != nil {
+			return err
+		}
 	}
 
-	publishSwitchScreenDiscoveryTopicInput := modelsMqtt.PublishSwitchDiscoveryTopicInput{
-		Topic: modelsMqtt.SwitchDiscoveryTopic{
-			Device:       device,
-			Name:         "Screen",
-			UniqueId:     input.Device.Mac + "_screen",
-			StateTopic:   prefix + "/display/switch/value",
-			CommandTopic: prefix + "/display/switch/set",
-			Availability: availability,
-			Icon:         "mdi:tablet-dashboard",
-		},
+	for _, kind := range models.FaultKinds {
+		publishBinarySensorDiscoveryTopicInput := modelsMqtt.PublishBinarySensorDiscoveryTopicInput{
+			Topic: modelsMqtt.BinarySensorDiscoveryTopic{
+				Device:         device,
+				Name:           kind.Title,
+				UniqueId:       input.Device.Mac + "_fault_" + kind.Name,
+				StateTopic:     prefix + "/fault/" + kind.Name + "/value",
+				Availability:   availability,
+				DeviceClass:    "problem",
+				PayloadOn:      "ON",
+				PayloadOff:     "OFF",
+				EntityCategory: modelsMqtt.EntityCategoryDiagnostic,
+			},
+		}
+		err = s.mqtt.PublishBinarySensorDiscoveryTopic(ctx, publishBinarySensorDiscoveryTopicInput)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range []string{models.FaultEventRaised, models.FaultEventCleared} {
+			publishDeviceTriggerDiscoveryTopicInput := modelsMqtt.PublishDeviceTriggerDiscoveryTopicInput{
+				Topic: modelsMqtt.DeviceTriggerDiscoveryTopic{
+					Device:         device,
+					AutomationType: "trigger",
+					Topic:          prefix + "/fault/" + kind.Name + "/event",
+					Payload:        event,
+					Type:           event,
+					Subtype:        kind.Name,
+					UniqueId:       input.Device.Mac + "_" + kind.Name + "_" + event,
+				},
+			}
+			err = s.mqtt.PublishDeviceTriggerDiscoveryTopic(ctx, publishDeviceTriggerDiscoveryTopicInput)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	return s.mqtt.PublishSwitchDiscoveryTopic(ctx, publishSwitchScreenDiscoveryTopicInput)
+	return nil
 }
 
 func (s *service) UpdateFanMode(ctx context.Context, input *models.UpdateFanModeInput) error {
@@ -801,20 +1349,8 @@ func (s *service) UpdateFanMode(ctx context.Context, input *models.UpdateFanMode
 		return err
 	}
 
-	upsertMqttFanModeMessageInput := &modelsRepo.UpsertMqttFanModeMessageInput{
-		Mac: input.Mac,
-		FanMode: modelsRepo.MqttFanModeMessage{
-			UpdatedAt: time.Now(),
-			FanMode:   input.FanMode,
-		},
-	}
-
-	err = s.cache.UpsertMqttFanModeMessage(ctx, upsertMqttFanModeMessageInput)
+	err = s.postCommand(ctx, models.UpdateDeviceStatesInput{Mac: input.Mac, FanMode: &input.FanMode})
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to save mqtt message to cache storage",
-			slog.Any("err", err),
-			slog.String("device", input.Mac),
-			slog.Any("input", upsertMqttFanModeMessageInput))
 		return err
 	}
 
@@ -835,6 +1371,10 @@ func (s *service) UpdateFanMode(ctx context.Context, input *models.UpdateFanMode
 }
 
 func (s *service) UpdateMode(ctx context.Context, input *models.UpdateModeInput) error {
+	if input.Mode == models.ModeHeatCool {
+		return s.updateHeatCoolMode(ctx, input.Mac)
+	}
+
 	err := input.Validate()
 	if err != nil {
 		s.logger.ErrorContext(ctx, "input data is not valid",
@@ -844,20 +1384,13 @@ func (s *service) UpdateMode(ctx context.Context, input *models.UpdateModeInput)
 		return err
 	}
 
-	upsertMqttModeMessageInput := &modelsRepo.UpsertMqttModeMessageInput{
-		Mac: input.Mac,
-		Mode: modelsRepo.MqttModeMessage{
-			UpdatedAt: time.Now(),
-			Mode:      input.Mode,
-		},
+	err = s.stopHeatCool(ctx, input.Mac)
+	if err != nil {
+		return err
 	}
 
-	err = s.cache.UpsertMqttModeMessage(ctx, upsertMqttModeMessageInput)
+	err = s.postCommand(ctx, models.UpdateDeviceStatesInput{Mac: input.Mac, Mode: &input.Mode})
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to save mqtt message to cache storage",
-			slog.Any("err", err),
-			slog.String("device", input.Mac),
-			slog.Any("input", upsertMqttModeMessageInput))
 		return err
 	}
 
@@ -887,20 +1420,8 @@ func (s *service) UpdateSwingMode(ctx context.Context, input *models.UpdateSwing
 		return err
 	}
 
-	upsertMqttSwingModeMessageInput := &modelsRepo.UpsertMqttSwingModeMessageInput{
-		Mac: input.Mac,
-		SwingMode: modelsRepo.MqttSwingModeMessage{
-			UpdatedAt: time.Now(),
-			SwingMode: input.SwingMode,
-		},
-	}
-
-	err = s.cache.UpsertMqttSwingModeMessage(ctx, upsertMqttSwingModeMessageInput)
+	err = s.postCommand(ctx, models.UpdateDeviceStatesInput{Mac: input.Mac, SwingMode: &input.SwingMode})
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to save mqtt message to cache storage",
-			slog.Any("err", err),
-			slog.String("device", input.Mac),
-			slog.Any("input", upsertMqttSwingModeMessageInput))
 		return err
 	}
 
@@ -933,8 +1454,8 @@ func (s *service) UpdateTemperature(ctx context.Context, input *models.UpdateTem
 		return err
 	}
 
-	input.Temperature = converter.Temperature(readDeviceConfigReturn.Config.TemperatureUnit, models.Celsius, input.Temperature)
-	err = input.Validate()
+	input.Temperature = converter.SetpointToCelsius(readDeviceConfigReturn.Config.TemperatureUnit, input.Temperature)
+	err = input.Validate(readDeviceConfigReturn.Config.MinTemp, readDeviceConfigReturn.Config.MaxTemp)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "input data is not valid",
 			slog.Any("err", err),
@@ -943,24 +1464,7 @@ func (s *service) UpdateTemperature(ctx context.Context, input *models.UpdateTem
 		return err
 	}
 
-	upsertMqttTemperatureMessageInput := &modelsRepo.UpsertMqttTemperatureMessageInput{
-		Mac: input.Mac,
-		Temperature: modelsRepo.MqttTemperatureMessage{
-			UpdatedAt:   time.Now(),
-			Temperature: input.Temperature,
-		},
-	}
-
-	err = s.cache.UpsertMqttTemperatureMessage(ctx, upsertMqttTemperatureMessageInput)
-	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to save mqtt message to cache storage",
-			slog.Any("err", err),
-			slog.String("device", input.Mac),
-			slog.Any("input", upsertMqttTemperatureMessageInput))
-		return err
-	}
-
-	return nil
+	return s.postCommand(ctx, models.UpdateDeviceStatesInput{Mac: input.Mac, Temperature: &input.Temperature})
 }
 
 func (s *service) UpdateDisplaySwitch(ctx context.Context, input *models.UpdateDisplaySwitchInput) error {
@@ -973,23 +1477,58 @@ func (s *service) UpdateDisplaySwitch(ctx context.Context, input *models.UpdateD
 		return err
 	}
 
-	upsertDisplaySwitchMessageInput := &modelsRepo.UpsertMqttDisplaySwitchMessageInput{
-		Mac: input.Mac,
-		DisplaySwitch: modelsRepo.MqttDisplaySwitchMessage{
-			UpdatedAt:   time.Now(),
-			IsDisplayOn: input.Status == "ON",
-		},
+	isDisplayOn := input.Status == "ON"
+	return s.postCommand(ctx, models.UpdateDeviceStatesInput{Mac: input.Mac, IsDisplayOn: &isDisplayOn})
+}
+
+// updateStates posts the command with several states to the device actor and publishes
+// the modes in the same way as the commands received via MQTT
+func (s *service) updateStates(ctx context.Context, command models.UpdateDeviceStatesInput) error {
+	if command.Mode != nil {
+		err := s.stopHeatCool(ctx, command.Mac)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = s.cache.UpsertMqttDisplaySwitchMessage(ctx, upsertDisplaySwitchMessageInput)
+	err := s.postCommand(ctx, command)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to save mqtt message to cache storage",
-			slog.Any("err", err),
-			slog.String("device", input.Mac),
-			slog.Any("input", upsertDisplaySwitchMessageInput))
 		return err
 	}
 
+	if command.Mode != nil {
+		publishModeInput := &modelsMqtt.PublishModeInput{Mac: command.Mac, Mode: *command.Mode}
+		err = s.mqtt.PublishMode(ctx, publishModeInput)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to publish mode to mqtt",
+				slog.Any("err", err),
+				slog.Any("input", publishModeInput))
+			return err
+		}
+	}
+
+	if command.FanMode != nil {
+		publishFanModeInput := &modelsMqtt.PublishFanModeInput{Mac: command.Mac, FanMode: *command.FanMode}
+		err = s.mqtt.PublishFanMode(ctx, publishFanModeInput)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to publish fan mode to mqtt",
+				slog.Any("err", err),
+				slog.Any("input", publishFanModeInput))
+			return err
+		}
+	}
+
+	if command.SwingMode != nil {
+		publishSwingModeInput := &modelsMqtt.PublishSwingModeInput{Mac: command.Mac, SwingMode: *command.SwingMode}
+		err = s.mqtt.PublishSwingMode(ctx, publishSwingModeInput)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to publish swing mode to mqtt",
+				slog.Any("err", err),
+				slog.Any("input", publishSwingModeInput))
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -1025,10 +1564,23 @@ func (s *service) UpdateDeviceStates(ctx context.Context, input *models.UpdateDe
 		verticalFixation = readDeviceStatusRawReturn.Status.FixationVertical
 	}
 
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{
+		Mac: input.Mac,
+	}
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", readDeviceConfigInput))
+		return err
+	}
+	minTemp, maxTemp := readDeviceConfigReturn.Config.MinTemp, readDeviceConfigReturn.Config.MaxTemp
+
 	// TEMPERATURE
 	var temperature, temperature05 int
 	if input.Temperature != nil {
-		if *input.Temperature > 32 || *input.Temperature < 16 {
+		if *input.Temperature > maxTemp || *input.Temperature < minTemp {
 			s.logger.ErrorContext(ctx, "Invalid parameter temperature",
 				slog.String("device", input.Mac),
 				slog.Any("input", *input.Temperature))
@@ -1042,10 +1594,16 @@ func (s *service) UpdateDeviceStates(ctx context.Context, input *models.UpdateDe
 			temperature05 = 1
 		}
 	} else {
-		if readDeviceStatusRawReturn.Status.Temperature < 16 {
-			temperature = 16 - 8
-		} else if readDeviceStatusRawReturn.Status.Temperature > 32 {
-			temperature = 32 - 8
+		if readDeviceStatusRawReturn.Status.Temperature < minTemp {
+			temperature = int(minTemp) - 8
+			if minTemp-float32(int(minTemp)) != 0 {
+				temperature05 = 1
+			}
+		} else if readDeviceStatusRawReturn.Status.Temperature > maxTemp {
+			temperature = int(maxTemp) - 8
+			if maxTemp-float32(int(maxTemp)) != 0 {
+				temperature05 = 1
+			}
 		} else {
 			temperature = int(readDeviceStatusRawReturn.Status.Temperature) - 8
 			if readDeviceStatusRawReturn.Status.Temperature-float32(int(readDeviceStatusRawReturn.Status.Temperature)) != 0 {
@@ -1115,6 +1673,24 @@ func (s *service) UpdateDeviceStates(ctx context.Context, input *models.UpdateDe
 		mode = readDeviceStatusRawReturn.Status.Mode
 	}
 
+	// TIMERS
+	// The native timers of the unit are sent only when they are enabled, otherwise the bridge runs them
+	var offTimer, onTimer byte
+	if readDeviceConfigReturn.Config.NativeTimer {
+		offTimerState, err := s.readTimerState(ctx, input.Mac, models.TimerOff)
+		if err != nil {
+			return err
+		}
+		onTimerState, err := s.readTimerState(ctx, input.Mac, models.TimerOn)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		offTimer = models.NativeTimer(offTimerState, now)
+		onTimer = models.NativeTimer(onTimerState, now)
+	}
+
 	// Insert values in payload
 	var payload [23]byte
 	payload[0] = 0xbb
@@ -1133,8 +1709,8 @@ func (s *service) UpdateDeviceStates(ctx context.Context, input *models.UpdateDe
 	payload[13] = 0b00000000 | fanMode<<5
 	payload[14] = 0b00000000 | turbo<<6 | mute<<7
 	payload[15] = 0b00000000 | mode<<5 | readDeviceStatusRawReturn.Status.Sleep<<2
-	payload[16] = 0b00000000
-	payload[17] = 0x00
+	payload[16] = offTimer
+	payload[17] = onTimer
 	payload[18] = 0b00000000 | power<<5 | readDeviceStatusRawReturn.Status.Health<<1 | readDeviceStatusRawReturn.Status.Clean<<2
 	payload[19] = 0x00
 	payload[20] = 0b00000000 | displaySwitch<<4 | readDeviceStatusRawReturn.Status.Mildew<<3
@@ -1162,7 +1738,7 @@ func (s *service) UpdateDeviceStates(ctx context.Context, input *models.UpdateDe
 		Payload: payloadChecksum[:],
 		Mac:     input.Mac,
 	}
-	_, err = s.sendCommand(ctx, sendCommandInput)
+	response, err := s.sendCommand(ctx, sendCommandInput)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to send a set command",
 			slog.Any("err", err),
@@ -1171,6 +1747,12 @@ func (s *service) UpdateDeviceStates(ctx context.Context, input *models.UpdateDe
 		return err
 	}
 
+	// The device reports the rejected command in the error code of the header
+	_, err = s.decryptResponse(ctx, input.Mac, response.Payload, 0)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1201,181 +1783,47 @@ func (s *service) UpdateDeviceAvailability(ctx context.Context, input *models.Up
 		return err
 	}
 
-	return nil
+	// The state of a group is unknown while a member is not available
+	return s.updateGroupStates(ctx, input.Mac)
 }
 
-func (s *service) StartDeviceMonitoring(ctx context.Context, input *models.StartDeviceMonitoringInput) error {
-	var (
-		modeUpdatedTime, swingModeUpdatedTime, fanModeUpdatedTime, temperatureUpdatedTime time.Time
-		isDisplayOnUpdatedTime                                                            time.Time
-		lastGetDeviceState, lastGetAmbientTemp                                            time.Time
-
-		isDeviceAvailable bool
-	)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-			if time.Now().Sub(lastGetAmbientTemp).Seconds() > 180 {
-				err := s.GetDeviceAmbientTemperature(ctx, &models.GetDeviceAmbientTemperatureInput{Mac: input.Mac})
-				if err != nil {
-					s.logger.ErrorContext(ctx, "failed to get ambient temperature",
-						slog.Any("err", err),
-						slog.String("device", input.Mac))
-
-					err = nil
-					continue
-				}
-				lastGetAmbientTemp = time.Now()
-			} else {
-				var (
-					forcedUpdateDeviceState  = false
-					mode, swingMode, fanMode *string
-					temperature              *float32
-					isDisplayOn              *bool
-				)
-
-				readMqttMessageInput := &modelsRepo.ReadMqttMessageInput{
-					Mac: input.Mac,
-				}
-				message, err := s.cache.ReadMqttMessage(ctx, readMqttMessageInput)
-				if err != nil {
-					return err
-				}
-
-				if message.Mode != nil {
-					if message.Mode.UpdatedAt != modeUpdatedTime {
-						forcedUpdateDeviceState = true
-						mode = &message.Mode.Mode
-					}
-				}
-
-				if message.FanMode != nil {
-					if message.FanMode.UpdatedAt != fanModeUpdatedTime {
-						forcedUpdateDeviceState = true
-						fanMode = &message.FanMode.FanMode
-					}
-				}
-
-				if message.SwingMode != nil {
-					if message.SwingMode.UpdatedAt != swingModeUpdatedTime {
-						forcedUpdateDeviceState = true
-						swingMode = &message.SwingMode.SwingMode
-					}
-				}
-
-				if message.Temperature != nil {
-					if message.Temperature.UpdatedAt != temperatureUpdatedTime {
-						forcedUpdateDeviceState = true
-						temperature = &message.Temperature.Temperature
-					}
-				}
-
-				if message.IsDisplayOn != nil {
-					if message.IsDisplayOn.UpdatedAt != isDisplayOnUpdatedTime {
-						forcedUpdateDeviceState = true
-						isDisplayOn = &message.IsDisplayOn.IsDisplayOn
-					}
-				}
-
-				if forcedUpdateDeviceState || int(time.Now().Sub(lastGetDeviceState).Seconds()) > s.updateInterval {
-					for {
-						err = s.GetDeviceStates(ctx, &models.GetDeviceStatesInput{Mac: input.Mac})
-						if err != nil {
-							s.logger.ErrorContext(ctx, "failed to get AC States",
-								slog.Any("err", err),
-								slog.String("device", input.Mac))
-
-							// If we cannot receive data from the air conditioner within three intervals,
-							// then we send the status that the air conditioner is unavailable
-							if time.Now().Sub(lastGetDeviceState).Seconds() > float64(s.updateInterval)*3 && isDeviceAvailable {
-								updateDeviceAvailabilityInput := &models.UpdateDeviceAvailabilityInput{
-									Mac:          input.Mac,
-									Availability: models.StatusOffline,
-								}
-								err = s.UpdateDeviceAvailability(ctx, updateDeviceAvailabilityInput)
-								if err != nil {
-									s.logger.ErrorContext(ctx, "failed to update device availability",
-										slog.Any("err", err),
-										slog.String("device", input.Mac),
-										slog.Any("input", updateDeviceAvailabilityInput))
-									err = nil
-								}
-								isDeviceAvailable = false
-							}
-							err = nil
-							continue
-						} else {
-							lastGetDeviceState = time.Now()
-							if !isDeviceAvailable {
-								updateDeviceAvailabilityInput := &models.UpdateDeviceAvailabilityInput{
-									Mac:          input.Mac,
-									Availability: models.StatusOnline,
-								}
-								err = s.UpdateDeviceAvailability(ctx, updateDeviceAvailabilityInput)
-								if err != nil {
-									s.logger.ErrorContext(ctx, "failed to update device availability",
-										slog.Any("err", err),
-										slog.String("device", input.Mac),
-										slog.Any("input", updateDeviceAvailabilityInput))
-
-									err = nil
-								}
-								isDeviceAvailable = true
-							}
-							break
-						}
-					}
-				}
-
-				if forcedUpdateDeviceState && isDeviceAvailable {
-					// A short pause before sending a new message to the air conditioner so that it does not hang
-					time.Sleep(time.Millisecond * 500)
-
-					updateDeviceStatesInput := &models.UpdateDeviceStatesInput{
-						Mac:         input.Mac,
-						FanMode:     fanMode,
-						SwingMode:   swingMode,
-						Mode:        mode,
-						Temperature: temperature,
-						IsDisplayOn: isDisplayOn,
-					}
-					err := s.UpdateDeviceStates(ctx, updateDeviceStatesInput)
-					if err != nil {
-						s.logger.ErrorContext(ctx, "failed to update device states",
-							slog.Any("err", err),
-							slog.String("device", input.Mac),
-							slog.Any("input", updateDeviceStatesInput))
-						err = nil
-						continue
-					}
-
-					// Reset the time of the last update to get fresh data from the air conditioner
-					lastGetDeviceState = time.UnixMicro(0)
+// readDeviceAvailability returns the cached availability or an empty string when it is not known yet
+func (s *service) readDeviceAvailability(ctx context.Context, mac string) (string, error) {
+	readDeviceAvailabilityInput := &modelsRepo.ReadDeviceAvailabilityInput{Mac: mac}
+	readDeviceAvailabilityReturn, err := s.cache.ReadDeviceAvailability(ctx, readDeviceAvailabilityInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceStatusAvailabilityNotFound) {
+			return "", nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the device availability",
+			slog.Any("err", err),
+			slog.Any("input", readDeviceAvailabilityInput))
+		return "", err
+	}
 
-					if message.Mode != nil {
-						modeUpdatedTime = message.Mode.UpdatedAt
-					}
-					if message.FanMode != nil {
-						fanModeUpdatedTime = message.FanMode.UpdatedAt
-					}
-					if message.SwingMode != nil {
-						swingModeUpdatedTime = message.SwingMode.UpdatedAt
-					}
-					if message.Temperature != nil {
-						temperatureUpdatedTime = message.Temperature.UpdatedAt
-					}
-					if message.IsDisplayOn != nil {
-						isDisplayOnUpdatedTime = message.IsDisplayOn.UpdatedAt
-					}
-				}
+	return readDeviceAvailabilityReturn.Availability, nil
+}
 
-				time.Sleep(time.Millisecond * 500)
-			}
-		}
+func (s *service) UpdateWorkerStatus(ctx context.Context, input *models.UpdateWorkerStatusInput) error {
+	publishWorkerStatusInput := &modelsMqtt.PublishWorkerStatusInput{
+		Mac: input.Mac,
+		Status: modelsMqtt.WorkerStatus{
+			State:     input.State,
+			Restarts:  input.Restarts,
+			Error:     input.Error,
+			UpdatedAt: input.UpdatedAt,
+		},
+	}
+	err := s.mqtt.PublishWorkerStatus(ctx, publishWorkerStatusInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the worker status",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", publishWorkerStatusInput))
+		return err
 	}
+
+	return nil
 }
 
 func (s *service) PublishStatesOnHomeAssistantRestart(ctx context.Context, input *models.PublishStatesOnHomeAssistantRestartInput) error {
@@ -1397,23 +1845,15 @@ func (s *service) PublishStatesOnHomeAssistantRestart(ctx context.Context, input
 			// Read all states and configs //
 			/////////////////////////////////
 
-			readDeviceStatusRawInput := &modelsRepo.ReadDeviceStatusRawInput{
-				Mac: mac,
-			}
-			readDeviceStatusRawReturn, err := s.cache.ReadDeviceStatusRaw(gCtx, readDeviceStatusRawInput)
+			// The values which the device has not reported yet are skipped, they are published after the first poll
+			status, err := s.readDeviceStatusRaw(gCtx, mac)
 			if err != nil {
-				s.logger.ErrorContext(gCtx, "failed to read the device status",
-					slog.Any("err", err),
-					slog.Any("input", readDeviceStatusRawInput))
 				return err
 			}
 
-			hassStatus := models.DeviceStatusRaw(readDeviceStatusRawReturn.Status).ConvertToDeviceStatusHass()
-
 			readAmbientTempInput := &modelsRepo.ReadAmbientTempInput{Mac: mac}
-
 			readAmbientTempReturn, err := s.cache.ReadAmbientTemp(gCtx, readAmbientTempInput)
-			if err != nil {
+			if err != nil && !errors.Is(err, modelsRepo.ErrorDeviceStatusAmbientTempNotFound) {
 				s.logger.ErrorContext(gCtx, "failed to read the ambient temperature",
 					slog.Any("err", err),
 					slog.Any("input", readAmbientTempInput))
@@ -1421,9 +1861,8 @@ func (s *service) PublishStatesOnHomeAssistantRestart(ctx context.Context, input
 			}
 
 			readDeviceAvailabilityInput := &modelsRepo.ReadDeviceAvailabilityInput{Mac: mac}
-
 			readDeviceAvailabilityReturn, err := s.cache.ReadDeviceAvailability(gCtx, readDeviceAvailabilityInput)
-			if err != nil {
+			if err != nil && !errors.Is(err, modelsRepo.ErrorDeviceStatusAvailabilityNotFound) {
 				s.logger.ErrorContext(gCtx, "failed to read the device availability",
 					slog.Any("err", err),
 					slog.Any("input", readDeviceAvailabilityInput))
@@ -1441,6 +1880,22 @@ func (s *service) PublishStatesOnHomeAssistantRestart(ctx context.Context, input
 				return err
 			}
 
+			config := models.DeviceConfig(readDeviceConfigReturn.Config)
+
+			// The reading of the external sensor replaces the ambient temperature of the unit
+			var ambientTemp *float32
+			if readAmbientTempReturn != nil {
+				ambientTemp = &readAmbientTempReturn.Temperature
+			}
+			externalTemp, err := s.readExternalTemp(gCtx, mac, config)
+			if err != nil {
+				return err
+			}
+			if externalTemp != nil {
+				temperature := float32(math.Round(float64(*externalTemp)*10) / 10)
+				ambientTemp = &temperature
+			}
+
 			/////////////////////////////////
 			// 		Publish all topics     //
 			/////////////////////////////////
@@ -1455,34 +1910,118 @@ func (s *service) PublishStatesOnHomeAssistantRestart(ctx context.Context, input
 
 			time.Sleep(time.Millisecond * 500)
 
-			publishAvailabilityInput := &modelsMqtt.PublishAvailabilityInput{
-				Mac:          mac,
-				Availability: readDeviceAvailabilityReturn.Availability,
+			if readDeviceAvailabilityReturn != nil {
+				publishAvailabilityInput := &modelsMqtt.PublishAvailabilityInput{
+					Mac:          mac,
+					Availability: readDeviceAvailabilityReturn.Availability,
+				}
+				err = s.mqtt.PublishAvailability(gCtx, publishAvailabilityInput)
+				if err != nil {
+					s.logger.ErrorContext(gCtx, "failed to publish device availability",
+						slog.Any("err", err),
+						slog.Any("input", publishAvailabilityInput))
+					return err
+				}
 			}
-			err = s.mqtt.PublishAvailability(gCtx, publishAvailabilityInput)
-			if err != nil {
-				s.logger.ErrorContext(gCtx, "failed to publish device availability",
+
+			if ambientTemp != nil {
+				// Send  temperature to MQTT
+				publishAmbientTempInput := &modelsMqtt.PublishAmbientTempInput{
+					Mac:         mac,
+					Temperature: converter.Temperature(models.Celsius, readDeviceConfigReturn.Config.TemperatureUnit, *ambientTemp),
+				}
+				err = s.mqtt.PublishAmbientTemp(gCtx, publishAmbientTempInput)
+				if err != nil {
+					s.logger.ErrorContext(gCtx, "failed to publish ambient temperature",
+						slog.Any("err", err),
+						slog.Any("input", publishAmbientTempInput))
+					return err
+				}
+			}
+
+			if config.HeatCool {
+				heatCool, err := s.readHeatCool(gCtx, config)
+				if err != nil {
+					return err
+				}
+				err = s.publishTemperatureRange(gCtx, config, heatCool)
+				if err != nil {
+					return err
+				}
+			}
+
+			readHvacActionInput := &modelsRepo.ReadHvacActionInput{Mac: mac}
+			readHvacActionReturn, err := s.cache.ReadHvacAction(gCtx, readHvacActionInput)
+			switch {
+			case errors.Is(err, modelsRepo.ErrorDeviceStatusHvacActionNotFound):
+			case err != nil:
+				s.logger.ErrorContext(gCtx, "failed to read the hvac action",
 					slog.Any("err", err),
-					slog.Any("input", publishAvailabilityInput))
+					slog.Any("input", readHvacActionInput))
 				return err
+			default:
+				publishHvacActionInput := &modelsMqtt.PublishHvacActionInput{
+					Mac:        mac,
+					HvacAction: readHvacActionReturn.HvacAction,
+				}
+				err = s.mqtt.PublishHvacAction(gCtx, publishHvacActionInput)
+				if err != nil {
+					s.logger.ErrorContext(gCtx, "failed to publish the hvac action",
+						slog.Any("err", err),
+						slog.Any("input", publishHvacActionInput))
+					return err
+				}
 			}
 
-			// Send  temperature to MQTT
-			publishAmbientTempInput := &modelsMqtt.PublishAmbientTempInput{
-				Mac:         mac,
-				Temperature: converter.Temperature(models.Celsius, readDeviceConfigReturn.Config.TemperatureUnit, readAmbientTempReturn.Temperature),
+			readDeviceInfoRawInput := &modelsRepo.ReadDeviceInfoRawInput{Mac: mac}
+			readDeviceInfoRawReturn, err := s.cache.ReadDeviceInfoRaw(gCtx, readDeviceInfoRawInput)
+			switch {
+			case errors.Is(err, modelsRepo.ErrorDeviceInfoRawNotFound):
+			case err != nil:
+				s.logger.ErrorContext(gCtx, "failed to read the device info",
+					slog.Any("err", err),
+					slog.Any("input", readDeviceInfoRawInput))
+				return err
+			default:
+				diagnostics := models.DeviceInfoRaw(readDeviceInfoRawReturn.Info).ConvertToDiagnostics(readDeviceConfigReturn.Config.TemperatureUnit)
+				for name, value := range diagnostics {
+					publishDiagnosticInput := &modelsMqtt.PublishDiagnosticInput{
+						Mac:   mac,
+						Name:  name,
+						Value: value,
+					}
+					err = s.mqtt.PublishDiagnostic(gCtx, publishDiagnosticInput)
+					if err != nil {
+						s.logger.ErrorContext(gCtx, "failed to publish the diagnostic value",
+							slog.Any("err", err),
+							slog.Any("input", publishDiagnosticInput))
+						return err
+					}
+				}
 			}
-			err = s.mqtt.PublishAmbientTemp(gCtx, publishAmbientTempInput)
+
+			err = s.republishFaults(gCtx, mac)
+			if err != nil {
+				return err
+			}
+
+			if status == nil {
+				return nil
+			}
+
+			hassStatus := status.ConvertToDeviceStatusHass()
+			err = s.applyThermostat(gCtx, config, &hassStatus)
+			if err != nil {
+				return err
+			}
+			err = s.applyHeatCool(gCtx, config, &hassStatus)
 			if err != nil {
-				s.logger.ErrorContext(gCtx, "failed to publish ambient temperature",
-					slog.Any("err", err),
-					slog.Any("input", publishAmbientTempInput))
 				return err
 			}
 
 			publishTemperatureInput := &modelsMqtt.PublishTemperatureInput{
 				Mac:         mac,
-				Temperature: converter.Temperature(models.Celsius, readDeviceConfigReturn.Config.TemperatureUnit, readDeviceStatusRawReturn.Status.Temperature),
+				Temperature: converter.SetpointFromCelsius(readDeviceConfigReturn.Config.TemperatureUnit, hassStatus.Temperature),
 			}
 			err = s.mqtt.PublishTemperature(gCtx, publishTemperatureInput)
 			if err != nil {
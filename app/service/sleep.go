@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// StartSleepCurve runs the sleep curve of the device. The curve is kept in the state file,
+// so the steps which have become due while the bridge was stopped are applied at the start
+// if the latest of them is within the catch-up window.
+func (s *service) StartSleepCurve(ctx context.Context, input *models.StartSleepCurveInput) error {
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find the device actor",
+			slog.Any("err", err),
+			slog.String("device", input.Mac))
+		return err
+	}
+
+	isFirst := true
+	for {
+		state, err := s.readSleepState(ctx, input.Mac)
+		if err != nil {
+			return err
+		}
+
+		var next time.Time
+		switch {
+		case state != nil:
+			next, err = s.runSleepCurve(ctx, input.Mac, actor, *state)
+		case isFirst:
+			// The retained state of a curve which does not run is published only once
+			err = s.publishSleep(ctx, input.Mac, nil, 0, time.Time{})
+		}
+		if err != nil {
+			// The step is kept, so it is applied on the next check and a failure does not restart the device worker
+			s.logger.ErrorContext(ctx, "failed to run the sleep curve, it is retried",
+				slog.Any("err", err),
+				slog.String("device", input.Mac))
+			next = time.Now().Add(models.RetryInterval)
+		} else {
+			isFirst = false
+		}
+
+		// Without a running curve the loop waits until a curve is started
+		if next.IsZero() {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-actor.sleepChanged:
+			}
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-actor.sleepChanged:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// runSleepCurve applies the due steps of the curve as one command and returns the time of the next step,
+// or the zero time when the curve is finished. The steps are dropped when the latest of them is too old.
+func (s *service) runSleepCurve(ctx context.Context, mac string, actor *deviceActor, state models.SleepState) (time.Time, error) {
+	profile, ok := findSleepProfile(actor.sleepProfiles, state.Profile)
+	if !ok {
+		// The profile has been removed from the config while the bridge was stopped
+		s.logger.ErrorContext(ctx, "sleep profile is not found",
+			slog.String("device", mac),
+			slog.String("profile", state.Profile))
+		return time.Time{}, s.stopSleep(ctx, mac, "the sleep profile "+state.Profile+" is not found", models.EventSourceBridge)
+	}
+
+	config, err := s.readDeviceConfig(ctx, mac)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now()
+	step := state.Step
+	command := models.UpdateDeviceStatesInput{Mac: mac}
+	for ; step < len(profile.Steps); step++ {
+		if state.StartedAt.Add(profile.Steps[step].After).After(now) {
+			break
+		}
+		command.Merge(config.SleepCommand(profile.Steps[step], state.Base))
+	}
+
+	if step != state.Step {
+		dueAt := state.StartedAt.Add(profile.Steps[step-1].After)
+		if now.Sub(dueAt) > models.CatchUpWindow {
+			s.logger.InfoContext(ctx, "the missed sleep steps are too old, they are skipped",
+				slog.String("device", mac),
+				slog.String("profile", profile.Name),
+				slog.Int("step", step),
+				slog.Time("planned", dueAt))
+		} else {
+			s.logger.InfoContext(ctx, "the sleep step is applied",
+				slog.String("device", mac),
+				slog.String("profile", profile.Name),
+				slog.Int("step", step),
+				slog.Any("command", command))
+
+			err = s.updateStates(ctx, command)
+			if err != nil {
+				return time.Time{}, err
+			}
+		}
+	}
+
+	if step == len(profile.Steps) {
+		return time.Time{}, s.stopSleep(ctx, mac, "the sleep profile "+profile.Name+" is finished", models.EventSourceBridge)
+	}
+
+	if step != state.Step {
+		state.Step = step
+		err = s.upsertSleepState(ctx, mac, state)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	next := state.StartedAt.Add(profile.Steps[step].After)
+	return next, s.publishSleep(ctx, mac, &state, len(profile.Steps), next)
+}
+
+// UpdateSleep starts the sleep profile or cancels the running curve with OFF.
+// The setpoint which has been reached is kept when the curve is canceled.
+func (s *service) UpdateSleep(ctx context.Context, input *models.UpdateSleepInput) error {
+	if input.Profile == models.SleepOff {
+		state, err := s.readSleepState(ctx, input.Mac)
+		if err != nil || state == nil {
+			return err
+		}
+
+		return s.stopSleep(ctx, input.Mac, "the sleep profile "+state.Profile+" is canceled", models.EventSourceMqtt)
+	}
+
+	return s.startSleep(ctx, input.Mac, input.Profile, nil, models.EventSourceMqtt)
+}
+
+// startSleep starts the curve from the setpoint or, when it is nil, from the last commanded or the current setpoint.
+// A running curve is replaced.
+func (s *service) startSleep(ctx context.Context, mac, name string, base *float32, source string) error {
+	actor, err := s.readActor(mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find the device actor",
+			slog.Any("err", err),
+			slog.String("device", mac))
+		return err
+	}
+
+	profile, ok := findSleepProfile(actor.sleepProfiles, name)
+	if !ok {
+		s.logger.ErrorContext(ctx, "sleep profile is not found",
+			slog.String("device", mac),
+			slog.String("profile", name))
+		return models.ErrorSleepProfileNotFound
+	}
+
+	if base == nil {
+		desired, err := s.readDesiredState(ctx, mac)
+		if err != nil {
+			return err
+		}
+		status, err := s.readDeviceStatusRaw(ctx, mac)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case desired != nil && desired.Temperature != nil:
+			base = desired.Temperature
+		case status != nil:
+			base = &status.Temperature
+		default:
+			s.logger.ErrorContext(ctx, "the setpoint is not known yet",
+				slog.String("device", mac),
+				slog.String("profile", name))
+			return modelsRepo.ErrorDeviceStatusRawNotFound
+		}
+	}
+
+	err = s.upsertSleepState(ctx, mac, models.SleepState{
+		Profile:   profile.Name,
+		StartedAt: time.Now(),
+		Base:      *base,
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case actor.sleepChanged <- struct{}{}:
+	default:
+	}
+
+	return s.publishDeviceEvent(ctx, mac, models.EventTypeSleep, source,
+		"the sleep profile "+profile.Name+" is started", nil)
+}
+
+// stopSleep removes the curve and publishes that no curve runs
+func (s *service) stopSleep(ctx context.Context, mac, message, source string) error {
+	actor, err := s.readActor(mac)
+	if err != nil {
+		return err
+	}
+
+	err = s.deleteSleepState(ctx, mac)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case actor.sleepChanged <- struct{}{}:
+	default:
+	}
+
+	s.logger.InfoContext(ctx, message, slog.String("device", mac))
+
+	err = s.publishSleep(ctx, mac, nil, 0, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	return s.publishDeviceEvent(ctx, mac, models.EventTypeSleep, source, message, nil)
+}
+
+func findSleepProfile(profiles []models.SleepProfile, name string) (models.SleepProfile, bool) {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+
+	return models.SleepProfile{}, false
+}
+
+// readSleepState returns the running curve or nil
+func (s *service) readSleepState(ctx context.Context, mac string) (*models.SleepState, error) {
+	readSleepStateInput := &modelsRepo.ReadSleepStateInput{Mac: mac}
+	readSleepStateReturn, err := s.storage.ReadSleepState(ctx, readSleepStateInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorSleepStateNotFound) {
+			return nil, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the sleep state",
+			slog.Any("err", err),
+			slog.Any("input", readSleepStateInput))
+		return nil, err
+	}
+
+	state := models.SleepState(readSleepStateReturn.State)
+	return &state, nil
+}
+
+func (s *service) upsertSleepState(ctx context.Context, mac string, state models.SleepState) error {
+	upsertSleepStateInput := &modelsRepo.UpsertSleepStateInput{
+		Mac:   mac,
+		State: modelsRepo.SleepState(state),
+	}
+	err := s.storage.UpsertSleepState(ctx, upsertSleepStateInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the sleep state",
+			slog.Any("err", err),
+			slog.Any("input", upsertSleepStateInput))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) deleteSleepState(ctx context.Context, mac string) error {
+	deleteSleepStateInput := &modelsRepo.DeleteSleepStateInput{Mac: mac}
+	err := s.storage.DeleteSleepState(ctx, deleteSleepStateInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete the sleep state",
+			slog.Any("err", err),
+			slog.Any("input", deleteSleepStateInput))
+		return err
+	}
+
+	return nil
+}
+
+// publishSleep publishes the running curve and its progress, or OFF when the state is nil
+func (s *service) publishSleep(ctx context.Context, mac string, state *models.SleepState, steps int, next time.Time) error {
+	publishSleepInput := &modelsMqtt.PublishSleepInput{
+		Mac:     mac,
+		Profile: models.SleepOff,
+		Next:    next,
+	}
+	if state != nil {
+		publishSleepInput.Profile = state.Profile
+		publishSleepInput.Progress = state.Step * 100 / steps
+	}
+
+	err := s.mqtt.PublishSleep(ctx, publishSleepInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the sleep state",
+			slog.Any("err", err),
+			slog.Any("input", publishSleepInput))
+		return err
+	}
+
+	return nil
+}
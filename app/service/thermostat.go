@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/converter"
+)
+
+// UpdateExternalTemperature stores the reading of the external sensor and publishes it as the current temperature
+func (s *service) UpdateExternalTemperature(ctx context.Context, input *models.UpdateExternalTemperatureInput) error {
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{Mac: input.Mac}
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", readDeviceConfigInput))
+		return err
+	}
+	config := readDeviceConfigReturn.Config
+
+	temperature, err := models.ParseExternalTemperature(input.Payload, config.ExternalTempKey)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to parse the external temperature",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.String("payload", string(input.Payload)))
+		return err
+	}
+
+	temperature = converter.Temperature(config.TemperatureUnit, models.Celsius, temperature)
+	if !isPlausibleTemperature(&temperature, models.PlausibleAmbientTempMin, models.PlausibleAmbientTempMax) {
+		s.logger.ErrorContext(ctx, "the external temperature is not plausible",
+			slog.String("device", input.Mac),
+			slog.Any("temperature", temperature))
+		return models.ErrorInvalidParameterTemperature
+	}
+
+	upsertExternalTempInput := &modelsRepo.UpsertExternalTempInput{
+		Mac: input.Mac,
+		Temp: modelsRepo.ExternalTemp{
+			Temperature: temperature,
+			UpdatedAt:   time.Now(),
+		},
+	}
+	err = s.cache.UpsertExternalTemp(ctx, upsertExternalTempInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the external temperature",
+			slog.Any("err", err),
+			slog.Any("input", upsertExternalTempInput))
+		return err
+	}
+
+	publishAmbientTempInput := &modelsMqtt.PublishAmbientTempInput{
+		Mac:         input.Mac,
+		Temperature: converter.Temperature(models.Celsius, config.TemperatureUnit, float32(math.Round(float64(temperature)*10)/10)),
+	}
+	err = s.mqtt.PublishAmbientTemp(ctx, publishAmbientTempInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish ambient temperature",
+			slog.Any("err", err),
+			slog.Any("input", publishAmbientTempInput))
+		return err
+	}
+
+	return nil
+}
+
+// readExternalTemp returns the reading of the external sensor in Celsius or nil when there is no fresh reading
+func (s *service) readExternalTemp(ctx context.Context, mac string, config models.DeviceConfig) (*float32, error) {
+	if len(config.ExternalTempTopic) == 0 {
+		return nil, nil
+	}
+
+	readExternalTempInput := &modelsRepo.ReadExternalTempInput{Mac: mac}
+	readExternalTempReturn, err := s.cache.ReadExternalTemp(ctx, readExternalTempInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceExternalTempNotFound) {
+			return nil, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the external temperature",
+			slog.Any("err", err),
+			slog.Any("input", readExternalTempInput))
+		return nil, err
+	}
+
+	if time.Since(readExternalTempReturn.Temp.UpdatedAt) > config.ExternalTempMaxAge {
+		return nil, nil
+	}
+
+	return &readExternalTempReturn.Temp.Temperature, nil
+}
+
+// readThermostat returns the state of the virtual thermostat. The unit is not idle by default.
+func (s *service) readThermostat(ctx context.Context, mac string) (models.Thermostat, error) {
+	readThermostatInput := &modelsRepo.ReadThermostatInput{Mac: mac}
+	readThermostatReturn, err := s.cache.ReadThermostat(ctx, readThermostatInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorDeviceThermostatNotFound) {
+			return models.Thermostat{}, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the thermostat",
+			slog.Any("err", err),
+			slog.Any("input", readThermostatInput))
+		return models.Thermostat{}, err
+	}
+
+	return models.Thermostat(readThermostatReturn.Thermostat), nil
+}
+
+func (s *service) upsertThermostat(ctx context.Context, mac string, thermostat models.Thermostat) error {
+	upsertThermostatInput := &modelsRepo.UpsertThermostatInput{
+		Mac:        mac,
+		Thermostat: modelsRepo.Thermostat(thermostat),
+	}
+	err := s.cache.UpsertThermostat(ctx, upsertThermostatInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the thermostat",
+			slog.Any("err", err),
+			slog.Any("input", upsertThermostatInput))
+		return err
+	}
+
+	return nil
+}
+
+// thermostatCommand runs the virtual thermostat of the device and returns the command for the unit or nil.
+// Without a fresh reading of the external sensor the unit switched off by the thermostat is switched on,
+// so the unit controls the temperature by itself.
+func (s *service) thermostatCommand(ctx context.Context, m *deviceMonitor) (*models.UpdateDeviceStatesInput, error) {
+	if m.config.ThermostatControl == models.ThermostatControlNone || m.lastStatus == nil {
+		return nil, nil
+	}
+
+	thermostat, err := s.readThermostat(ctx, m.mac)
+	if err != nil {
+		return nil, err
+	}
+	room, err := s.readExternalTemp(ctx, m.mac, m.config)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := s.readDesiredState(ctx, m.mac)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		command *models.UpdateDeviceStatesInput
+		isIdle  bool
+	)
+	if room != nil {
+		canAdjust := time.Since(m.thermostatAdjustedAt) >= m.config.ThermostatInterval
+		command, isIdle = m.config.ThermostatCommand(*room, desired, *m.lastStatus, thermostat.IsIdle, canAdjust)
+	} else if thermostat.IsIdle && desired != nil && desired.Mode != nil && *desired.Mode != "off" {
+		command = &models.UpdateDeviceStatesInput{Mac: m.mac, Mode: desired.Mode}
+	}
+
+	// The unit is switched off and on by a later check when the protection allows it
+	if command != nil {
+		if wait, _ := m.shortCycleWait(time.Now(), *command); wait > 0 {
+			command, isIdle = nil, thermostat.IsIdle
+		}
+	}
+
+	if isIdle != thermostat.IsIdle {
+		err = s.upsertThermostat(ctx, m.mac, models.Thermostat{IsIdle: isIdle})
+		if err != nil {
+			return nil, err
+		}
+		err = s.updateHvacAction(ctx, m.mac)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if command != nil {
+		m.thermostatAdjustedAt = time.Now()
+		s.logger.InfoContext(ctx, "the unit is adjusted by the thermostat",
+			slog.String("device", m.mac),
+			slog.Any("room", room),
+			slog.Bool("idle", isIdle),
+			slog.Any("command", command))
+	}
+
+	return command, nil
+}
+
+// applyThermostat shows the target and the mode of the virtual thermostat instead of the unit ones:
+// the setpoint control changes the unit setpoint, and the power control switches the unit off.
+func (s *service) applyThermostat(ctx context.Context, config models.DeviceConfig, status *models.DeviceStatusHass) error {
+	if config.ThermostatControl == models.ThermostatControlNone {
+		return nil
+	}
+
+	desired, err := s.readDesiredState(ctx, config.Mac)
+	if err != nil || desired == nil {
+		return err
+	}
+
+	switch config.ThermostatControl {
+	case models.ThermostatControlSetpoint:
+		if desired.Temperature != nil {
+			status.Temperature = *desired.Temperature
+		}
+	case models.ThermostatControlPower:
+		thermostat, err := s.readThermostat(ctx, config.Mac)
+		if err != nil {
+			return err
+		}
+		if thermostat.IsIdle && desired.Mode != nil {
+			status.Mode = *desired.Mode
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	modelsMqtt "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/models"
+	modelsRepo "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+)
+
+// StartTimers runs the off and on timers of the device and publishes the remaining time every minute.
+// The timers are kept by the bridge, so a timer which has expired while the bridge was stopped is run at the start,
+// unless it has expired before the catch-up window.
+func (s *service) StartTimers(ctx context.Context, input *models.StartTimersInput) error {
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to find the device actor",
+			slog.Any("err", err),
+			slog.String("device", input.Mac))
+		return err
+	}
+
+	isFirst := true
+	for {
+		now := time.Now().In(s.location)
+		next := now.Add(models.TimerPublishInterval)
+
+		for _, kind := range models.TimerKinds {
+			timer, err := s.readTimerState(ctx, input.Mac, kind.Name)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case timer == nil:
+				// The retained state of a timer which is not set is published only once
+				if isFirst {
+					err = s.publishTimer(ctx, input.Mac, kind.Name, time.Time{}, now)
+				}
+			case now.Sub(timer.At) > models.CatchUpWindow:
+				err = s.dropTimer(ctx, input.Mac, kind.Name, timer.At)
+			case !timer.At.After(now):
+				err = s.runTimer(ctx, input.Mac, kind.Name)
+			default:
+				err = s.publishTimer(ctx, input.Mac, kind.Name, timer.At, now)
+				if timer.At.Before(next) {
+					next = timer.At
+				}
+			}
+			if err != nil {
+				// The timer is kept, so it is run on the next check and a failure does not restart the device worker
+				s.logger.ErrorContext(ctx, "failed to update the timer, it is retried",
+					slog.Any("err", err),
+					slog.String("device", input.Mac),
+					slog.String("timer", kind.Name))
+				next = earliest(next, now.Add(models.RetryInterval))
+			}
+		}
+		isFirst = false
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-actor.timersChanged:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// runTimer switches the device off or on and removes the timer
+func (s *service) runTimer(ctx context.Context, mac, kind string) error {
+	command, err := s.timerCommand(ctx, mac, kind)
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "the timer is expired",
+		slog.String("device", mac),
+		slog.String("timer", kind),
+		slog.Any("command", command))
+
+	err = s.updateStates(ctx, command)
+	if err != nil {
+		return err
+	}
+
+	err = s.deleteTimerState(ctx, mac, kind)
+	if err != nil {
+		return err
+	}
+
+	err = s.publishTimer(ctx, mac, kind, time.Time{}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return s.publishDeviceEvent(ctx, mac, models.EventTypeTimer, models.EventSourceTimer,
+		"the "+kind+" timer is expired", nil)
+}
+
+// dropTimer removes the timer which has expired too long ago without running it
+func (s *service) dropTimer(ctx context.Context, mac, kind string, at time.Time) error {
+	s.logger.InfoContext(ctx, "the missed timer is too old, it is dropped",
+		slog.String("device", mac),
+		slog.String("timer", kind),
+		slog.Time("at", at))
+
+	err := s.deleteTimerState(ctx, mac, kind)
+	if err != nil {
+		return err
+	}
+
+	return s.publishTimer(ctx, mac, kind, time.Time{}, time.Now())
+}
+
+// timerCommand returns the command of the timer. The on timer restores the last commanded mode
+// or the mode which the unit had before it was switched off.
+func (s *service) timerCommand(ctx context.Context, mac, kind string) (models.UpdateDeviceStatesInput, error) {
+	mode := "off"
+	if kind == models.TimerOn {
+		desired, err := s.readDesiredState(ctx, mac)
+		if err != nil {
+			return models.UpdateDeviceStatesInput{}, err
+		}
+		status, err := s.readDeviceStatusRaw(ctx, mac)
+		if err != nil {
+			return models.UpdateDeviceStatesInput{}, err
+		}
+
+		switch {
+		case desired != nil && desired.Mode != nil && *desired.Mode != "off":
+			mode = *desired.Mode
+		case status != nil && models.ModeStatuses[int(status.Mode)] != "":
+			mode = models.ModeStatuses[int(status.Mode)]
+		default:
+			mode = "auto"
+		}
+	}
+
+	return models.UpdateDeviceStatesInput{Mac: mac, Mode: &mode}, nil
+}
+
+func (s *service) UpdateTimer(ctx context.Context, input *models.UpdateTimerInput) error {
+	now := time.Now().In(s.location)
+	at, err := input.At(now)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "input data is not valid",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", input))
+		return err
+	}
+
+	actor, err := s.readActor(input.Mac)
+	if err != nil {
+		return err
+	}
+
+	if at.IsZero() {
+		err = s.deleteTimerState(ctx, input.Mac, input.Kind)
+	} else {
+		err = s.upsertTimerState(ctx, input.Mac, input.Kind, models.TimerState{At: at})
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.publishTimer(ctx, input.Mac, input.Kind, at, now)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case actor.timersChanged <- struct{}{}:
+	default:
+	}
+
+	readDeviceConfigInput := &modelsRepo.ReadDeviceConfigInput{Mac: input.Mac}
+	readDeviceConfigReturn, err := s.cache.ReadDeviceConfig(ctx, readDeviceConfigInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read device config",
+			slog.Any("err", err),
+			slog.String("device", input.Mac),
+			slog.Any("input", readDeviceConfigInput))
+		return err
+	}
+
+	// The native timer is sent with the set frame, the timer of the bridge is run anyway
+	if readDeviceConfigReturn.Config.NativeTimer {
+		return s.postCommand(ctx, models.UpdateDeviceStatesInput{Mac: input.Mac, IsTimerChanged: true})
+	}
+
+	return nil
+}
+
+// readTimerState returns the timer or nil when it is not set
+func (s *service) readTimerState(ctx context.Context, mac, kind string) (*models.TimerState, error) {
+	readTimerStateInput := &modelsRepo.ReadTimerStateInput{Mac: mac, Kind: kind}
+	readTimerStateReturn, err := s.storage.ReadTimerState(ctx, readTimerStateInput)
+	if err != nil {
+		if errors.Is(err, modelsRepo.ErrorTimerStateNotFound) {
+			return nil, nil
+		}
+		s.logger.ErrorContext(ctx, "failed to read the timer state",
+			slog.Any("err", err),
+			slog.Any("input", readTimerStateInput))
+		return nil, err
+	}
+
+	timer := models.TimerState(readTimerStateReturn.State)
+	return &timer, nil
+}
+
+func (s *service) upsertTimerState(ctx context.Context, mac, kind string, timer models.TimerState) error {
+	upsertTimerStateInput := &modelsRepo.UpsertTimerStateInput{
+		Mac:   mac,
+		Kind:  kind,
+		State: modelsRepo.TimerState(timer),
+	}
+	err := s.storage.UpsertTimerState(ctx, upsertTimerStateInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upsert the timer state",
+			slog.Any("err", err),
+			slog.Any("input", upsertTimerStateInput))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) deleteTimerState(ctx context.Context, mac, kind string) error {
+	deleteTimerStateInput := &modelsRepo.DeleteTimerStateInput{Mac: mac, Kind: kind}
+	err := s.storage.DeleteTimerState(ctx, deleteTimerStateInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete the timer state",
+			slog.Any("err", err),
+			slog.Any("input", deleteTimerStateInput))
+		return err
+	}
+
+	return nil
+}
+
+// publishTimer publishes the remaining minutes rounded up and the end time. A zero end time means that the timer is not set.
+func (s *service) publishTimer(ctx context.Context, mac, kind string, at, now time.Time) error {
+	publishTimerInput := &modelsMqtt.PublishTimerInput{
+		Mac:  mac,
+		Kind: kind,
+		At:   at,
+	}
+	if !at.IsZero() {
+		publishTimerInput.Remaining = int(math.Ceil(at.Sub(now).Minutes()))
+	}
+
+	err := s.mqtt.PublishTimer(ctx, publishTimerInput)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to publish the timer",
+			slog.Any("err", err),
+			slog.Any("input", publishTimerInput))
+		return err
+	}
+
+	return nil
+}
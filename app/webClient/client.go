@@ -13,15 +13,28 @@ import (
 
 type webClient struct {
 	logger *slog.Logger
+	// inflight limits the UDP transactions with all the devices at the same time
+	inflight chan struct{}
+	// responseTimeout bounds the time a transaction holds its slot when the device does not respond
+	responseTimeout time.Duration
 }
 
-func NewWebClient(logger *slog.Logger) app.WebClient {
+func NewWebClient(logger *slog.Logger, maxInflightRequests int, responseTimeout time.Duration) app.WebClient {
 	return &webClient{
-		logger: logger,
+		logger:          logger,
+		inflight:        make(chan struct{}, maxInflightRequests),
+		responseTimeout: responseTimeout,
 	}
 }
 
 func (w *webClient) SendCommand(ctx context.Context, input *models.SendCommandInput) (*models.SendCommandReturn, error) {
+	select {
+	case w.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-w.inflight }()
+
 	conn, err := net.Dial("udp", input.Ip+":"+strconv.Itoa(int(input.Port)))
 	if err != nil {
 		w.logger.ErrorContext(ctx, "Failed to dial address", slog.Any("err", err))
@@ -34,7 +47,7 @@ func (w *webClient) SendCommand(ctx context.Context, input *models.SendCommandIn
 		}
 	}(conn)
 
-	err = conn.SetDeadline(time.Now().Add(time.Second * 10))
+	err = conn.SetDeadline(time.Now().Add(w.responseTimeout))
 	if err != nil {
 		w.logger.ErrorContext(ctx, "Failed to set deadline", slog.Any("err", err))
 		return nil, err
@@ -47,11 +60,11 @@ func (w *webClient) SendCommand(ctx context.Context, input *models.SendCommandIn
 	}
 
 	response := make([]byte, 1024)
-	_, err = conn.Read(response)
+	n, err := conn.Read(response)
 	if err != nil {
 		w.logger.ErrorContext(ctx, "Failed to read the response", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &models.SendCommandReturn{Payload: response}, nil
+	return &models.SendCommandReturn{Payload: response[:n]}, nil
 }
@@ -13,11 +13,52 @@ type (
 		Service Service   `yaml:"service" json:"service"`
 		Mqtt    Mqtt      `yaml:"mqtt" json:"mqtt"`
 		Devices []Devices `yaml:"devices" json:"devices"`
+		// Scenes are the named states which can be applied to a device or to all the devices at once
+		Scenes map[string]DeviceStates `yaml:"scenes" json:"scenes"`
+		// Groups are the sets of devices which are commanded together
+		Groups []Group `yaml:"groups" json:"groups"`
+		// SleepProfiles are the setpoint curves which the bridge runs over the night
+		SleepProfiles map[string]SleepProfile `yaml:"sleep_profiles" json:"sleep_profiles"`
+	}
+
+	SleepProfile struct {
+		Steps []SleepStep `yaml:"steps" json:"steps"`
+	}
+
+	// SleepStep is applied the time after the start of the curve
+	SleepStep struct {
+		// After is the time from the start of the curve in minutes
+		After int `yaml:"after" json:"after"`
+		// Offset is added to the setpoint at the start of the curve, in the temperature unit of the device
+		Offset  *float32 `yaml:"offset" json:"offset"`
+		FanMode *string  `yaml:"fan_mode" json:"fan_mode"`
+		Display *string  `yaml:"display" json:"display"`
+	}
+
+	Group struct {
+		Name string `yaml:"name" json:"name"`
+		// Devices are the MAC addresses of the members
+		Devices []string `yaml:"devices" json:"devices"`
+		// Discovery publishes a Home Assistant climate entity of the group
+		Discovery bool `yaml:"discovery" json:"discovery"`
 	}
 
 	Service struct {
 		UpdateInterval int    `env-default:"10"    yaml:"update_interval" json:"update_interval"`
 		LogLevel       string `env-default:"error" yaml:"log_level" json:"log_level"`
+		// MaxInflightRequests limits the UDP requests which are sent to all the devices at the same time
+		MaxInflightRequests int `env-default:"2" yaml:"max_inflight_requests" json:"max_inflight_requests"`
+		// ResponseTimeout is the time in milliseconds to wait for the response of a device, an offline device holds a request slot for it
+		ResponseTimeout int `env-default:"1500" yaml:"response_timeout_ms" json:"response_timeout_ms"`
+		// StartupStagger is the delay in milliseconds between the starts of the devices
+		StartupStagger int `env-default:"1000" yaml:"startup_stagger_ms" json:"startup_stagger_ms"`
+		// AuthBackoffMin and AuthBackoffMax are the bounds in seconds of the exponential backoff of the authorization retries
+		AuthBackoffMin int `env-default:"3" yaml:"auth_backoff_min" json:"auth_backoff_min"`
+		AuthBackoffMax int `env-default:"300" yaml:"auth_backoff_max" json:"auth_backoff_max"`
+		// Timezone is the IANA name of the time zone of the schedules, e.g. Europe/Berlin. Default: the local time zone
+		Timezone string `yaml:"timezone" json:"timezone"`
+		// StateFile keeps the state of the schedules and the timers between the restarts
+		StateFile string `env-default:"./config/state.json" yaml:"state_file" json:"state_file"`
 	}
 
 	Mqtt struct {
@@ -32,6 +73,10 @@ type (
 		SkipCertCnCheck          bool    `env-default:"true" yaml:"skip_cert_cn_check" json:"skip_cert_cn_check"`
 		CertificateClient        *string `yaml:"certificate_client" json:"certificate_client"`
 		KeyClient                *string `yaml:"key-client" json:"key_client"`
+		// DiscoveryFormat selects how devices are described to the controller:
+		// homeassistant (default) or homie (Homie 4 convention, e.g. for openHAB).
+		DiscoveryFormat string `env-default:"homeassistant" yaml:"discovery_format" json:"discovery_format"`
+		HomieTopic      string `env-default:"homie" yaml:"homie_topic" json:"homie_topic"`
 	}
 
 	Devices struct {
@@ -42,6 +87,161 @@ type (
 		// TemperatureUnit defines the temperature unit of the device, C or F.
 		// If this is not set, the temperature unit is Celsius.
 		TemperatureUnit string `env-default:"C" yaml:"temperature_unit" json:"temperature_unit"` // BUG cleanenv env-default is not working
+		// MinTemp and MaxTemp define the setpoint range in the temperature unit of the device.
+		// Heat pumps with frost protection accept setpoints down to 8 °C. Default: 16 - 32 °C.
+		MinTemp *float32      `yaml:"min_temp" json:"min_temp"`
+		MaxTemp *float32      `yaml:"max_temp" json:"max_temp"`
+		Ambient DeviceAmbient `yaml:"ambient" json:"ambient"`
+		Polling DevicePolling `yaml:"polling" json:"polling"`
+		// RestoreState re-applies the last state commanded via MQTT when the device
+		// comes back online switched off while it has been commanded on, as after a power loss
+		RestoreState bool          `yaml:"restore_state" json:"restore_state"`
+		Enforce      DeviceEnforce `yaml:"enforce" json:"enforce"`
+		// Interlock switches the unit off while a window or a door is open
+		Interlock DeviceInterlock `yaml:"interlock" json:"interlock"`
+		// Protection keeps the compressor from short cycling
+		Protection DeviceProtection `yaml:"protection" json:"protection"`
+		// ExternalSensor replaces the return-air sensor of the unit with a room sensor published via MQTT
+		ExternalSensor DeviceExternalSensor `yaml:"external_sensor" json:"external_sensor"`
+		// HeatCool adds the heat_cool mode with the low and high targets which is emulated by the bridge
+		HeatCool DeviceHeatCool `yaml:"heat_cool" json:"heat_cool"`
+		// Schedules change the states of the device at the set times
+		Schedules []DeviceSchedule `yaml:"schedules" json:"schedules"`
+		// NativeTimer also sends the off and on timers to the unit, so they run while the bridge is down.
+		// The encoding of the timer bytes is not verified on all the units
+		NativeTimer bool `yaml:"native_timer" json:"native_timer"`
+	}
+
+	// DeviceExternalSensor configures the external temperature sensor and the virtual thermostat.
+	// The temperatures are in the unit of the device.
+	DeviceExternalSensor struct {
+		// Topic is the MQTT topic of the sensor, e.g. zigbee2mqtt/bedroom_sensor
+		Topic string `yaml:"topic" json:"topic"`
+		// Key is the field of the JSON payload, nested fields are separated by dots. Empty means a plain number payload
+		Key string `yaml:"key" json:"key"`
+		// MaxAge is the age in seconds after which the reading is ignored. Default: 900
+		MaxAge int `yaml:"max_age" json:"max_age"`
+		// Control is none (default), setpoint or power. With setpoint the unit setpoint is adjusted,
+		// with power the unit is switched off and on, so the room reaches the target temperature.
+		Control string `yaml:"control" json:"control"`
+		// Hysteresis is the allowed deviation of the room temperature from the target. Default: 0.5 °C
+		Hysteresis *float32 `yaml:"hysteresis" json:"hysteresis"`
+		// MaxOffset is the largest difference between the unit setpoint and the target. Default: 3 °C
+		MaxOffset *float32 `yaml:"max_offset" json:"max_offset"`
+		// Interval is the least time in seconds between two setpoint adjustments. Default: 300
+		Interval int `yaml:"interval" json:"interval"`
+	}
+
+	// DeviceHeatCool configures the heat_cool mode. The temperatures are in the unit of the device.
+	DeviceHeatCool struct {
+		Enabled bool `yaml:"enabled" json:"enabled"`
+		// Low and High are the initial targets. Default: 20 - 24 °C
+		Low  *float32 `yaml:"low" json:"low"`
+		High *float32 `yaml:"high" json:"high"`
+		// Deadband is how far the room temperature has to pass the other target to switch the mode. Default: 1 °C
+		Deadband *float32 `yaml:"deadband" json:"deadband"`
+		// MinDwell is the least time in seconds between two switches of the mode, 0 is allowed. Default: 900
+		MinDwell *int `yaml:"min_dwell" json:"min_dwell"`
+	}
+
+	// DeviceSchedule is a weekly rule which applies the states at the set time
+	DeviceSchedule struct {
+		Name string `yaml:"name" json:"name"`
+		// Days are mon, tue, wed, thu, fri, sat and sun. Default: every day
+		Days []string `yaml:"days" json:"days"`
+		// Time is the time of day in the service time zone, e.g. 07:30
+		Time string `yaml:"time" json:"time"`
+		// Enabled is the initial state of the schedule switch. Default: true
+		Enabled      *bool `yaml:"enabled" json:"enabled"`
+		DeviceStates `yaml:",inline"`
+		// Sleep starts the sleep profile after the states are applied
+		Sleep *string `yaml:"sleep" json:"sleep"`
+	}
+
+	// DeviceStates are the states which are applied. The temperature is in the unit of the device, the display is ON or OFF
+	DeviceStates struct {
+		Mode        *string  `yaml:"mode" json:"mode"`
+		Temperature *float32 `yaml:"temperature" json:"temperature"`
+		FanMode     *string  `yaml:"fan_mode" json:"fan_mode"`
+		SwingMode   *string  `yaml:"swing_mode" json:"swing_mode"`
+		Display     *string  `yaml:"display" json:"display"`
+	}
+
+	// DeviceEnforce configures the reverting of the changes made by the IR remote
+	DeviceEnforce struct {
+		// Mode is off (default), always or quiet_hours. The changes which differ from the desired state
+		// are reverted always or only during the quiet hours.
+		Mode string `yaml:"mode" json:"mode"`
+		// QuietHours is the daily window in the time zone of the service, e.g. 22:00-07:00
+		QuietHours string `yaml:"quiet_hours" json:"quiet_hours"`
+		// MinTemp and MaxTemp limit the setpoint in the temperature unit of the device whatever the mode is
+		MinTemp *float32 `yaml:"min_temp" json:"min_temp"`
+		MaxTemp *float32 `yaml:"max_temp" json:"max_temp"`
+	}
+
+	// DeviceInterlock configures the window and door interlock
+	DeviceInterlock struct {
+		Contacts []DeviceContact `yaml:"contacts" json:"contacts"`
+		// Action is off (default) or fan_only
+		Action string `yaml:"action" json:"action"`
+		// GracePeriod is the time in seconds a contact has to stay open before the unit is switched, 0 is allowed. Default: 30
+		GracePeriod *int `yaml:"grace_period" json:"grace_period"`
+	}
+
+	// DeviceContact is a sensor of the interlock, e.g. a window contact or a presence sensor
+	DeviceContact struct {
+		// Topic is the MQTT topic of the sensor without wildcards, e.g. zigbee2mqtt/bedroom_window
+		Topic string `yaml:"topic" json:"topic"`
+		// Key is the field of the JSON payload, nested fields are separated by dots. Empty means the whole payload
+		Key string `yaml:"key" json:"key"`
+		// Open is the value which means open, e.g. false for the contact field of zigbee2mqtt
+		Open string `yaml:"open" json:"open"`
+	}
+
+	// DeviceProtection configures the anti-short-cycle protection of the compressor.
+	// Every switch on or off and every change of the mode of a running unit is a transition.
+	DeviceProtection struct {
+		// MinOffTime is the least time in seconds the unit stays off before it is switched on. Default: 0
+		MinOffTime int `yaml:"min_off_time" json:"min_off_time"`
+		// MinRunTime is the least time in seconds the unit runs in a mode before it is switched off or to another mode. Default: 0
+		MinRunTime int `yaml:"min_run_time" json:"min_run_time"`
+		// Action is queue (default) or reject. The queued transition is made when it is allowed,
+		// the rejected one is dropped. The other states of the command are applied at once.
+		Action string `yaml:"action" json:"action"`
+	}
+
+	// DeviceAmbient configures the processing of the ambient temperature. The temperatures are in the unit of the device.
+	DeviceAmbient struct {
+		// Offset is added to every reading of the return-air sensor
+		Offset float32 `yaml:"offset" json:"offset"`
+		// OutlierThreshold is the largest accepted jump between two readings. Default: 4 °C, 0 disables the check
+		OutlierThreshold *float32 `yaml:"outlier_threshold" json:"outlier_threshold"`
+		// Smoothing is the filter of the readings: none (default), moving_average or median
+		Smoothing string `yaml:"smoothing" json:"smoothing"`
+		// Samples is the number of the readings for the filter. Default: 5
+		Samples int `yaml:"samples" json:"samples"`
+	}
+
+	// DevicePolling configures how often the device is requested. Zero values are replaced by the defaults.
+	DevicePolling struct {
+		// Interval is the interval of the state requests in seconds. Default: service update_interval
+		Interval int `yaml:"interval" json:"interval"`
+		// AmbientInterval is the interval of the ambient temperature requests in seconds. Default: 180
+		AmbientInterval int `yaml:"ambient_interval" json:"ambient_interval"`
+		// OfflineIntervals is the number of state intervals without an answer after which the device is offline. Default: 3
+		OfflineIntervals int `yaml:"offline_intervals" json:"offline_intervals"`
+		// CommandDelay is the pause around a command frame and before a retry in milliseconds, 0 is allowed. Default: 500
+		CommandDelay *int `yaml:"command_delay_ms" json:"command_delay_ms"`
+		// Debounce is the window in milliseconds in which the commands are coalesced into one frame, 0 is allowed. Default: 300
+		Debounce *int `yaml:"debounce_ms" json:"debounce_ms"`
+		// Adaptive enables the fast polling after a command or a change by the IR remote
+		// and the slow polling when the device is off
+		Adaptive bool `yaml:"adaptive" json:"adaptive"`
+		// FastInterval is the state interval in seconds during FastWindow seconds after the activity. Default: 2 and 60
+		FastInterval int `yaml:"fast_interval" json:"fast_interval"`
+		FastWindow   int `yaml:"fast_window" json:"fast_window"`
+		// IdleInterval is the state interval in seconds when the device is off. Default: 60
+		IdleInterval int `yaml:"idle_interval" json:"idle_interval"`
 	}
 )
 
@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"math/rand"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+	// The time zones of the schedules are embedded as the image has no zoneinfo
+	_ "time/tzdata"
 
 	"github.com/ArtemVladimirov/broadlinkac2mqtt/app"
 	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt"
@@ -16,17 +22,36 @@ import (
 	workspaceMqttSender "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/publisher"
 	workspaceMqttReceiver "github.com/ArtemVladimirov/broadlinkac2mqtt/app/mqtt/subscriber"
 	workspaceCache "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/cache"
+	workspaceStorage "github.com/ArtemVladimirov/broadlinkac2mqtt/app/repository/storage"
 	workspaceService "github.com/ArtemVladimirov/broadlinkac2mqtt/app/service"
 	workspaceServiceModels "github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
 	workspaceWebClient "github.com/ArtemVladimirov/broadlinkac2mqtt/app/webClient"
 	"github.com/ArtemVladimirov/broadlinkac2mqtt/config"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/converter"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/supervisor"
 	paho "github.com/eclipse/paho.mqtt.golang"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	workerMinBackoff = time.Second
+	workerMaxBackoff = time.Minute
+	shutdownTimeout  = time.Second * 5
+)
+
 type App struct {
 	devices             []workspaceServiceModels.DeviceConfig
+	schedules           map[string][]workspaceServiceModels.Schedule
+	contacts            map[string][]workspaceServiceModels.Contact
+	scenes              map[string][]workspaceServiceModels.Scene
+	groups              []workspaceServiceModels.Group
+	sleepProfiles       []workspaceServiceModels.SleepProfile
 	autoDiscoveryTopic  *string
+	discoveryFormat     string
+	homieTopic          string
+	startupStagger      time.Duration
+	authBackoffMin      time.Duration
+	authBackoffMax      time.Duration
 	topicPrefix         string
 	logLevel            string
 	wsBroadLinkReceiver app.WebClient
@@ -42,6 +67,27 @@ func NewApp(logger *slog.Logger) (*App, error) {
 		return nil, err
 	}
 
+	if cfg.Service.MaxInflightRequests < 1 || cfg.Service.ResponseTimeout < 1 || cfg.Service.StartupStagger < 0 ||
+		cfg.Service.AuthBackoffMin < 1 || cfg.Service.AuthBackoffMax < cfg.Service.AuthBackoffMin {
+		err = errors.New("request limits are wrong")
+		logger.Error("service config is incorrect", slog.Any("service", cfg.Service), slog.Any("err", err))
+		return nil, err
+	}
+
+	location := time.Local
+	if len(cfg.Service.Timezone) != 0 {
+		location, err = time.LoadLocation(cfg.Service.Timezone)
+		if err != nil {
+			logger.Error("timezone is incorrect", slog.String("timezone", cfg.Service.Timezone), slog.Any("err", err))
+			return nil, err
+		}
+	}
+
+	storage, err := workspaceStorage.NewStorage(logger, cfg.Service.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
 	// MQTT
 	mqttConfig := workspaceMqttModels.ConfigMqtt{
 		Broker:                   cfg.Mqtt.Broker,
@@ -51,26 +97,44 @@ func NewApp(logger *slog.Logger) (*App, error) {
 		TopicPrefix:              cfg.Mqtt.TopicPrefix,
 		AutoDiscoveryTopic:       cfg.Mqtt.AutoDiscoveryTopic,
 		AutoDiscoveryTopicRetain: cfg.Mqtt.AutoDiscoveryTopicRetain,
+		DiscoveryFormat:          cfg.Mqtt.DiscoveryFormat,
+		HomieTopic:               cfg.Mqtt.HomieTopic,
 	}
 
 	opts, _ := mqtt.NewMqttConfig(logger, cfg.Mqtt)
 	client := paho.NewClient(opts)
 
 	//Configure MQTT Sender Layer
-	mqttSender := workspaceMqttSender.NewMqttSender(
-		logger,
-		mqttConfig,
-		client,
-	)
+	var mqttSender app.MqttPublisher
+	switch cfg.Mqtt.DiscoveryFormat {
+	case workspaceMqttModels.DiscoveryFormatHomeAssistant:
+		mqttSender = workspaceMqttSender.NewMqttSender(
+			logger,
+			mqttConfig,
+			client,
+		)
+	case workspaceMqttModels.DiscoveryFormatHomie:
+		mqttSender = workspaceMqttSender.NewHomieSender(
+			logger,
+			mqttConfig,
+			client,
+		)
+	default:
+		err = errors.New("unknown discovery format")
+		logger.Error("mqtt config is incorrect", slog.String("discovery_format", cfg.Mqtt.DiscoveryFormat), slog.Any("err", err))
+		return nil, err
+	}
 
 	//Configure Service Layer
 	service := workspaceService.NewService(
 		logger,
 		cfg.Mqtt.TopicPrefix,
-		cfg.Service.UpdateInterval,
+		location,
 		mqttSender,
-		workspaceWebClient.NewWebClient(logger),
+		workspaceWebClient.NewWebClient(logger, cfg.Service.MaxInflightRequests,
+			time.Duration(cfg.Service.ResponseTimeout)*time.Millisecond),
 		workspaceCache.NewCache(logger),
+		storage,
 	)
 	//Configure MQTT Receiver Layer
 	mqttReceiver := workspaceMqttReceiver.NewMqttReceiver(
@@ -79,7 +143,27 @@ func NewApp(logger *slog.Logger) (*App, error) {
 		mqttConfig,
 	)
 
+	sleepProfiles := make([]workspaceServiceModels.SleepProfile, 0, len(cfg.SleepProfiles))
+	for name, profile := range cfg.SleepProfiles {
+		prof, err := newSleepProfile(name, profile)
+		if err != nil {
+			logger.Error("sleep profile is incorrect", slog.String("profile", name), slog.Any("err", err))
+			return nil, err
+		}
+		sleepProfiles = append(sleepProfiles, prof)
+	}
+
 	devices := make([]workspaceServiceModels.DeviceConfig, 0, len(cfg.Devices))
+	schedules := make(map[string][]workspaceServiceModels.Schedule, len(cfg.Devices))
+	contacts := make(map[string][]workspaceServiceModels.Contact, len(cfg.Devices))
+	scenes := make(map[string][]workspaceServiceModels.Scene, len(cfg.Devices))
+	sceneNames := make([]string, 0, len(cfg.Scenes))
+	for name := range cfg.Scenes {
+		sceneNames = append(sceneNames, name)
+	}
+	slices.Sort(sceneNames)
+	// sceneDevices is the number of the devices which support the scene
+	sceneDevices := make(map[string]int, len(cfg.Scenes))
 	for _, device := range cfg.Devices {
 		if len(device.TemperatureUnit) == 0 {
 			device.TemperatureUnit = "C"
@@ -91,7 +175,106 @@ func NewApp(logger *slog.Logger) (*App, error) {
 			Name:            device.Name,
 			Port:            device.Port,
 			TemperatureUnit: strings.ToUpper(device.TemperatureUnit),
+			MinTemp:         workspaceServiceModels.DefaultMinTemp,
+			MaxTemp:         workspaceServiceModels.DefaultMaxTemp,
 		}
+		if device.MinTemp != nil {
+			dev.MinTemp = converter.SetpointToCelsius(dev.TemperatureUnit, *device.MinTemp)
+		}
+		if device.MaxTemp != nil {
+			dev.MaxTemp = converter.SetpointToCelsius(dev.TemperatureUnit, *device.MaxTemp)
+		}
+
+		dev.AmbientOffset = converter.DifferenceToCelsius(dev.TemperatureUnit, device.Ambient.Offset)
+		dev.AmbientOutlierThreshold = workspaceServiceModels.DefaultAmbientOutlierThreshold
+		if device.Ambient.OutlierThreshold != nil {
+			dev.AmbientOutlierThreshold = converter.DifferenceToCelsius(dev.TemperatureUnit, *device.Ambient.OutlierThreshold)
+		}
+		dev.AmbientSmoothing = workspaceServiceModels.AmbientSmoothingNone
+		if len(device.Ambient.Smoothing) != 0 {
+			dev.AmbientSmoothing = device.Ambient.Smoothing
+		}
+		dev.AmbientSamples = workspaceServiceModels.DefaultAmbientSamples
+		if device.Ambient.Samples != 0 {
+			dev.AmbientSamples = device.Ambient.Samples
+		}
+
+		dev.PollInterval = time.Duration(valueOrDefault(device.Polling.Interval, cfg.Service.UpdateInterval)) * time.Second
+		dev.AmbientPollInterval = time.Duration(valueOrDefault(device.Polling.AmbientInterval, workspaceServiceModels.DefaultAmbientPollInterval)) * time.Second
+		dev.OfflineIntervals = valueOrDefault(device.Polling.OfflineIntervals, workspaceServiceModels.DefaultOfflineIntervals)
+		dev.CommandDelay = time.Duration(pointerOrDefault(device.Polling.CommandDelay, workspaceServiceModels.DefaultCommandDelay)) * time.Millisecond
+		dev.CommandDebounce = time.Duration(pointerOrDefault(device.Polling.Debounce, workspaceServiceModels.DefaultCommandDebounce)) * time.Millisecond
+		dev.AdaptivePolling = device.Polling.Adaptive
+		dev.RestoreState = device.RestoreState
+		dev.NativeTimer = device.NativeTimer
+
+		dev.EnforceMode = workspaceServiceModels.EnforceModeOff
+		if len(device.Enforce.Mode) != 0 {
+			dev.EnforceMode = device.Enforce.Mode
+		}
+		if len(device.Enforce.QuietHours) != 0 {
+			dev.EnforceQuietStart, dev.EnforceQuietEnd, err = workspaceServiceModels.ParseDailyWindow(device.Enforce.QuietHours)
+			if err != nil {
+				logger.Error("quiet hours are incorrect", slog.String("device", device.Mac), slog.Any("err", err))
+				return nil, err
+			}
+		} else if dev.EnforceMode == workspaceServiceModels.EnforceModeQuietHours {
+			err = errors.New("quiet hours are not set")
+			logger.Error("device config is incorrect", slog.String("device", device.Mac), slog.Any("err", err))
+			return nil, err
+		}
+		if device.Enforce.MinTemp != nil {
+			minTemp := converter.SetpointToCelsius(dev.TemperatureUnit, *device.Enforce.MinTemp)
+			dev.EnforceMinTemp = &minTemp
+		}
+		if device.Enforce.MaxTemp != nil {
+			maxTemp := converter.SetpointToCelsius(dev.TemperatureUnit, *device.Enforce.MaxTemp)
+			dev.EnforceMaxTemp = &maxTemp
+		}
+		dev.ExternalTempTopic = device.ExternalSensor.Topic
+		dev.ExternalTempKey = device.ExternalSensor.Key
+		dev.ExternalTempMaxAge = time.Duration(valueOrDefault(device.ExternalSensor.MaxAge, workspaceServiceModels.DefaultExternalTempMaxAge)) * time.Second
+		dev.ThermostatControl = device.ExternalSensor.Control
+		if len(dev.ThermostatControl) == 0 {
+			dev.ThermostatControl = workspaceServiceModels.ThermostatControlNone
+		}
+		dev.ThermostatHysteresis = workspaceServiceModels.DefaultThermostatHysteresis
+		if device.ExternalSensor.Hysteresis != nil {
+			dev.ThermostatHysteresis = converter.DifferenceToCelsius(dev.TemperatureUnit, *device.ExternalSensor.Hysteresis)
+		}
+		dev.ThermostatMaxOffset = workspaceServiceModels.DefaultThermostatMaxOffset
+		if device.ExternalSensor.MaxOffset != nil {
+			dev.ThermostatMaxOffset = converter.DifferenceToCelsius(dev.TemperatureUnit, *device.ExternalSensor.MaxOffset)
+		}
+		dev.ThermostatInterval = time.Duration(valueOrDefault(device.ExternalSensor.Interval, workspaceServiceModels.DefaultThermostatInterval)) * time.Second
+		dev.HeatCool = device.HeatCool.Enabled
+		dev.HeatCoolLow = workspaceServiceModels.DefaultHeatCoolLow
+		if device.HeatCool.Low != nil {
+			dev.HeatCoolLow = converter.SetpointToCelsius(dev.TemperatureUnit, *device.HeatCool.Low)
+		}
+		dev.HeatCoolHigh = workspaceServiceModels.DefaultHeatCoolHigh
+		if device.HeatCool.High != nil {
+			dev.HeatCoolHigh = converter.SetpointToCelsius(dev.TemperatureUnit, *device.HeatCool.High)
+		}
+		dev.HeatCoolDeadband = workspaceServiceModels.DefaultHeatCoolDeadband
+		if device.HeatCool.Deadband != nil {
+			dev.HeatCoolDeadband = converter.DifferenceToCelsius(dev.TemperatureUnit, *device.HeatCool.Deadband)
+		}
+		dev.HeatCoolMinDwell = time.Duration(pointerOrDefault(device.HeatCool.MinDwell, workspaceServiceModels.DefaultHeatCoolMinDwell)) * time.Second
+		dev.ShortCycleMinOff = time.Duration(device.Protection.MinOffTime) * time.Second
+		dev.ShortCycleMinRun = time.Duration(device.Protection.MinRunTime) * time.Second
+		dev.ShortCycleAction = workspaceServiceModels.ShortCycleActionQueue
+		if len(device.Protection.Action) != 0 {
+			dev.ShortCycleAction = device.Protection.Action
+		}
+		dev.InterlockAction = workspaceServiceModels.InterlockActionOff
+		if len(device.Interlock.Action) != 0 {
+			dev.InterlockAction = device.Interlock.Action
+		}
+		dev.InterlockGrace = time.Duration(pointerOrDefault(device.Interlock.GracePeriod, workspaceServiceModels.DefaultInterlockGrace)) * time.Second
+		dev.FastPollInterval = time.Duration(valueOrDefault(device.Polling.FastInterval, workspaceServiceModels.DefaultFastPollInterval)) * time.Second
+		dev.FastPollWindow = time.Duration(valueOrDefault(device.Polling.FastWindow, workspaceServiceModels.DefaultFastPollWindow)) * time.Second
+		dev.IdlePollInterval = time.Duration(valueOrDefault(device.Polling.IdleInterval, workspaceServiceModels.DefaultIdlePollInterval)) * time.Second
 
 		err = dev.Validate()
 		if err != nil {
@@ -99,16 +282,116 @@ func NewApp(logger *slog.Logger) (*App, error) {
 			return nil, err
 		}
 
+		for _, schedule := range device.Schedules {
+			sch, err := newSchedule(dev, schedule)
+			if err != nil {
+				logger.Error("schedule is incorrect", slog.String("device", device.Mac), slog.String("schedule", schedule.Name), slog.Any("err", err))
+				return nil, err
+			}
+			if _, ok := cfg.SleepProfiles[sch.Sleep]; len(sch.Sleep) != 0 && !ok {
+				err = errors.New("sleep profile is not found")
+				logger.Error("schedule is incorrect", slog.String("device", device.Mac), slog.String("schedule", schedule.Name), slog.Any("err", err))
+				return nil, err
+			}
+			for _, other := range schedules[dev.Mac] {
+				if other.Name == sch.Name {
+					err = errors.New("schedule name is not unique")
+					logger.Error("schedule is incorrect", slog.String("device", device.Mac), slog.String("schedule", schedule.Name), slog.Any("err", err))
+					return nil, err
+				}
+			}
+			schedules[dev.Mac] = append(schedules[dev.Mac], sch)
+		}
+
+		for _, contact := range device.Interlock.Contacts {
+			con := workspaceServiceModels.Contact{
+				Topic: contact.Topic,
+				Key:   contact.Key,
+				Open:  contact.Open,
+			}
+			err = con.Validate()
+			if err != nil {
+				logger.Error("interlock contact is incorrect", slog.String("device", device.Mac), slog.String("topic", contact.Topic), slog.Any("err", err))
+				return nil, err
+			}
+			for _, other := range contacts[dev.Mac] {
+				if other.Topic == con.Topic {
+					err = errors.New("contact topic is not unique")
+					logger.Error("interlock contact is incorrect", slog.String("device", device.Mac), slog.String("topic", contact.Topic), slog.Any("err", err))
+					return nil, err
+				}
+			}
+			contacts[dev.Mac] = append(contacts[dev.Mac], con)
+		}
+
+		// Every scene is checked against every device, the device which does not support it is skipped
+		for _, name := range sceneNames {
+			scene, err := newScene(dev, name, cfg.Scenes[name])
+			if err != nil {
+				logger.Warn("scene is not supported by the device, it is skipped",
+					slog.String("device", device.Mac), slog.String("scene", name), slog.Any("err", err))
+				continue
+			}
+			scenes[dev.Mac] = append(scenes[dev.Mac], scene)
+			sceneDevices[name]++
+		}
+
 		devices = append(devices, dev)
 	}
 
+	for _, name := range sceneNames {
+		if sceneDevices[name] == 0 {
+			err := errors.New("scene " + name + " is not supported by any device")
+			logger.Error("scene is incorrect", slog.String("scene", name), slog.Any("err", err))
+			return nil, err
+		}
+	}
+
+	macs := make([]string, 0, len(devices))
+	for _, device := range devices {
+		macs = append(macs, device.Mac)
+	}
+	groups := make([]workspaceServiceModels.Group, 0, len(cfg.Groups))
+	for _, group := range cfg.Groups {
+		grp := workspaceServiceModels.Group{
+			Name:      group.Name,
+			Discovery: group.Discovery,
+		}
+		for _, mac := range group.Devices {
+			grp.Devices = append(grp.Devices, strings.ToLower(mac))
+		}
+		err = grp.Validate(macs)
+		if err != nil {
+			logger.Error("group is incorrect", slog.String("group", group.Name), slog.Any("err", err))
+			return nil, err
+		}
+		for _, other := range groups {
+			if other.Name == grp.Name {
+				err = errors.New("group name is not unique")
+				logger.Error("group is incorrect", slog.String("group", group.Name), slog.Any("err", err))
+				return nil, err
+			}
+		}
+		groups = append(groups, grp)
+	}
+
 	application := &App{
 		wsMqttReceiver:     mqttReceiver,
 		client:             client,
 		devices:            devices,
+		schedules:          schedules,
+		contacts:           contacts,
+		scenes:             scenes,
+		groups:             groups,
+		sleepProfiles:      sleepProfiles,
 		wsService:          service,
 		topicPrefix:        cfg.Mqtt.TopicPrefix,
 		autoDiscoveryTopic: cfg.Mqtt.AutoDiscoveryTopic,
+		discoveryFormat:    cfg.Mqtt.DiscoveryFormat,
+		homieTopic:         cfg.Mqtt.HomieTopic,
+		startupStagger:     time.Duration(cfg.Service.StartupStagger) * time.Millisecond,
+		authBackoffMin:     time.Duration(cfg.Service.AuthBackoffMin) * time.Second,
+		authBackoffMax:     time.Duration(cfg.Service.AuthBackoffMax) * time.Second,
 		logLevel:           cfg.Service.LogLevel,
 	}
 
@@ -126,7 +409,7 @@ func (app *App) Run(ctx context.Context, logger *slog.Logger) error {
 		}
 	}
 
-	if app.autoDiscoveryTopic != nil {
+	if app.autoDiscoveryTopic != nil && app.discoveryFormat == workspaceMqttModels.DiscoveryFormatHomeAssistant {
 		if token := app.client.Subscribe(*app.autoDiscoveryTopic+"/status", 0, app.wsMqttReceiver.GetStatesOnHomeAssistantRestart(ctx)); token.Wait() && token.Error() != nil {
 			err := token.Error()
 			if err != nil {
@@ -141,13 +424,13 @@ func (app *App) Run(ctx context.Context, logger *slog.Logger) error {
 	// Create Device
 	for _, device := range app.devices {
 		err := app.wsService.CreateDevice(ctx, &workspaceServiceModels.CreateDeviceInput{
-			Config: workspaceServiceModels.DeviceConfig{
-				Mac:             device.Mac,
-				Ip:              device.Ip,
-				Name:            device.Name,
-				Port:            device.Port,
-				TemperatureUnit: device.TemperatureUnit,
-			}})
+			Config:    device,
+			Schedules: app.schedules[device.Mac],
+			Contacts:  app.contacts[device.Mac],
+			Scenes:    app.scenes[device.Mac],
+			// The offsets of the sleep steps are in the unit of every device, so the profiles are shared
+			SleepProfiles: app.sleepProfiles,
+		})
 		if err != nil {
 			logger.ErrorContext(ctx, "failed to create the device",
 				slog.Any("err", err))
@@ -155,53 +438,97 @@ func (app *App) Run(ctx context.Context, logger *slog.Logger) error {
 		}
 	}
 
+	// The devices can share a sensor or a contact, so every topic is subscribed once for all of them
+	externalTempTopics := make(map[string][]string)
+	contactTopics := make(map[string][]string)
 	for _, device := range app.devices {
-		device := device
-		go func() {
-			for {
-				err := app.wsService.AuthDevice(ctx, &workspaceServiceModels.AuthDeviceInput{Mac: device.Mac})
-				if err == nil {
-					break
-				}
-				logger.ErrorContext(ctx, "failed to Auth device "+device.Mac+". Reconnect in 3 seconds...",
-					slog.Any("err", err))
-				time.Sleep(time.Second * 3)
-			}
+		if len(device.ExternalTempTopic) != 0 {
+			externalTempTopics[device.ExternalTempTopic] = append(externalTempTopics[device.ExternalTempTopic], device.Mac)
+		}
+		for _, contact := range app.contacts[device.Mac] {
+			contactTopics[contact.Topic] = append(contactTopics[contact.Topic], device.Mac)
+		}
+	}
+	if len(externalTempTopics) != 0 {
+		workspaceMqttReceiver.ExternalSensorRouters(ctx, logger, externalTempTopics, app.client, app.wsMqttReceiver)
+	}
+	if len(contactTopics) != 0 {
+		workspaceMqttReceiver.InterlockRouters(ctx, logger, contactTopics, app.client, app.wsMqttReceiver)
+	}
 
-			// Subscribe on MQTT handlers
-			workspaceMqttReceiver.Routers(ctx, logger, device.Mac, app.topicPrefix, app.client, app.wsMqttReceiver)
+	if len(app.scenes) != 0 {
+		workspaceMqttReceiver.SceneRouters(ctx, logger, app.topicPrefix, app.client, app.wsMqttReceiver)
+	}
 
-			//Publish Discovery Topic
-			if app.autoDiscoveryTopic != nil {
-				err := app.wsService.PublishDiscoveryTopic(ctx, &workspaceServiceModels.PublishDiscoveryTopicInput{Device: device})
-				if err != nil {
-					return
-				}
+	// Create Group
+	for _, group := range app.groups {
+		err := app.wsService.CreateGroup(ctx, &workspaceServiceModels.CreateGroupInput{Group: group})
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to create the group",
+				slog.Any("err", err))
+			return err
+		}
+
+		if app.autoDiscoveryTopic != nil && app.discoveryFormat == workspaceMqttModels.DiscoveryFormatHomeAssistant {
+			err = app.wsService.PublishGroupDiscoveryTopic(ctx, &workspaceServiceModels.PublishGroupDiscoveryTopicInput{Name: group.Name})
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to publish the group discovery topic",
+					slog.Any("err", err))
+				return err
 			}
+		}
+	}
+	if len(app.groups) != 0 {
+		workspaceMqttReceiver.GroupRouters(ctx, logger, app.topicPrefix, app.client, app.wsMqttReceiver)
+	}
 
-			err := app.wsService.StartDeviceMonitoring(ctx, &workspaceServiceModels.StartDeviceMonitoringInput{Mac: device.Mac})
+	// Every device is run by a worker which is restarted when it fails or panics
+	deviceSupervisor := supervisor.New(logger, workerMinBackoff, workerMaxBackoff,
+		func(ctx context.Context, status supervisor.Status) {
+			err := app.wsService.UpdateWorkerStatus(ctx, &workspaceServiceModels.UpdateWorkerStatusInput{
+				Mac:       status.Name,
+				State:     status.State,
+				Restarts:  status.Restarts,
+				Error:     status.Error,
+				UpdatedAt: status.UpdatedAt,
+			})
 			if err != nil {
+				logger.ErrorContext(ctx, "failed to update the worker status",
+					slog.String("device", status.Name),
+					slog.Any("err", err))
+			}
+		})
+
+	var workers sync.WaitGroup
+	for i, device := range app.devices {
+		device := device
+		// The devices are started one by one, so they are not authorized and polled at the same time
+		startDelay := app.startupStagger * time.Duration(i)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			select {
+			case <-ctx.Done():
 				return
+			case <-time.After(startDelay):
 			}
+
+			deviceSupervisor.Run(ctx, device.Mac, func(ctx context.Context) error {
+				return app.runDevice(ctx, logger, device)
+			})
 		}()
 	}
 
 	// Graceful shutdown
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, syscall.SIGKILL, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
-	killSignal := <-interrupt
-	switch killSignal {
-	case syscall.SIGKILL:
-		logger.Info("Got SIGKILL...")
-	case syscall.SIGQUIT:
-		logger.Info("Got SIGQUIT...")
-	case syscall.SIGTERM:
-		logger.Info("Got SIGTERM...")
-	case syscall.SIGINT:
-		logger.Info("Got SIGINT...")
-	default:
-		logger.Info("Undefined killSignal...")
-	}
+	<-ctx.Done()
+	logger.Info("Shutting down...")
+	workers.Wait()
+
+	// The context is canceled, so the last messages are published with a new one
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
 	// Publish offline states for devices
 	g := new(errgroup.Group)
 	for _, device := range app.devices {
@@ -220,6 +547,23 @@ func (app *App) Run(ctx context.Context, logger *slog.Logger) error {
 			return nil
 		})
 	}
+	// The groups are available while the bridge runs
+	for _, group := range app.groups {
+		group := group
+		g.Go(func() error {
+			err := app.wsService.UpdateGroupAvailability(ctx, &workspaceServiceModels.UpdateGroupAvailabilityInput{
+				Name:         group.Name,
+				Availability: "offline",
+			})
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to update group availability",
+					slog.String("group", group.Name),
+					slog.Any("err", err))
+				return err
+			}
+			return nil
+		})
+	}
 	if err := g.Wait(); err != nil {
 		return err
 	}
@@ -229,9 +573,73 @@ func (app *App) Run(ctx context.Context, logger *slog.Logger) error {
 	return nil
 }
 
+// runDevice authorizes the device, subscribes on its topics and monitors it until the context is canceled
+func (app *App) runDevice(ctx context.Context, logger *slog.Logger, device workspaceServiceModels.DeviceConfig) error {
+	backoff := app.authBackoffMin
+	for {
+		err := app.wsService.AuthDevice(ctx, &workspaceServiceModels.AuthDeviceInput{Mac: device.Mac})
+		if err == nil {
+			break
+		}
+
+		// The jitter spreads the retries of the devices which have failed at the same time
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff)/4+1))
+		logger.ErrorContext(ctx, "failed to Auth device "+device.Mac+". Reconnect in "+delay.Round(time.Second).String()+"...",
+			slog.Any("err", err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		backoff *= 2
+		if backoff > app.authBackoffMax {
+			backoff = app.authBackoffMax
+		}
+	}
+
+	err := app.wsService.GetDeviceFirmware(ctx, &workspaceServiceModels.GetDeviceFirmwareInput{Mac: device.Mac})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get the firmware version of device "+device.Mac,
+			slog.Any("err", err))
+	}
+
+	// Subscribe on MQTT handlers
+	workspaceMqttReceiver.Routers(ctx, logger, device.Mac, app.topicPrefix, app.client, app.wsMqttReceiver)
+	if app.discoveryFormat == workspaceMqttModels.DiscoveryFormatHomie {
+		workspaceMqttReceiver.HomieRouters(ctx, logger, device.Mac, app.homieTopic, app.client, app.wsMqttReceiver)
+	}
+
+	//Publish Discovery Topic
+	if app.autoDiscoveryTopic != nil || app.discoveryFormat == workspaceMqttModels.DiscoveryFormatHomie {
+		err = app.wsService.PublishDiscoveryTopic(ctx, &workspaceServiceModels.PublishDiscoveryTopicInput{Device: device})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The scheduler, the timers, the sleep curve and the device actor are restarted together when one of them fails
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return app.wsService.StartScheduler(gCtx, &workspaceServiceModels.StartSchedulerInput{Mac: device.Mac})
+	})
+	g.Go(func() error {
+		return app.wsService.StartTimers(gCtx, &workspaceServiceModels.StartTimersInput{Mac: device.Mac})
+	})
+	g.Go(func() error {
+		return app.wsService.StartSleepCurve(gCtx, &workspaceServiceModels.StartSleepCurveInput{Mac: device.Mac})
+	})
+	g.Go(func() error {
+		return app.wsService.StartDeviceMonitoring(gCtx, &workspaceServiceModels.StartDeviceMonitoringInput{Mac: device.Mac})
+	})
+
+	return g.Wait()
+}
+
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
 	logLevel := &slog.LevelVar{}
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
@@ -265,3 +673,116 @@ func main() {
 		return
 	}
 }
+
+// valueOrDefault returns the value or the default when the value is not set
+func valueOrDefault(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// pointerOrDefault returns the value or the default when the value is not set, so 0 can be set
+func pointerOrDefault(value *int, defaultValue int) int {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+// newSchedule converts the schedule from the config. The temperature is converted to Celsius.
+func newSchedule(device workspaceServiceModels.DeviceConfig, schedule config.DeviceSchedule) (workspaceServiceModels.Schedule, error) {
+	sch := workspaceServiceModels.Schedule{
+		Name:    schedule.Name,
+		Enabled: schedule.Enabled == nil || *schedule.Enabled,
+	}
+
+	var err error
+	sch.At, err = workspaceServiceModels.ParseTimeOfDay(schedule.Time)
+	if err != nil {
+		return sch, err
+	}
+
+	for _, day := range schedule.Days {
+		weekday, err := workspaceServiceModels.ParseWeekday(day)
+		if err != nil {
+			return sch, err
+		}
+		sch.Days = append(sch.Days, weekday)
+	}
+
+	sch.Command, err = newCommand(device, schedule.DeviceStates)
+	if err != nil {
+		return sch, err
+	}
+	if schedule.Sleep != nil {
+		sch.Sleep = *schedule.Sleep
+	}
+
+	return sch, sch.Validate(device)
+}
+
+func newSleepProfile(name string, profile config.SleepProfile) (workspaceServiceModels.SleepProfile, error) {
+	prof := workspaceServiceModels.SleepProfile{Name: name}
+
+	for _, step := range profile.Steps {
+		st := workspaceServiceModels.SleepStep{
+			After:   time.Duration(step.After) * time.Minute,
+			Offset:  step.Offset,
+			FanMode: step.FanMode,
+		}
+		if step.Display != nil {
+			display := &workspaceServiceModels.UpdateDisplaySwitchInput{Status: *step.Display}
+			err := display.Validate()
+			if err != nil {
+				return prof, err
+			}
+			isDisplayOn := display.Status == "ON"
+			st.IsDisplayOn = &isDisplayOn
+		}
+		prof.Steps = append(prof.Steps, st)
+	}
+
+	return prof, prof.Validate()
+}
+
+func newScene(device workspaceServiceModels.DeviceConfig, name string, states config.DeviceStates) (workspaceServiceModels.Scene, error) {
+	command, err := newCommand(device, states)
+	if err != nil {
+		return workspaceServiceModels.Scene{}, err
+	}
+
+	scene := workspaceServiceModels.Scene{
+		Name:    name,
+		Command: command,
+	}
+
+	return scene, scene.Validate(device)
+}
+
+// newCommand converts the states from the config to the command of the device. The temperature is converted to Celsius.
+func newCommand(device workspaceServiceModels.DeviceConfig, states config.DeviceStates) (workspaceServiceModels.UpdateDeviceStatesInput, error) {
+	command := workspaceServiceModels.UpdateDeviceStatesInput{
+		Mac:       device.Mac,
+		FanMode:   states.FanMode,
+		SwingMode: states.SwingMode,
+		Mode:      states.Mode,
+	}
+
+	if states.Temperature != nil {
+		temperature := converter.SetpointToCelsius(device.TemperatureUnit, *states.Temperature)
+		command.Temperature = &temperature
+	}
+
+	if states.Display != nil {
+		display := &workspaceServiceModels.UpdateDisplaySwitchInput{Mac: device.Mac, Status: *states.Display}
+		err := display.Validate()
+		if err != nil {
+			return command, err
+		}
+		isDisplayOn := display.Status == "ON"
+		command.IsDisplayOn = &isDisplayOn
+	}
+
+	return command, nil
+}
@@ -11,6 +11,13 @@ func Encrypt(key, iv, plaintext []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	if len(iv) != block.BlockSize() {
+		return nil, ErrorInvalidIvLength
+	}
+	if len(plaintext)%block.BlockSize() != 0 {
+		return nil, ErrorInvalidPlaintextLength
+	}
+
 	ciphertext := make([]byte, len(plaintext))
 
 	mode := cipher.NewCBCEncrypter(block, iv)
@@ -25,12 +32,11 @@ func Decrypt(key, iv, ciphertext []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	for {
-		if len(ciphertext)%block.BlockSize() != 0 {
-			ciphertext = append(ciphertext, byte(0))
-		} else {
-			break
-		}
+	if len(iv) != block.BlockSize() {
+		return nil, ErrorInvalidIvLength
+	}
+	if len(ciphertext)%block.BlockSize() != 0 {
+		return nil, ErrorInvalidCiphertextLength
 	}
 
 	decrypted := make([]byte, len(ciphertext))
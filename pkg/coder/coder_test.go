@@ -0,0 +1,49 @@
+package coder
+
+import (
+	"bytes"
+	"testing"
+)
+
+var (
+	defaultKey = []byte{0x09, 0x76, 0x28, 0x34, 0x3f, 0xe9, 0x9e, 0x23, 0x76, 0x5c, 0x15, 0x13, 0xac, 0xcf, 0x8b, 0x02}
+	defaultIv  = []byte{0x56, 0x2e, 0x17, 0x99, 0x6d, 0x09, 0x3d, 0x28, 0xdd, 0xb3, 0xba, 0x69, 0x5a, 0x2e, 0x6f, 0x58}
+)
+
+func TestDecryptAuthResponse(t *testing.T) {
+	payload, err := Decrypt(defaultKey, defaultIv, decodeHex(t, authResponse)[HeaderLength:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The device id is 1 and the new key follows it
+	if !bytes.Equal(payload[:4], []byte{1, 0, 0, 0}) {
+		t.Fatalf("the device id is %x, want 01000000", payload[:4])
+	}
+}
+
+func FuzzDecrypt(f *testing.F) {
+	f.Add(defaultKey, defaultIv, decodeHex(f, authResponse)[HeaderLength:])
+	f.Add(decodeHex(f, "cc44e4349c0cb4a46c54dc9c04ccecf4"), defaultIv, decodeHex(f, ambientResponse)[HeaderLength:])
+	f.Add(defaultKey, defaultIv, decodeHex(f, ambientResponse)[HeaderLength+1:])
+	f.Add(defaultKey, defaultIv[:8], []byte{})
+	f.Add([]byte{}, []byte{}, []byte{})
+
+	f.Fuzz(func(t *testing.T, key, iv, ciphertext []byte) {
+		plaintext, err := Decrypt(key, iv, ciphertext)
+		if err != nil {
+			return
+		}
+		if len(plaintext) != len(ciphertext) {
+			t.Fatalf("Decrypt returned %d bytes for %d bytes", len(plaintext), len(ciphertext))
+		}
+
+		encrypted, err := Encrypt(key, iv, plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt failed on the decrypted data: %v", err)
+		}
+		if !bytes.Equal(encrypted, ciphertext) {
+			t.Fatal("Encrypt did not return the ciphertext back")
+		}
+	})
+}
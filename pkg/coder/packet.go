@@ -0,0 +1,84 @@
+package coder
+
+import (
+	"bytes"
+	"errors"
+)
+
+const (
+	// HeaderLength is the length of the unencrypted header of a packet
+	HeaderLength = 0x38
+
+	checksumOffset   = 0x20
+	errorCodeOffset  = 0x22
+	deviceTypeOffset = 0x24
+	macOffset        = 0x2a
+	checksumSeed     = 0xbeaf
+)
+
+var (
+	ErrorPacketTooShort          = errors.New("ErrorPacketTooShort")
+	ErrorPacketMagic             = errors.New("ErrorPacketMagic")
+	ErrorPacketChecksum          = errors.New("ErrorPacketChecksum")
+	ErrorPacketDeviceError       = errors.New("ErrorPacketDeviceError")
+	ErrorPacketDeviceType        = errors.New("ErrorPacketDeviceType")
+	ErrorPacketMac               = errors.New("ErrorPacketMac")
+	ErrorInvalidCiphertextLength = errors.New("ErrorInvalidCiphertextLength")
+	ErrorInvalidPlaintextLength  = errors.New("ErrorInvalidPlaintextLength")
+	ErrorInvalidIvLength         = errors.New("ErrorInvalidIvLength")
+	ErrorPacketPayloadNotAligned = errors.New("ErrorPacketPayloadNotAligned")
+	ErrorPacketPayloadIsEmpty    = errors.New("ErrorPacketPayloadIsEmpty")
+)
+
+var magic = []byte{0x5a, 0xa5, 0xaa, 0x55, 0x5a, 0xa5, 0xaa, 0x55}
+
+// Checksum returns the checksum of the data which is used in the packet header
+func Checksum(data []byte) uint16 {
+	checksum := checksumSeed
+	for _, b := range data {
+		checksum = (checksum + int(b)) & 0xffff
+	}
+	return uint16(checksum)
+}
+
+// ValidatePacket checks the header of the packet received from the device before it is decrypted:
+// the magic, the length, the checksum, the error code, the device type and the MAC address.
+func ValidatePacket(packet []byte, devType uint16, mac []byte) error {
+	if len(packet) < HeaderLength {
+		return ErrorPacketTooShort
+	}
+
+	if !bytes.Equal(packet[:len(magic)], magic) {
+		return ErrorPacketMagic
+	}
+
+	checksum := uint16(packet[checksumOffset]) | uint16(packet[checksumOffset+1])<<8
+	withoutChecksum := append([]byte(nil), packet...)
+	withoutChecksum[checksumOffset] = 0
+	withoutChecksum[checksumOffset+1] = 0
+	if Checksum(withoutChecksum) != checksum {
+		return ErrorPacketChecksum
+	}
+
+	if uint16(packet[errorCodeOffset])|uint16(packet[errorCodeOffset+1])<<8 != 0 {
+		return ErrorPacketDeviceError
+	}
+
+	if uint16(packet[deviceTypeOffset])|uint16(packet[deviceTypeOffset+1])<<8 != devType {
+		return ErrorPacketDeviceType
+	}
+
+	if !bytes.Equal(packet[macOffset:macOffset+len(mac)], mac) {
+		return ErrorPacketMac
+	}
+
+	payloadLength := len(packet) - HeaderLength
+	if payloadLength == 0 {
+		return ErrorPacketPayloadIsEmpty
+	}
+	if payloadLength%16 != 0 {
+		return ErrorPacketPayloadNotAligned
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package coder
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The responses captured from a device, see the comments of AuthDevice and GetDeviceAmbientTemperature
+const (
+	authResponse    = "5aa5aa555aa5aa5500000000000000000000000000000000000000000000000028dc00002a4ee90363f734ea34dadac800000000c1c70000bb6cbbbb34585cd442b9cfbbdb303eea55afe062cdd638164b81cc384084ef9e"
+	ambientResponse = "5aa5aa555aa5aa5500000000000000000000000000000000000000000000000040e300002a4eee03907c34ea34dadac801000000cfc100002c4fa6c565f78b46829220a36fbf6524a68a0497eb37efe6a6422a4f6b8aed81d167c38db269c50ae4e29105bc525e60"
+	ambientRequest  = "5aa5aa555aa5aa55000000000000000000000000000000000000000000000000a1d100002a4e6a00907c34ea34dadac801000000b9c000003d197732162cb4f5f9e18aca7b1bff13"
+
+	deviceType = 0x4e2a
+)
+
+var deviceMac = []byte{0x34, 0xea, 0x34, 0xda, 0xda, 0xc8}
+
+func decodeHex(tb testing.TB, value string) []byte {
+	tb.Helper()
+
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func TestValidatePacket(t *testing.T) {
+	for _, response := range []string{authResponse, ambientResponse} {
+		err := ValidatePacket(decodeHex(t, response), deviceType, deviceMac)
+		if err != nil {
+			t.Fatalf("ValidatePacket(%s) = %v, want nil", response, err)
+		}
+	}
+}
+
+func FuzzValidatePacket(f *testing.F) {
+	f.Add(decodeHex(f, authResponse), uint16(deviceType))
+	f.Add(decodeHex(f, ambientResponse), uint16(deviceType))
+	f.Add(decodeHex(f, ambientRequest), uint16(deviceType))
+	f.Add(decodeHex(f, authResponse)[:HeaderLength], uint16(deviceType))
+	f.Add([]byte{}, uint16(0))
+
+	f.Fuzz(func(t *testing.T, packet []byte, devType uint16) {
+		original := append([]byte(nil), packet...)
+
+		err := ValidatePacket(packet, devType, deviceMac)
+		if string(packet) != string(original) {
+			t.Fatal("ValidatePacket has changed the packet")
+		}
+		if err != nil {
+			return
+		}
+
+		payloadLength := len(packet) - HeaderLength
+		if payloadLength <= 0 || payloadLength%16 != 0 {
+			t.Fatalf("ValidatePacket accepted the payload of %d bytes", payloadLength)
+		}
+	})
+}
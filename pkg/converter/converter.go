@@ -1,5 +1,13 @@
 package converter
 
+import "math"
+
+const (
+	celsius    = "C"
+	fahrenheit = "F"
+)
+
+// Temperature converts the measured temperature between the units with a precision of 0.1 degree
 func Temperature(inputUnit, outputUnit string, value float32) float32 {
 	if inputUnit == "C" {
 		if outputUnit == "C" {
@@ -7,13 +15,13 @@ func Temperature(inputUnit, outputUnit string, value float32) float32 {
 		}
 
 		if outputUnit == "F" {
-			return float32(int((value * 9 / 5) + 32))
+			return round(value*9/5+32, 10)
 		}
 	}
 
 	if inputUnit == "F" {
 		if outputUnit == "C" {
-			return float32(int((value-32)*5/9*10)) / 10
+			return round((value-32)*5/9, 10)
 		}
 
 		if outputUnit == "F" {
@@ -23,3 +31,59 @@ func Temperature(inputUnit, outputUnit string, value float32) float32 {
 
 	return 0
 }
+
+// DifferenceToCelsius converts the temperature difference in the unit to Celsius
+func DifferenceToCelsius(unit string, value float32) float32 {
+	if unit == fahrenheit {
+		return value * 5 / 9
+	}
+	return value
+}
+
+// SetpointToCelsius maps the setpoint in the unit to the nearest half degree Celsius, which is
+// the resolution of the device. Every whole Fahrenheit degree gets its own half degree Celsius,
+// so SetpointFromCelsius returns the same Fahrenheit setpoint back.
+func SetpointToCelsius(unit string, value float32) float32 {
+	switch unit {
+	case celsius:
+		return round(value, 2)
+	case fahrenheit:
+		return round((value-32)*5/9, 2)
+	default:
+		return 0
+	}
+}
+
+// SetpointFromCelsius maps the half degree Celsius setpoint of the device to the unit.
+// The Fahrenheit setpoints are whole degrees.
+func SetpointFromCelsius(unit string, value float32) float32 {
+	switch unit {
+	case celsius:
+		return value
+	case fahrenheit:
+		return round(value*9/5+32, 1)
+	default:
+		return 0
+	}
+}
+
+// SetpointStep returns the step of the setpoint in the unit
+func SetpointStep(unit string) float32 {
+	if unit == fahrenheit {
+		return 1
+	}
+	return 0.5
+}
+
+// Precision returns the precision of the displayed temperatures in the unit
+func Precision(unit string) float32 {
+	if unit == fahrenheit {
+		return 1
+	}
+	return 0.1
+}
+
+// round rounds the value to 1/fraction
+func round(value float32, fraction float64) float32 {
+	return float32(math.Round(float64(value)*fraction) / fraction)
+}
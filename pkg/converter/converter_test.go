@@ -0,0 +1,56 @@
+package converter_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/app/service/models"
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/converter"
+)
+
+func TestSetpointRoundTrip(t *testing.T) {
+	tests := []struct {
+		name             string
+		minTemp, maxTemp float32
+	}{
+		{name: "default range", minTemp: models.DefaultMinTemp, maxTemp: models.DefaultMaxTemp},
+		{name: "full range", minTemp: 8, maxTemp: 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" celsius", func(t *testing.T) {
+			for value := tt.minTemp; value <= tt.maxTemp; value += 0.5 {
+				device := converter.SetpointToCelsius("C", value)
+				if device != value {
+					t.Fatalf("SetpointToCelsius(C, %v) = %v, want %v", value, device, value)
+				}
+				got := converter.SetpointFromCelsius("C", device)
+				if got != value {
+					t.Fatalf("SetpointFromCelsius(C, %v) = %v, want %v", device, got, value)
+				}
+			}
+		})
+
+		t.Run(tt.name+" fahrenheit", func(t *testing.T) {
+			minTemp := float32(math.Ceil(float64(converter.SetpointFromCelsius("F", tt.minTemp))))
+			maxTemp := float32(math.Floor(float64(converter.SetpointFromCelsius("F", tt.maxTemp))))
+
+			seen := make(map[float32]float32)
+			for value := minTemp; value <= maxTemp; value++ {
+				device := converter.SetpointToCelsius("F", value)
+				if device*2 != float32(math.Round(float64(device*2))) {
+					t.Fatalf("SetpointToCelsius(F, %v) = %v, which is not a half degree", value, device)
+				}
+				if other, ok := seen[device]; ok {
+					t.Fatalf("SetpointToCelsius(F, %v) = %v, the same as for %v", value, device, other)
+				}
+				seen[device] = value
+
+				got := converter.SetpointFromCelsius("F", device)
+				if got != value {
+					t.Fatalf("SetpointFromCelsius(F, %v) = %v, want %v", device, got, value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	StateRunning    = "running"
+	StateRestarting = "restarting"
+	StateStopped    = "stopped"
+)
+
+// Worker is a long-running function. It is restarted when it returns an error or panics.
+// A worker that returns nil is finished and is not restarted.
+type Worker func(ctx context.Context) error
+
+type Status struct {
+	Name      string
+	State     string
+	Restarts  int
+	Error     string
+	UpdatedAt time.Time
+}
+
+// Supervisor runs the workers and restarts them with an exponential backoff
+type Supervisor struct {
+	logger     *slog.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	onStatus   func(ctx context.Context, status Status)
+}
+
+// New returns a supervisor. onStatus is called on every change of the worker state and may be nil.
+func New(logger *slog.Logger, minBackoff, maxBackoff time.Duration, onStatus func(ctx context.Context, status Status)) *Supervisor {
+	return &Supervisor{
+		logger:     logger,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		onStatus:   onStatus,
+	}
+}
+
+// Run runs the worker until the context is canceled or the worker is finished.
+// The backoff is reset when the worker has been running longer than the largest backoff.
+func (s *Supervisor) Run(ctx context.Context, name string, worker Worker) {
+	backoff := s.minBackoff
+	status := Status{Name: name}
+
+	for {
+		status.State = StateRunning
+		s.report(ctx, status)
+
+		startedAt := time.Now()
+		err := s.runSafely(ctx, worker)
+
+		if ctx.Err() != nil || err == nil {
+			status.State = StateStopped
+			status.Error = ""
+			s.report(context.WithoutCancel(ctx), status)
+			s.logger.InfoContext(ctx, "worker is stopped", slog.String("worker", name))
+			return
+		}
+
+		if time.Since(startedAt) > s.maxBackoff {
+			backoff = s.minBackoff
+		}
+
+		status.State = StateRestarting
+		status.Restarts++
+		status.Error = err.Error()
+		s.report(ctx, status)
+		s.logger.ErrorContext(ctx, "worker failed, restarting",
+			slog.String("worker", name),
+			slog.Int("restarts", status.Restarts),
+			slog.Duration("backoff", backoff),
+			slog.Any("err", err))
+
+		select {
+		case <-ctx.Done():
+			status.State = StateStopped
+			s.report(context.WithoutCancel(ctx), status)
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// runSafely converts a panic of the worker into an error
+func (s *Supervisor) runSafely(ctx context.Context, worker Worker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.ErrorContext(ctx, "worker panicked",
+				slog.Any("panic", r),
+				slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return worker(ctx)
+}
+
+func (s *Supervisor) report(ctx context.Context, status Status) {
+	if s.onStatus == nil {
+		return
+	}
+
+	status.UpdatedAt = time.Now()
+	s.onStatus(ctx, status)
+}
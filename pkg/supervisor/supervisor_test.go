@@ -0,0 +1,202 @@
+package supervisor_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ArtemVladimirov/broadlinkac2mqtt/pkg/supervisor"
+)
+
+var errFailed = errors.New("failed")
+
+// recorder keeps the statuses reported by the supervisor
+type recorder struct {
+	mutex    sync.Mutex
+	statuses []supervisor.Status
+}
+
+func (r *recorder) onStatus(_ context.Context, status supervisor.Status) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.statuses = append(r.statuses, status)
+}
+
+func (r *recorder) all() []supervisor.Status {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]supervisor.Status(nil), r.statuses...)
+}
+
+func newSupervisor(minBackoff, maxBackoff time.Duration, r *recorder) *supervisor.Supervisor {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if r == nil {
+		return supervisor.New(logger, minBackoff, maxBackoff, nil)
+	}
+	return supervisor.New(logger, minBackoff, maxBackoff, r.onStatus)
+}
+
+func TestRunRecoversPanic(t *testing.T) {
+	r := &recorder{}
+	calls := 0
+	newSupervisor(time.Millisecond, time.Millisecond*10, r).Run(context.Background(), "worker", func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			panic("broken")
+		}
+		return nil
+	})
+
+	if calls != 2 {
+		t.Fatalf("the worker is run %d times, want 2", calls)
+	}
+
+	statuses := r.all()
+	if len(statuses) != 4 {
+		t.Fatalf("%d statuses are reported, want 4: %+v", len(statuses), statuses)
+	}
+	if restarting := statuses[1]; restarting.State != supervisor.StateRestarting || restarting.Error != "panic: broken" {
+		t.Fatalf("the status after the panic is %+v, want restarting with the panic", restarting)
+	}
+}
+
+func TestRunReportsStatus(t *testing.T) {
+	r := &recorder{}
+	calls := 0
+	newSupervisor(time.Millisecond, time.Millisecond*10, r).Run(context.Background(), "34ea34dadac8", func(ctx context.Context) error {
+		calls++
+		if calls <= 2 {
+			return errFailed
+		}
+		return nil
+	})
+
+	want := []supervisor.Status{
+		{Name: "34ea34dadac8", State: supervisor.StateRunning},
+		{Name: "34ea34dadac8", State: supervisor.StateRestarting, Restarts: 1, Error: errFailed.Error()},
+		{Name: "34ea34dadac8", State: supervisor.StateRunning, Restarts: 1, Error: errFailed.Error()},
+		{Name: "34ea34dadac8", State: supervisor.StateRestarting, Restarts: 2, Error: errFailed.Error()},
+		{Name: "34ea34dadac8", State: supervisor.StateRunning, Restarts: 2, Error: errFailed.Error()},
+		{Name: "34ea34dadac8", State: supervisor.StateStopped, Restarts: 2},
+	}
+
+	statuses := r.all()
+	if len(statuses) != len(want) {
+		t.Fatalf("%d statuses are reported, want %d: %+v", len(statuses), len(want), statuses)
+	}
+	for i, status := range statuses {
+		if status.UpdatedAt.IsZero() {
+			t.Fatalf("status %d has no time", i)
+		}
+		status.UpdatedAt = time.Time{}
+		if status != want[i] {
+			t.Fatalf("status %d is %+v, want %+v", i, status, want[i])
+		}
+	}
+}
+
+func TestRunBackoff(t *testing.T) {
+	const (
+		minBackoff = time.Millisecond * 10
+		maxBackoff = time.Millisecond * 300
+	)
+
+	var starts []time.Time
+	newSupervisor(minBackoff, maxBackoff, nil).Run(context.Background(), "worker", func(ctx context.Context) error {
+		starts = append(starts, time.Now())
+		switch len(starts) {
+		case 7:
+			return nil
+		case 6:
+			// The worker which has been running longer than the largest backoff resets it
+			time.Sleep(maxBackoff + time.Millisecond*50)
+		}
+		return errFailed
+	})
+
+	if len(starts) != 7 {
+		t.Fatalf("the worker is run %d times, want 7", len(starts))
+	}
+
+	// The backoff doubles from the smallest one up to the largest one
+	for i, backoff := range []time.Duration{10, 20, 40, 80, 160} {
+		if gap := starts[i+1].Sub(starts[i]); gap < backoff*time.Millisecond {
+			t.Fatalf("restart %d is after %v, want at least %v", i+1, gap, backoff*time.Millisecond)
+		}
+	}
+
+	gap := starts[6].Sub(starts[5]) - maxBackoff - time.Millisecond*50
+	if gap >= maxBackoff/2 {
+		t.Fatalf("the restart after the long run is after %v, want the reset backoff of %v", gap, minBackoff)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	t.Run("running", func(t *testing.T) {
+		r := &recorder{}
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			newSupervisor(time.Millisecond, time.Millisecond*10, r).Run(ctx, "worker", func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		}()
+
+		<-started
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Run is not stopped by the canceled context")
+		}
+
+		statuses := r.all()
+		if last := statuses[len(statuses)-1]; last.State != supervisor.StateStopped || last.Restarts != 0 {
+			t.Fatalf("the last status is %+v, want stopped without restarts", last)
+		}
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		r := &recorder{}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			newSupervisor(time.Hour, time.Hour, r).Run(ctx, "worker", func(ctx context.Context) error {
+				return errFailed
+			})
+		}()
+
+		// The context is canceled once the supervisor waits for the restart
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			statuses := r.all()
+			if len(statuses) != 0 && statuses[len(statuses)-1].State == supervisor.StateRestarting {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the worker is not restarting")
+			}
+		}
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Run is not stopped by the canceled context during the backoff")
+		}
+
+		statuses := r.all()
+		if last := statuses[len(statuses)-1]; last.State != supervisor.StateStopped || last.Restarts != 1 {
+			t.Fatalf("the last status is %+v, want stopped after one restart", last)
+		}
+	})
+}